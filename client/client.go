@@ -0,0 +1,338 @@
+// Package client provides a Go client for the routes of the maxmind HTTP
+// server.
+package client
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pquerna/ffjson/ffjson"
+	"github.com/rabbitt/maxmind/mm"
+)
+
+const (
+	DefaultTimeout             = 5 * time.Second
+	DefaultRetries             = 2
+	DefaultBackoff             = 100 * time.Millisecond
+	DefaultMaxBackoff          = 2 * time.Second
+	DefaultMaxIdleConnsPerHost = 32
+)
+
+// Client is the interface to a maxmind server. It is implemented by
+// HttpClient, and may be mocked by tests of code that depends on it.
+type Client interface {
+	// Lookup returns the geodata for ip.
+	Lookup(ctx context.Context, ip string) (*mm.GeoData, error)
+
+	// BatchLookup returns the result of looking up each of ips, in order.
+	// Invalid ips are reported by their result, not by the error.
+	BatchLookup(ctx context.Context, ips []string) ([]*mm.LookupResult, error)
+
+	// Info describes the server and its database.
+	Info(ctx context.Context) (*mm.ServerInfo, error)
+
+	// Alive reports whether the server is up and serving requests.
+	Alive(ctx context.Context) error
+
+	// Health returns the outcome of the server's readiness checks. When a
+	// check fails, both the response and an error are returned.
+	Health(ctx context.Context) (*mm.HealthResponse, error)
+
+	// Usage returns the number of requests made, and rejected, per api key.
+	Usage(ctx context.Context) ([]mm.ApiKeyUsage, error)
+}
+
+// Error reports a request the server rejected, or could not answer.
+type Error struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *Error) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("maxmind server responded %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+	}
+	return fmt.Sprintf("maxmind server responded %d %s: %s", e.StatusCode, http.StatusText(e.StatusCode), e.Message)
+}
+
+// HttpClient is a Client talking to a maxmind server over HTTP. Connections
+// to the server are pooled and reused, and requests failing with a network
+// error, a 429, or a 502, 503 or 504 are retried with exponential backoff.
+// It is safe for concurrent use.
+type HttpClient struct {
+	// ApiKey, if set, is sent as a bearer token with every request.
+	ApiKey string
+
+	// Timeout bounds each attempt at a request; 0 disables it.
+	Timeout time.Duration
+
+	// Retries is the number of times a failed request is retried.
+	Retries int
+
+	// Backoff is the delay before the first retry, doubling with each
+	// further retry up to MaxBackoff. Delays are jittered.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+
+	UserAgent string
+
+	Http *http.Client
+
+	baseUrl string
+}
+
+// NewClient creates a client of the server at baseUrl, either
+// http(s)://host:port or unix:///path/to/socket.
+func NewClient(baseUrl string) (*HttpClient, error) {
+	u, err := url.Parse(baseUrl)
+	if err != nil {
+		return nil, err
+	}
+
+	dialer := &net.Dialer{Timeout: DefaultTimeout, KeepAlive: 30 * time.Second}
+	transport := &http.Transport{
+		Proxy:               http.ProxyFromEnvironment,
+		DialContext:         dialer.DialContext,
+		MaxIdleConns:        100,
+		MaxIdleConnsPerHost: DefaultMaxIdleConnsPerHost,
+		IdleConnTimeout:     90 * time.Second,
+	}
+
+	switch u.Scheme {
+	case "http", "https":
+		baseUrl = strings.TrimRight(u.String(), "/")
+	case "unix":
+		if u.Path == "" {
+			return nil, fmt.Errorf("missing socket path in `%s`", baseUrl)
+		}
+		socket := u.Path
+		transport.Proxy = nil
+		transport.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return dialer.DialContext(ctx, "unix", socket)
+		}
+		baseUrl = "http://unix"
+	default:
+		return nil, fmt.Errorf("unsupported url `%s`; expected http://, https:// or unix://", baseUrl)
+	}
+
+	return &HttpClient{
+		Timeout:    DefaultTimeout,
+		Retries:    DefaultRetries,
+		Backoff:    DefaultBackoff,
+		MaxBackoff: DefaultMaxBackoff,
+		UserAgent:  "maxmind-client/0.0.1",
+		Http:       &http.Client{Transport: transport},
+		baseUrl:    baseUrl,
+	}, nil
+}
+
+// Close releases the pooled connections to the server.
+func (c *HttpClient) Close() {
+	c.Http.CloseIdleConnections()
+}
+
+func (c *HttpClient) Lookup(ctx context.Context, ip string) (*mm.GeoData, error) {
+	var response mm.JsonResponse
+	if err := c.call(ctx, "GET", "/ip/"+url.PathEscape(ip), nil, &response); err != nil {
+		return nil, err
+	}
+	if response.Data == nil {
+		return nil, &Error{StatusCode: http.StatusOK, Message: "response has no data"}
+	}
+	return response.Data, nil
+}
+
+func (c *HttpClient) BatchLookup(ctx context.Context, ips []string) ([]*mm.LookupResult, error) {
+	body, err := ffjson.Marshal(&mm.BatchRequest{Ips: ips})
+	if err != nil {
+		return nil, err
+	}
+
+	var response mm.BatchResponse
+	if err = c.call(ctx, "POST", "/batch", body, &response); err != nil {
+		return nil, err
+	}
+	return response.Results, nil
+}
+
+func (c *HttpClient) Info(ctx context.Context) (*mm.ServerInfo, error) {
+	var info mm.ServerInfo
+	if err := c.call(ctx, "GET", "/info", nil, &info); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+func (c *HttpClient) Alive(ctx context.Context) error {
+	return c.call(ctx, "GET", "/healthz", nil, nil)
+}
+
+func (c *HttpClient) Health(ctx context.Context) (*mm.HealthResponse, error) {
+	status, body, err := c.do(ctx, "GET", "/readyz", nil, http.StatusServiceUnavailable)
+	if err != nil {
+		return nil, err
+	}
+
+	var health mm.HealthResponse
+	if err = ffjson.Unmarshal(body, &health); err != nil {
+		return nil, responseError(status, body)
+	}
+	if status != http.StatusOK {
+		return &health, &Error{StatusCode: status, Message: "not ready"}
+	}
+	return &health, nil
+}
+
+func (c *HttpClient) Usage(ctx context.Context) ([]mm.ApiKeyUsage, error) {
+	var usage []mm.ApiKeyUsage
+	if err := c.call(ctx, "GET", "/usage", nil, &usage); err != nil {
+		return nil, err
+	}
+	return usage, nil
+}
+
+// call makes a request, decoding a successful JSON response into out.
+func (c *HttpClient) call(ctx context.Context, method string, path string, body []byte, out interface{}) error {
+	status, response, err := c.do(ctx, method, path, body)
+	if err != nil {
+		return err
+	}
+	if status != http.StatusOK {
+		return responseError(status, response)
+	}
+	if out == nil {
+		return nil
+	}
+
+	if err = ffjson.Unmarshal(response, out); err != nil {
+		return fmt.Errorf("unable to decode response to %s %s: %s", method, path, err)
+	}
+
+	// lookups report some failures in the body of a successful response
+	if jr, ok := out.(*mm.JsonResponse); ok && jr.Status != "success" {
+		return &Error{StatusCode: status, Message: jr.Message}
+	}
+	return nil
+}
+
+// responseError describes a failed response, using the message of a JSON
+// error body when there is one.
+func responseError(status int, body []byte) error {
+	var response mm.JsonResponse
+	if ffjson.Unmarshal(body, &response) == nil && response.Message != "" {
+		return &Error{StatusCode: status, Message: response.Message}
+	}
+	return &Error{StatusCode: status, Message: strings.TrimSpace(string(body))}
+}
+
+func retryable(status int) bool {
+	switch status {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// do makes a request, retrying failed attempts, and returns the status and
+// body of the final response. Statuses listed in final are never retried.
+func (c *HttpClient) do(ctx context.Context, method string, path string, body []byte, final ...int) (int, []byte, error) {
+	for attempt := 0; ; attempt++ {
+		status, header, response, err := c.attempt(ctx, method, path, body)
+		if ctx.Err() != nil {
+			return 0, nil, ctx.Err()
+		}
+
+		retry := err != nil || retryable(status)
+		for _, s := range final {
+			if status == s {
+				retry = false
+			}
+		}
+		if !retry || attempt >= c.Retries {
+			return status, response, err
+		}
+
+		timer := time.NewTimer(c.backoff(attempt, header))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return 0, nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+func (c *HttpClient) attempt(ctx context.Context, method string, path string, body []byte) (int, http.Header, []byte, error) {
+	if c.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.Timeout)
+		defer cancel()
+	}
+
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseUrl+path, reader)
+	if err != nil {
+		return 0, nil, nil, err
+	}
+
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.ApiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.ApiKey)
+	}
+	if c.UserAgent != "" {
+		req.Header.Set("User-Agent", c.UserAgent)
+	}
+
+	resp, err := c.Http.Do(req)
+	if err != nil {
+		return 0, nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	response, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, nil, nil, err
+	}
+	return resp.StatusCode, resp.Header, response, nil
+}
+
+// backoff returns how long to wait before retrying attempt, preferring the
+// server's Retry-After when it gave one.
+func (c *HttpClient) backoff(attempt int, header http.Header) time.Duration {
+	if header != nil {
+		if seconds, err := strconv.Atoi(header.Get("Retry-After")); err == nil && seconds >= 0 {
+			if delay := time.Duration(seconds) * time.Second; c.MaxBackoff <= 0 || delay <= c.MaxBackoff {
+				return delay
+			}
+			return c.MaxBackoff
+		}
+	}
+
+	if c.Backoff <= 0 {
+		return 0
+	}
+
+	delay := c.Backoff << uint(attempt)
+	if delay <= 0 || (c.MaxBackoff > 0 && delay > c.MaxBackoff) {
+		delay = c.MaxBackoff
+	}
+
+	// full jitter, so that clients failing together don't retry together
+	return time.Duration(rand.Int63n(int64(delay) + 1))
+}
@@ -0,0 +1,134 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rabbitt/maxmind/mm"
+)
+
+// testClient returns a client of a server answering with handle, retrying
+// without delay.
+func testClient(t *testing.T, handle http.HandlerFunc) *HttpClient {
+	server := httptest.NewServer(handle)
+	t.Cleanup(server.Close)
+
+	c, err := NewClient(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(c.Close)
+	c.Backoff = time.Millisecond
+	return c
+}
+
+func TestClientRetries(t *testing.T) {
+	var attempts int32
+	c := testClient(t, func(writer http.ResponseWriter, req *http.Request) {
+		if atomic.AddInt32(&attempts, 1) < 3 {
+			writer.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		writer.Write([]byte(`{"status": "success", "message": "OK", "data": {"country": {"iso_code": "GB"}}}`))
+	})
+
+	data, err := c.Lookup(context.Background(), "81.2.69.142")
+	if err != nil || data.Country.IsoCode != "GB" || attempts != 3 {
+		t.Errorf("Lookup = %+v, %v after %d attempts", data, err, attempts)
+	}
+}
+
+func TestClientGivesUp(t *testing.T) {
+	var attempts int32
+	c := testClient(t, func(writer http.ResponseWriter, req *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		writer.Header().Set("Retry-After", "0")
+		writer.WriteHeader(http.StatusTooManyRequests)
+		writer.Write([]byte(`{"status": "error", "message": "rate limit exceeded"}`))
+	})
+
+	var clientErr *Error
+	_, err := c.Lookup(context.Background(), "81.2.69.142")
+	if !errors.As(err, &clientErr) || clientErr.StatusCode != http.StatusTooManyRequests ||
+		clientErr.Message != "rate limit exceeded" || attempts != int32(DefaultRetries+1) {
+		t.Errorf("Lookup = %v after %d attempts; want a 429 *Error after %d", err, attempts, DefaultRetries+1)
+	}
+}
+
+func TestClientErrors(t *testing.T) {
+	for _, test := range []struct {
+		status  int
+		body    string
+		want    int
+		message string
+	}{
+		{http.StatusUnprocessableEntity, `{"status": "error", "message": "unable to decode ip"}`, 422, "unable to decode ip"},
+		{http.StatusUnauthorized, "no\n", 401, "no"},
+		{http.StatusBadGateway, `{"status": "error", "message": "upstream failed"}`, 502, "upstream failed"},
+		{http.StatusOK, `{"status": "error", "message": "lookup failed"}`, 200, "lookup failed"},
+	} {
+		var attempts int32
+		c := testClient(t, func(writer http.ResponseWriter, req *http.Request) {
+			atomic.AddInt32(&attempts, 1)
+			writer.WriteHeader(test.status)
+			writer.Write([]byte(test.body))
+		})
+		c.Retries = 1
+
+		var clientErr *Error
+		data, err := c.Lookup(context.Background(), "81.2.69.142")
+		if data != nil || !errors.As(err, &clientErr) || clientErr.StatusCode != test.want || clientErr.Message != test.message {
+			t.Errorf("%d %s = %+v, %v; want a %d *Error", test.status, test.body, data, err, test.want)
+		}
+		if retried := attempts > 1; retried != retryable(test.status) {
+			t.Errorf("%d was attempted %d times", test.status, attempts)
+		}
+	}
+}
+
+func TestUpstream(t *testing.T) {
+	var apiKey string
+	c := testClient(t, func(writer http.ResponseWriter, req *http.Request) {
+		apiKey = req.Header.Get("Authorization")
+		switch req.URL.Path {
+		case "/info":
+			writer.Write([]byte(`{"database": {"type": "GeoIP2-City"}}`))
+		case "/ip/81.2.69.142":
+			writer.Write([]byte(`{"status": "success", "message": "OK", "data": {"country": {"iso_code": "GB", "name": "United Kingdom"}}}`))
+		case "/ip/10.0.0.1":
+			writer.Write([]byte(`{"status": "success", "message": "OK", "data": {}}`))
+		default:
+			writer.WriteHeader(http.StatusServiceUnavailable)
+		}
+	})
+	c.ApiKey = "secret"
+	c.Retries = 0
+
+	upstream := &Upstream{Client: c}
+	if err := upstream.Reload(); err != nil || upstream.Metadata().Type != "GeoIP2-City" {
+		t.Fatalf("Reload = %v", err)
+	}
+	if apiKey != "Bearer secret" {
+		t.Errorf("upstream sent Authorization %q", apiKey)
+	}
+
+	if data, err := upstream.LookupIP(context.Background(), net.ParseIP("81.2.69.142")); err != nil || data.Country.Name != "United Kingdom" {
+		t.Errorf("LookupIP = %+v, %v", data, err)
+	}
+
+	var notFound *mm.NotFoundError
+	if _, err := upstream.LookupIP(context.Background(), net.ParseIP("10.0.0.1")); !errors.As(err, &notFound) {
+		t.Errorf("LookupIP of an unknown ip = %v; want a *mm.NotFoundError", err)
+	}
+
+	var clientErr *Error
+	if _, err := upstream.LookupIP(context.Background(), net.ParseIP("192.0.2.1")); !errors.As(err, &clientErr) || clientErr.StatusCode != 503 {
+		t.Errorf("LookupIP of a failing server = %v; want a 503 *Error", err)
+	}
+}
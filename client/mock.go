@@ -0,0 +1,69 @@
+package client
+
+import (
+	"context"
+	"errors"
+
+	"github.com/rabbitt/maxmind/mm"
+)
+
+// ErrNotMocked is returned by a MockClient for calls it has no function for.
+var ErrNotMocked = errors.New("call not mocked")
+
+// MockClient is a Client answering each call with the matching function,
+// for use in tests of code that depends on a Client.
+type MockClient struct {
+	LookupFunc      func(ctx context.Context, ip string) (*mm.GeoData, error)
+	BatchLookupFunc func(ctx context.Context, ips []string) ([]*mm.LookupResult, error)
+	InfoFunc        func(ctx context.Context) (*mm.ServerInfo, error)
+	AliveFunc       func(ctx context.Context) error
+	HealthFunc      func(ctx context.Context) (*mm.HealthResponse, error)
+	UsageFunc       func(ctx context.Context) ([]mm.ApiKeyUsage, error)
+}
+
+func (m *MockClient) Lookup(ctx context.Context, ip string) (*mm.GeoData, error) {
+	if m.LookupFunc == nil {
+		return nil, ErrNotMocked
+	}
+	return m.LookupFunc(ctx, ip)
+}
+
+func (m *MockClient) BatchLookup(ctx context.Context, ips []string) ([]*mm.LookupResult, error) {
+	if m.BatchLookupFunc == nil {
+		return nil, ErrNotMocked
+	}
+	return m.BatchLookupFunc(ctx, ips)
+}
+
+func (m *MockClient) Info(ctx context.Context) (*mm.ServerInfo, error) {
+	if m.InfoFunc == nil {
+		return nil, ErrNotMocked
+	}
+	return m.InfoFunc(ctx)
+}
+
+func (m *MockClient) Alive(ctx context.Context) error {
+	if m.AliveFunc == nil {
+		return ErrNotMocked
+	}
+	return m.AliveFunc(ctx)
+}
+
+func (m *MockClient) Health(ctx context.Context) (*mm.HealthResponse, error) {
+	if m.HealthFunc == nil {
+		return nil, ErrNotMocked
+	}
+	return m.HealthFunc(ctx)
+}
+
+func (m *MockClient) Usage(ctx context.Context) ([]mm.ApiKeyUsage, error) {
+	if m.UsageFunc == nil {
+		return nil, ErrNotMocked
+	}
+	return m.UsageFunc(ctx)
+}
+
+var (
+	_ Client = (*HttpClient)(nil)
+	_ Client = (*MockClient)(nil)
+)
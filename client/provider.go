@@ -0,0 +1,93 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+
+	"github.com/rabbitt/maxmind/mm"
+)
+
+// Upstream is an mm.Provider answering lookups from another maxmind server,
+// so that a server, or the lookup tool, can front a shared one.
+type Upstream struct {
+	Client Client
+
+	info *mm.DatabaseInfo
+	lock sync.RWMutex
+}
+
+// NewUpstream returns a provider answering from the server at baseUrl,
+// authenticating with apiKey when it is set, and fetching the description
+// of its database to check that it is up. Failures are reported as a
+// *mm.DatabaseError.
+func NewUpstream(baseUrl string, apiKey string) (*Upstream, error) {
+	c, err := NewClient(baseUrl)
+	if err != nil {
+		return nil, &mm.DatabaseError{Path: baseUrl, Err: err}
+	}
+	c.ApiKey = apiKey
+
+	upstream := &Upstream{Client: c}
+	if err = upstream.Reload(); err != nil {
+		c.Close()
+		return nil, &mm.DatabaseError{Path: baseUrl, Err: err}
+	}
+	return upstream, nil
+}
+
+// LookupIP returns the geodata the upstream server has for ip, or a
+// *mm.NotFoundError when it has none.
+func (u *Upstream) LookupIP(ctx context.Context, ip net.IP) (*mm.GeoData, error) {
+	if ip == nil {
+		return nil, &mm.InvalidIpError{}
+	}
+
+	data, err := u.Client.Lookup(ctx, ip.String())
+	if err != nil {
+		return nil, err
+	}
+	if data == nil || data.Unknown() {
+		return nil, &mm.NotFoundError{Ip: ip}
+	}
+	return data, nil
+}
+
+// Metadata describes the upstream server's database, as of when the
+// provider was created or last reloaded.
+func (u *Upstream) Metadata() *mm.DatabaseInfo {
+	u.lock.RLock()
+	defer u.lock.RUnlock()
+
+	return u.info
+}
+
+// Reload refetches the description of the upstream server's database.
+func (u *Upstream) Reload() error {
+	info, err := u.Client.Info(context.Background())
+	if err != nil {
+		return err
+	}
+	if info.Database == nil {
+		return errors.New("upstream server did not describe its database")
+	}
+
+	u.lock.Lock()
+	defer u.lock.Unlock()
+
+	u.info = info.Database
+	return nil
+}
+
+// Close releases the pooled connections to the upstream server.
+func (u *Upstream) Close() {
+	if c, ok := u.Client.(*HttpClient); ok {
+		c.Close()
+	}
+}
+
+var (
+	_ mm.Provider = (*Upstream)(nil)
+	_ mm.Reloader = (*Upstream)(nil)
+)
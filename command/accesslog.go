@@ -0,0 +1,109 @@
+package command
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"time"
+
+	"github.com/rabbitt/maxmind/mm"
+)
+
+// responseRecorder captures the status and size of a response as it is
+// written.
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecorder) Write(data []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	n, err := r.ResponseWriter.Write(data)
+	r.bytes += int64(n)
+	return n, err
+}
+
+// maxRequestIdLength bounds the length of the X-Request-ID a client may
+// send.
+const maxRequestIdLength = 64
+
+// validRequestId reports whether id is short, and made of letters, digits,
+// dots, dashes and underscores alone, so that it is safe to echo and log.
+func validRequestId(id string) bool {
+	if id == "" || len(id) > maxRequestIdLength {
+		return false
+	}
+	for _, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '-', r == '_':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// requestId returns the request's X-Request-ID, generating one if the
+// client did not send a valid one.
+func requestId(req *http.Request) string {
+	if id := req.Header.Get("X-Request-ID"); validRequestId(id) {
+		return id
+	}
+
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(buf)
+}
+
+// AccessLogHandler records every request handled by Handler in Log.
+type AccessLogHandler struct {
+	Handler http.Handler
+	Log     *mm.AccessLog
+	Ui      Ui
+}
+
+func (h *AccessLogHandler) ServeHTTP(writer http.ResponseWriter, req *http.Request) {
+	start := time.Now()
+
+	id := requestId(req)
+	if id != "" {
+		writer.Header().Set("X-Request-ID", id)
+	}
+
+	recorder := &responseRecorder{ResponseWriter: writer}
+	h.Handler.ServeHTTP(recorder, req)
+
+	if recorder.status == 0 {
+		recorder.status = http.StatusOK
+	}
+
+	err := h.Log.Log(&mm.AccessLogEntry{
+		Time:      start,
+		RequestId: id,
+		ClientIp:  clientIp(req),
+		Method:    req.Method,
+		Path:      req.URL.RequestURI(),
+		Protocol:  req.Proto,
+		Status:    recorder.status,
+		Bytes:     recorder.bytes,
+		Latency:   float64(time.Since(start)) / float64(time.Millisecond),
+		Cache:     writer.Header().Get("X-Cache"),
+		Referer:   req.Referer(),
+		UserAgent: req.UserAgent(),
+	})
+	if err != nil {
+		h.Ui.Errorf("failed to write access log: %s\n", err)
+	}
+}
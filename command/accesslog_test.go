@@ -0,0 +1,31 @@
+package command
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRequestId(t *testing.T) {
+	for id, valid := range map[string]bool{
+		"4f1c2e9a-7b3d-4c8e":    true,
+		"req_42.retry":          true,
+		"":                      false,
+		"a b":                   false,
+		`"quoted"`:              false,
+		"line\nbreak":           false,
+		strings.Repeat("a", 64): true,
+		strings.Repeat("a", 65): false,
+	} {
+		req := httptest.NewRequest("GET", "/ip/81.2.69.142", nil)
+		req.Header.Set("X-Request-ID", id)
+
+		got := requestId(req)
+		if valid && got != id {
+			t.Errorf("requestId(%q) = %q; want it echoed", id, got)
+		}
+		if !valid && (got == id || !validRequestId(got)) {
+			t.Errorf("requestId(%q) = %q; want a generated id", id, got)
+		}
+	}
+}
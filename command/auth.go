@@ -0,0 +1,123 @@
+package command
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/pquerna/ffjson/ffjson"
+	"github.com/rabbitt/maxmind/mm"
+)
+
+// requestApiKey extracts the api key from either an `Authorization: Bearer`
+// or an `X-API-Key` header.
+func requestApiKey(req *http.Request) string {
+	if auth := req.Header.Get("Authorization"); auth != "" {
+		if fields := strings.Fields(auth); len(fields) == 2 && strings.EqualFold(fields[0], "Bearer") {
+			return fields[1]
+		}
+	}
+	return req.Header.Get("X-API-Key")
+}
+
+func writeJsonError(writer http.ResponseWriter, code int, message string) {
+	j, _ := ffjson.Marshal(&mm.JsonResponse{
+		Status:  "error",
+		Message: message,
+	})
+
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(code)
+	writer.Write(j)
+}
+
+// authenticate wraps handle, registered under route, so that it requires a
+// valid api key when api keys are configured.
+func (c *ServerCommand) authenticate(route string, handle httprouter.Handle) httprouter.Handle {
+	return func(writer http.ResponseWriter, req *http.Request, params httprouter.Params) {
+		if c.apiKeys == nil {
+			handle(writer, req, params)
+			return
+		}
+
+		key := requestApiKey(req)
+		if key == "" {
+			writer.Header().Set("WWW-Authenticate", `Bearer realm="maxmind"`)
+			writeJsonError(writer, http.StatusUnauthorized, "missing api key")
+			return
+		}
+
+		apiKey, allowance, err := c.apiKeys.Authorize(key, route)
+		switch err {
+		case nil:
+			if allowance.Limit > 0 {
+				setRateLimitHeaders(writer, allowance)
+			}
+			handle(writer, req, params)
+		case mm.ErrApiKeyUnknown:
+			writer.Header().Set("WWW-Authenticate", `Bearer realm="maxmind", error="invalid_token"`)
+			writeJsonError(writer, http.StatusUnauthorized, err.Error())
+		case mm.ErrApiKeyRouteDenied:
+			writeJsonError(writer, http.StatusForbidden, err.Error())
+		case mm.ErrApiKeyQuotaReached:
+			setRateLimitHeaders(writer, allowance)
+			writeJsonError(writer, http.StatusTooManyRequests, err.Error())
+			c.Ui.Warnf("rate limited request for %s from api key %s\n", req.URL.Path, apiKey.Name)
+		}
+	}
+}
+
+// chargeApiKey charges the api key req was authorized with cost more
+// tokens of its rate limit, answering req with a 429, and returning false,
+// when they are not available.
+func (c *ServerCommand) chargeApiKey(writer http.ResponseWriter, req *http.Request, cost int) bool {
+	if c.apiKeys == nil || cost <= 0 {
+		return true
+	}
+
+	allowance, err := c.apiKeys.ChargeN(requestApiKey(req), cost)
+	switch err {
+	case nil:
+		if allowance.Limit > 0 {
+			setRateLimitHeaders(writer, allowance)
+		}
+		return true
+	case mm.ErrApiKeyQuotaReached:
+		setRateLimitHeaders(writer, allowance)
+		writeJsonError(writer, http.StatusTooManyRequests, err.Error())
+	default:
+		// the key was removed by a reload since the request was authorized
+		writeJsonError(writer, http.StatusUnauthorized, err.Error())
+	}
+	return false
+}
+
+// usageHandler reports the number of requests made, and rejected, by the
+// caller's key, or by every key when the caller's is an admin key.
+func (c *ServerCommand) usageHandler(writer http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	usage := []mm.ApiKeyUsage{}
+	if c.apiKeys != nil {
+		usage = c.apiKeys.Usage(c.apiKeys.Find(requestApiKey(req)))
+	}
+
+	j, err := ffjson.Marshal(usage)
+	if err != nil {
+		c.Ui.Error(err)
+		writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	writer.Header().Set("Content-Type", "application/json")
+	writer.Write(j)
+}
+
+func (c *ServerCommand) reloadApiKeys() {
+	if c.apiKeys == nil {
+		return
+	}
+
+	c.Ui.Infof("Reloading api keys from %s ...\n", c.Config.ApiKeysFile)
+	if err := c.apiKeys.Reload(); err != nil {
+		c.Ui.Errorf("failed to reload api keys; continuing with the previous ones. error was: %s\n", err)
+	}
+}
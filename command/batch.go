@@ -0,0 +1,81 @@
+package command
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/pquerna/ffjson/ffjson"
+	"github.com/rabbitt/maxmind/mm"
+)
+
+// maxBatchBody bounds the size of a batch request body.
+const maxBatchBody = 1 << 20
+
+// batchHandler answers POST /batch, looking up each ip of the request in
+// order. Invalid ips are reported per result rather than failing the batch.
+func (c *ServerCommand) batchHandler(writer http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	body, err := io.ReadAll(io.LimitReader(req.Body, maxBatchBody+1))
+	if err != nil {
+		writeJsonError(writer, http.StatusBadRequest, "unable to read request body")
+		return
+	}
+	if len(body) > maxBatchBody {
+		writeJsonError(writer, http.StatusRequestEntityTooLarge, "request body too large")
+		return
+	}
+
+	var batch mm.BatchRequest
+	if err = ffjson.Unmarshal(body, &batch); err != nil {
+		writeJsonError(writer, http.StatusBadRequest, fmt.Sprintf("unable to decode request: %s", err))
+		return
+	}
+
+	switch {
+	case len(batch.Ips) == 0:
+		writeJsonError(writer, http.StatusBadRequest, "no ips to look up")
+		return
+	case len(batch.Ips) > mm.MaxBatchSize:
+		writeJsonError(writer, http.StatusRequestEntityTooLarge,
+			fmt.Sprintf("too many ips; at most %d may be looked up per request", mm.MaxBatchSize))
+		return
+	}
+
+	// the request has already been charged for its first ip
+	if !c.takeRateLimit(writer, req, len(batch.Ips)-1) || !c.chargeApiKey(writer, req, len(batch.Ips)-1) {
+		return
+	}
+
+	response := &mm.BatchResponse{
+		Status:  "success",
+		Message: "OK",
+		Results: make([]*mm.LookupResult, 0, len(batch.Ips)),
+	}
+
+	for _, ipText := range batch.Ips {
+		if req.Context().Err() != nil {
+			// the client has gone away; nobody is left to answer
+			return
+		}
+
+		result := &mm.LookupResult{Ip: ipText, Status: "success", Message: "OK"}
+		if record, origins, err := c.lookupRecord(req.Context(), ipText); err != nil {
+			result.Status = "error"
+			result.Message = err.Error()
+		} else {
+			result.Data, result.Origins = record, origins
+		}
+		response.Results = append(response.Results, result)
+	}
+
+	j, err := ffjson.Marshal(response)
+	if err != nil {
+		c.Ui.Error(err)
+		writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	writer.Header().Set("Content-Type", "application/json")
+	writer.Write(j)
+}
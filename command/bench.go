@@ -0,0 +1,435 @@
+package command
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"math"
+	"math/rand"
+	"net"
+	"net/http"
+	"os"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pmylund/go-cache"
+	"github.com/pquerna/ffjson/ffjson"
+	"github.com/rabbitt/maxmind/client"
+	"github.com/rabbitt/maxmind/mm"
+)
+
+var BenchTargets = map[string]bool{
+	"db":    true,
+	"cache": true,
+	"http":  true,
+}
+
+var BenchOutputTypes = map[string]bool{
+	"json":  true,
+	"table": true,
+}
+
+type BenchCommand struct {
+	Ui Ui
+}
+
+// defaultBenchSeed seeds the random ips, so that runs compare the same
+// workload unless asked otherwise.
+const defaultBenchSeed = 1
+
+// BenchLatency summarizes the latency of the requests, in milliseconds.
+type BenchLatency struct {
+	Min  float64 `json:"min"`
+	Mean float64 `json:"mean"`
+	P50  float64 `json:"p50"`
+	P99  float64 `json:"p99"`
+	P999 float64 `json:"p999"`
+	Max  float64 `json:"max"`
+}
+
+type BenchResult struct {
+	Target      string       `json:"target"`
+	Workload    string       `json:"workload"`
+	Seed        int64        `json:"seed,omitempty"`
+	Ips         int          `json:"ips"`
+	Concurrency int          `json:"concurrency"`
+	Requests    int          `json:"requests"`
+	Errors      int          `json:"errors"`
+	Elapsed     float64      `json:"elapsed_seconds"`
+	Throughput  float64      `json:"requests_per_second"`
+	Latency     BenchLatency `json:"latency_ms"`
+	AllocsPerOp float64      `json:"allocs_per_op"`
+	BytesPerOp  float64      `json:"bytes_per_op"`
+}
+
+// randomIps generates count random, globally routable looking, ips; a
+// fraction ipv6 of them IPv6.
+func randomIps(count int, ipv6 float64, rng *rand.Rand) []string {
+	ips := make([]string, 0, count)
+	for len(ips) < count {
+		var ip net.IP
+		if rng.Float64() < ipv6 {
+			ip = make(net.IP, net.IPv6len)
+			rng.Read(ip)
+			ip[0] = 0x20 // 2000::/3, global unicast
+			ip[1] &= 0x0f
+		} else {
+			ip = make(net.IP, net.IPv4len)
+			rng.Read(ip)
+		}
+		if !ip.IsGlobalUnicast() || ip.IsPrivate() {
+			continue
+		}
+		ips = append(ips, ip.String())
+	}
+	return ips
+}
+
+// replayIps reads the ips to replay, one per line, from path, or from
+// stdin when path is "-". Blank lines and # comments are skipped.
+func replayIps(path string) ([]string, error) {
+	file := os.Stdin
+	if path != "-" {
+		var err error
+		if file, err = os.Open(path); err != nil {
+			return nil, &mm.PathError{Path: path, Err: err}
+		}
+		defer file.Close()
+	}
+
+	var ips []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		ips = append(ips, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(ips) == 0 {
+		return nil, fmt.Errorf("no ips to replay in %s", path)
+	}
+	return ips, nil
+}
+
+// percentile returns the p-th percentile, by nearest rank, of sorted.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	idx := int(math.Ceil(p*float64(len(sorted)))) - 1
+	if idx < 0 {
+		idx = 0
+	}
+	return sorted[idx]
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// run calls lookup for the ips, round robin, from concurrency workers
+// until requests have been made or, when duration is set, until it has
+// passed.
+func (c *BenchCommand) run(ips []string, concurrency int, requests int, duration time.Duration,
+	lookup func(ctx context.Context, idx int) error) (*BenchResult, error) {
+
+	ctx := context.Background()
+	if duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, duration)
+		defer cancel()
+		requests = math.MaxInt32
+	}
+
+	var next int64 = -1
+	var failed int64
+	latencies := make([][]time.Duration, concurrency)
+	if duration <= 0 {
+		// sized up front, so that recording latencies doesn't allocate
+		for worker := range latencies {
+			latencies[worker] = make([]time.Duration, 0, requests/concurrency+1)
+		}
+	}
+
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+
+	start := time.Now()
+	var wg sync.WaitGroup
+	for worker := 0; worker < concurrency; worker++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			for ctx.Err() == nil {
+				op := atomic.AddInt64(&next, 1)
+				if op >= int64(requests) {
+					return
+				}
+
+				began := time.Now()
+				err := lookup(ctx, int(op)%len(ips))
+				latencies[worker] = append(latencies[worker], time.Since(began))
+				if err != nil && ctx.Err() == nil {
+					atomic.AddInt64(&failed, 1)
+				}
+			}
+		}(worker)
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	runtime.ReadMemStats(&after)
+
+	var all []time.Duration
+	for _, l := range latencies {
+		all = append(all, l...)
+	}
+	if len(all) == 0 {
+		return nil, errors.New("no requests were made")
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i] < all[j] })
+
+	var total time.Duration
+	for _, l := range all {
+		total += l
+	}
+
+	ops := float64(len(all))
+	return &BenchResult{
+		Ips:         len(ips),
+		Concurrency: concurrency,
+		Requests:    len(all),
+		Errors:      int(failed),
+		Elapsed:     elapsed.Seconds(),
+		Throughput:  ops / elapsed.Seconds(),
+		Latency: BenchLatency{
+			Min:  milliseconds(all[0]),
+			Mean: milliseconds(total) / ops,
+			P50:  milliseconds(percentile(all, 0.50)),
+			P99:  milliseconds(percentile(all, 0.99)),
+			P999: milliseconds(percentile(all, 0.999)),
+			Max:  milliseconds(all[len(all)-1]),
+		},
+		AllocsPerOp: float64(after.Mallocs-before.Mallocs) / ops,
+		BytesPerOp:  float64(after.TotalAlloc-before.TotalAlloc) / ops,
+	}, nil
+}
+
+func (c *BenchCommand) outputAsTable(result *BenchResult) {
+	c.Ui.Outputf("Target:       %s\n", result.Target)
+	if result.Workload == "random" {
+		c.Ui.Outputf("Workload:     %s (%d ips, seed %d)\n", result.Workload, result.Ips, result.Seed)
+	} else {
+		c.Ui.Outputf("Workload:     %s (%d ips)\n", result.Workload, result.Ips)
+	}
+	c.Ui.Outputf("Concurrency:  %d\n", result.Concurrency)
+	c.Ui.Outputf("Requests:     %d (%d errors)\n", result.Requests, result.Errors)
+	c.Ui.Outputf("Elapsed:      %.3fs\n", result.Elapsed)
+	c.Ui.Outputf("Throughput:   %.2f requests/s\n", result.Throughput)
+	c.Ui.Outputf("Latency:      min %.4fms  mean %.4fms  p50 %.4fms  p99 %.4fms  p999 %.4fms  max %.4fms\n",
+		result.Latency.Min, result.Latency.Mean, result.Latency.P50, result.Latency.P99, result.Latency.P999, result.Latency.Max)
+	c.Ui.Outputf("Allocations:  %.2f allocs/op, %.0f B/op\n", result.AllocsPerOp, result.BytesPerOp)
+}
+
+func (c *BenchCommand) Help() string {
+	return fmt.Sprintf(`Usage: %s bench [options]
+
+Benchmarks lookups against the database, the database behind the server's
+cache, or a running server, reporting throughput, latency percentiles and
+allocations.
+
+Options:
+  -t, -bench.target     <string>    What to benchmark; one of db, cache, or http
+                                    (default: %s)
+  -f, -database.file    <file>      Path to MaxMind Database, for the db and cache
+                                    targets (default: %s)
+  -u, -server.url       <url>       URL of the server, for the http target
+                                    (default: %s)
+  -k, -auth.key         <string>    API key to send to the server
+  -i, -input            <file>      File of ips to replay, one per line, or - for
+                                    stdin. (default: random ips)
+  -r, -random.count     <integer>   Number of random ips to generate (default: %d)
+  -6, -random.ipv6      <float>     Fraction of random ips that are IPv6
+                                    (default: %.2f)
+  -s, -random.seed      <integer>   Seed of the random ips (default: %d)
+  -c, -concurrency      <integer>   Number of concurrent workers (default: %d)
+  -n, -requests         <integer>   Number of requests to make (default: %d)
+  -d, -duration         <duration>  Run for this long instead of a number of
+                                    requests, e.g. 30s
+  -T, -cache.ttl        <float>     Cache TTL, in seconds, of the cache target
+                                    (default: %.2f)
+  -o, -output.type      <string>    Render mode (one of: json, or table)
+                                    (default: %s)
+`, os.Args[0], "db", DefaultDatabasePath, "http://127.0.0.1:8000", 10000, 0.1, defaultBenchSeed, runtime.NumCPU(), 100000, float64(3600), "table")
+}
+
+func (c *BenchCommand) Synopsis() string {
+	return "Benchmark lookups against the database or a running server"
+}
+
+// Execute runs the benchmark and reports its results.
+func (c *BenchCommand) Execute(args []string) error {
+	var err error
+
+	var mainParse = flag.NewFlagSet("bench", flag.ContinueOnError)
+	target := mainParse.String("bench.target", "db", "what to benchmark; one of 'db', 'cache', or 'http'")
+	mainParse.StringVar(target, "t", "db", "what to benchmark; one of 'db', 'cache', or 'http'")
+	dbFile := mainParse.String("database.file", DefaultDatabasePath, "`path` to the database file that contains GeoIP information")
+	mainParse.StringVar(dbFile, "f", DefaultDatabasePath, "`path` to the database file that contains GeoIP information")
+	serverUrl := mainParse.String("server.url", "http://127.0.0.1:8000", "`url` of the server, for the http target")
+	mainParse.StringVar(serverUrl, "u", "http://127.0.0.1:8000", "`url` of the server, for the http target")
+	apiKey := mainParse.String("auth.key", "", "api `key` to send to the server")
+	mainParse.StringVar(apiKey, "k", "", "api `key` to send to the server")
+	input := mainParse.String("input", "", "`file` of ips to replay, or - for stdin")
+	mainParse.StringVar(input, "i", "", "`file` of ips to replay, or - for stdin")
+	randomCount := mainParse.Int("random.count", 10000, "`number` of random ips to generate")
+	mainParse.IntVar(randomCount, "r", 10000, "`number` of random ips to generate")
+	randomIpv6 := mainParse.Float64("random.ipv6", 0.1, "`fraction` of random ips that are IPv6")
+	mainParse.Float64Var(randomIpv6, "6", 0.1, "`fraction` of random ips that are IPv6")
+	randomSeed := mainParse.Int64("random.seed", defaultBenchSeed, "`seed` of the random ips")
+	mainParse.Int64Var(randomSeed, "s", defaultBenchSeed, "`seed` of the random ips")
+	concurrency := mainParse.Int("concurrency", runtime.NumCPU(), "`number` of concurrent workers")
+	mainParse.IntVar(concurrency, "c", runtime.NumCPU(), "`number` of concurrent workers")
+	requests := mainParse.Int("requests", 100000, "`number` of requests to make")
+	mainParse.IntVar(requests, "n", 100000, "`number` of requests to make")
+	duration := mainParse.Duration("duration", 0, "`duration` to run for instead of a number of requests")
+	mainParse.DurationVar(duration, "d", 0, "`duration` to run for instead of a number of requests")
+	cacheTtl := mainParse.Float64("cache.ttl", float64(3600), "cache `ttl`, in seconds, of the cache target")
+	mainParse.Float64Var(cacheTtl, "T", float64(3600), "cache `ttl`, in seconds, of the cache target")
+	outType := mainParse.String("output.type", "table", "Output `type`; one of 'json', or 'table'")
+	mainParse.StringVar(outType, "o", "table", "Output `type`; one of 'json', or 'table'")
+
+	mainParse.Usage = func() {
+		c.Ui.Output(c.Help())
+		mainParse.PrintDefaults()
+	}
+	if err = mainParse.Parse(args); err != nil {
+		return usageError(err)
+	}
+
+	switch {
+	case !BenchTargets[*target]:
+		return usageError(fmt.Errorf("invalid bench target '%s'; expected one of 'db', 'cache', or 'http'", *target))
+	case !BenchOutputTypes[*outType]:
+		return usageError(fmt.Errorf("invalid output type '%s'; expected one of 'json', or 'table'", *outType))
+	case *concurrency < 1:
+		return usageError(errors.New("concurrency must be at least 1"))
+	case *requests < 1 && *duration <= 0:
+		return usageError(errors.New("requests must be at least 1"))
+	case *input == "" && *randomCount < 1:
+		return usageError(errors.New("random count must be at least 1"))
+	}
+
+	var ips []string
+	workload := "random"
+	if *input != "" {
+		workload = "replay"
+		if ips, err = replayIps(*input); err != nil {
+			return err
+		}
+	} else {
+		ips = randomIps(*randomCount, *randomIpv6, rand.New(rand.NewSource(*randomSeed)))
+	}
+
+	var lookup func(ctx context.Context, idx int) error
+
+	switch *target {
+	case "db", "cache":
+		dbPath, err := mm.NewPathname(*dbFile).RealPath()
+		if err != nil {
+			return err
+		}
+
+		database, err := mm.OpenDatabase(dbPath.Path())
+		if err != nil {
+			return err
+		}
+		defer database.Close()
+
+		parsed := make([]net.IP, len(ips))
+		for idx, ipText := range ips {
+			parsed[idx] = net.ParseIP(ipText)
+		}
+
+		lookupIp := func(ctx context.Context, idx int) (*mm.GeoData, error) {
+			record, err := database.LookupIP(ctx, parsed[idx])
+			if _, notFound := err.(*mm.NotFoundError); notFound {
+				return &mm.GeoData{}, nil
+			}
+			return record, err
+		}
+
+		lookup = func(ctx context.Context, idx int) error {
+			_, err := lookupIp(ctx, idx)
+			return err
+		}
+
+		if *target == "cache" {
+			memCache := cache.New(time.Duration(*cacheTtl*float64(time.Second)), 1*time.Second)
+			lookup = func(ctx context.Context, idx int) error {
+				if _, found := memCache.Get(ips[idx]); found {
+					return nil
+				}
+				record, err := lookupIp(ctx, idx)
+				if err != nil {
+					return err
+				}
+				memCache.Set(ips[idx], record, cache.DefaultExpiration)
+				return nil
+			}
+		}
+
+	case "http":
+		httpClient, err := client.NewClient(*serverUrl)
+		if err != nil {
+			return usageError(err)
+		}
+		defer httpClient.Close()
+
+		// every failure should count, rather than be hidden by a retry
+		httpClient.Retries = 0
+		httpClient.ApiKey = *apiKey
+		if transport, ok := httpClient.Http.Transport.(*http.Transport); ok && transport.MaxIdleConnsPerHost < *concurrency {
+			transport.MaxIdleConnsPerHost = *concurrency
+		}
+
+		lookup = func(ctx context.Context, idx int) error {
+			_, err := httpClient.Lookup(ctx, ips[idx])
+			return err
+		}
+	}
+
+	result, err := c.run(ips, *concurrency, *requests, *duration, lookup)
+	if err != nil {
+		return err
+	}
+	result.Target = *target
+	result.Workload = workload
+	if workload == "random" {
+		result.Seed = *randomSeed
+	}
+
+	switch *outType {
+	case "json":
+		j, err := ffjson.Marshal(result)
+		if err != nil {
+			return err
+		}
+		c.Ui.Output(string(j))
+	case "table":
+		c.outputAsTable(result)
+	}
+
+	return nil
+}
@@ -0,0 +1,108 @@
+package command
+
+import (
+	"encoding/json"
+	"math/rand"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func newBenchCommand(ui Ui) executor {
+	return &BenchCommand{Ui: ui}
+}
+
+func TestPercentile(t *testing.T) {
+	sorted := []time.Duration{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+	for _, test := range []struct {
+		sorted []time.Duration
+		p      float64
+		want   time.Duration
+	}{
+		{sorted, 0, 1},
+		{sorted, 0.5, 5},
+		{sorted, 0.9, 9},
+		{sorted, 0.95, 10},
+		{sorted, 0.99, 10},
+		{sorted, 1, 10},
+		{sorted[:1], 0.99, 1},
+		{nil, 0.5, 0},
+	} {
+		if got := percentile(test.sorted, test.p); got != test.want {
+			t.Errorf("percentile(%v, %v) = %v; want %v", test.sorted, test.p, got, test.want)
+		}
+	}
+}
+
+func TestRandomIps(t *testing.T) {
+	for _, ipv6 := range []float64{0, 0.5, 1} {
+		ips := randomIps(200, ipv6, rand.New(rand.NewSource(1)))
+		if len(ips) != 200 {
+			t.Fatalf("randomIps(200, %v) returned %d ips", ipv6, len(ips))
+		}
+
+		var v6 int
+		for _, text := range ips {
+			ip := net.ParseIP(text)
+			if ip == nil || !ip.IsGlobalUnicast() || ip.IsPrivate() {
+				t.Errorf("randomIps(200, %v) returned %q", ipv6, text)
+			} else if ip.To4() == nil {
+				v6++
+			}
+		}
+		if (ipv6 == 0 && v6 != 0) || (ipv6 == 1 && v6 != len(ips)) || (ipv6 == 0.5 && (v6 == 0 || v6 == len(ips))) {
+			t.Errorf("randomIps(200, %v) returned %d IPv6 ips", ipv6, v6)
+		}
+	}
+}
+
+func TestReplayIps(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "ips.txt")
+	if err := os.WriteFile(path, []byte("# sampled\n81.2.69.142\n\n  2001:218::1  \n#1.1.1.1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if ips, err := replayIps(path); err != nil || !reflect.DeepEqual(ips, []string{"81.2.69.142", "2001:218::1"}) {
+		t.Errorf("replayIps = %q, %v", ips, err)
+	}
+
+	empty := filepath.Join(dir, "empty.txt")
+	if err := os.WriteFile(empty, []byte("# nothing\n\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{empty, filepath.Join(dir, "missing.txt")} {
+		if ips, err := replayIps(path); err == nil {
+			t.Errorf("replayIps(%s) = %q; want an error", path, ips)
+		}
+	}
+}
+
+func TestBenchCommandSeed(t *testing.T) {
+	dbPath := buildFixture(t, "GeoIP2-City-Test.json")
+
+	for _, test := range []struct {
+		args []string
+		seed int64
+	}{
+		{nil, defaultBenchSeed},
+		{[]string{"-s", "42"}, 42},
+		{[]string{"-random.seed", "-7"}, -7},
+	} {
+		args := append([]string{"-f", dbPath, "-n", "100", "-r", "10", "-o", "json"}, test.args...)
+		output, err := runCommand(newBenchCommand, args...)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var result BenchResult
+		if err = json.Unmarshal([]byte(output), &result); err != nil {
+			t.Fatalf("%s: %s", err, output)
+		}
+		if result.Workload != "random" || result.Seed != test.seed || result.Requests != 100 {
+			t.Errorf("bench %q = %+v; want seed %d", test.args, result, test.seed)
+		}
+	}
+}
@@ -0,0 +1,236 @@
+package command
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/rabbitt/maxmind/mm"
+	"github.com/rabbitt/maxmind/mmdb"
+)
+
+type BuildCommand struct {
+	Ui Ui
+}
+
+// readInput reads the networks of the CSV file at path, or of stdin when
+// path is -, into w. A directory is read as the CSV edition of a GeoLite2
+// or GeoIP2 database.
+func readInput(w *mmdb.Writer, path string) error {
+	if path != "-" && mm.IsCsvDatabase(path) {
+		database, err := mm.OpenCsvDatabase(path)
+		if err != nil {
+			return err
+		}
+		defer database.Close()
+
+		// the database takes the release date of the first directory, and
+		// its type unless given
+		info := database.Metadata()
+		if w.DatabaseType == "" {
+			w.DatabaseType = info.Type
+		}
+		if w.BuildTime.IsZero() {
+			w.BuildTime = info.BuildTime
+		}
+		return database.Convert(w)
+	}
+
+	var in io.Reader = os.Stdin
+	if path != "-" {
+		realPath, err := mm.NewPathname(path).RealPath()
+		if err != nil {
+			return err
+		}
+
+		file, err := os.Open(realPath.Path())
+		if err != nil {
+			return &mm.PathError{Path: path, Err: err}
+		}
+		defer file.Close()
+		in = file
+	}
+
+	if err := w.ReadCsv(in); err != nil {
+		return fmt.Errorf("%s: %s", path, err)
+	}
+	return nil
+}
+
+// openDatabase opens the database at path for the offline tools, compiling
+// CSV files of networks, and directories holding the CSV edition of a
+// database, as the build command would.
+func openDatabase(path string) (*mm.Database, error) {
+	if mm.IsCsv(path) {
+		return mm.CompileDatabase(path)
+	}
+	return mm.OpenDatabase(path)
+}
+
+// writeDatabase writes the database to path by way of a temporary file,
+// which is opened to check that it loads before it replaces path, so that
+// a server reloading path never sees a partial or broken database.
+func writeDatabase(w *mmdb.Writer, path string) (*mm.DatabaseInfo, error) {
+	file, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return nil, &mm.PathError{Path: path, Err: err}
+	}
+	tmpPath := file.Name()
+	defer os.Remove(tmpPath)
+
+	_, err = w.WriteTo(file)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to write %s: %s", path, err)
+	}
+
+	database, err := mm.OpenDatabase(tmpPath)
+	if err != nil {
+		return nil, err
+	}
+	info := database.Metadata()
+	database.Close()
+
+	if err = os.Chmod(tmpPath, 0644); err != nil {
+		return nil, err
+	}
+	if err = os.Rename(tmpPath, path); err != nil {
+		return nil, &mm.PathError{Path: path, Err: err}
+	}
+	return info, nil
+}
+
+func (c *BuildCommand) Help() string {
+	return fmt.Sprintf(`Usage: %s build [options] file.csv|directory ... file.csv|directory
+
+Compiles CSV files of networks, such as internal office and datacenter
+ranges, into a MaxMind database the server and lookup tool can load. Later
+networks take precedence over the parts of earlier ones they overlap.
+
+Directories are read as the CSV edition of a GeoLite2 or GeoIP2 City or
+Country database, as MaxMind distributes it, e.g. GeoLite2-City-CSV_20181016,
+converting it to a MaxMind database.
+
+The header names the network column, and a column for each field of the
+records; either the dotted path of the field, e.g. location.latitude or
+site.name, optionally followed by its type, e.g. site.rack:uint16, or one
+of the short names:
+
+  continent, continent_name, country, country_name, subdivision,
+  subdivision_name, city, postal, latitude (lat), longitude (lon),
+  accuracy_radius, time_zone
+
+Options:
+  -o, -output           <file>      Path of the database to write
+  -t, -database.type    <string>    Database type recorded in the metadata
+                                    (default: %s, or the type of the
+                                    first directory)
+  -d, -description      <string>    English description recorded in the metadata
+                                    (default: "<type> built by maxmind build")
+  -l, -languages        <string>    Comma separated languages of the names in
+                                    the records (default: %s)
+  -v, -ip.version       <integer>   IP version of the database; 4 or 6
+                                    (default: %d)
+  -r, -record.size      <integer>   Search tree record size, in bits; 24, 28 or
+                                    32, or 0 for the smallest that fits
+                                    (default: %d)
+  -a, -alias.ipv4       <bool>      Alias the IPv4-mapped (::ffff:0:0/96) and
+                                    6to4 (2002::/16) networks to the IPv4 ones
+                                    (default: %t)
+`, os.Args[0], "GeoIP2-City", "en", 6, 0, true)
+}
+
+func (c *BuildCommand) Synopsis() string {
+	return "Build a MaxMind database from CSV files of networks"
+}
+
+// Execute compiles the CSV files given in args into a database.
+func (c *BuildCommand) Execute(args []string) error {
+	var err error
+
+	var mainParse = flag.NewFlagSet("build", flag.ContinueOnError)
+	output := mainParse.String("output", "", "`path` of the database to write")
+	mainParse.StringVar(output, "o", "", "`path` of the database to write")
+	databaseType := mainParse.String("database.type", "GeoIP2-City", "database `type` recorded in the metadata")
+	mainParse.StringVar(databaseType, "t", "GeoIP2-City", "database `type` recorded in the metadata")
+	description := mainParse.String("description", "", "English `description` recorded in the metadata")
+	mainParse.StringVar(description, "d", "", "English `description` recorded in the metadata")
+	languages := mainParse.String("languages", "en", "comma separated `languages` of the names in the records")
+	mainParse.StringVar(languages, "l", "en", "comma separated `languages` of the names in the records")
+	ipVersion := mainParse.Int("ip.version", 6, "IP `version` of the database; 4 or 6")
+	mainParse.IntVar(ipVersion, "v", 6, "IP `version` of the database; 4 or 6")
+	recordSize := mainParse.Int("record.size", 0, "search tree record `size`, in bits; 24, 28, 32, or 0 for the smallest that fits")
+	mainParse.IntVar(recordSize, "r", 0, "search tree record `size`, in bits; 24, 28, 32, or 0 for the smallest that fits")
+	aliasIpv4 := mainParse.Bool("alias.ipv4", true, "alias the IPv4-mapped and 6to4 networks to the IPv4 ones")
+	mainParse.BoolVar(aliasIpv4, "a", true, "alias the IPv4-mapped and 6to4 networks to the IPv4 ones")
+
+	mainParse.Usage = func() {
+		c.Ui.Output(c.Help())
+		mainParse.PrintDefaults()
+	}
+	if err = mainParse.Parse(args); err != nil {
+		return usageError(err)
+	}
+
+	typeSet := false
+	mainParse.Visit(func(f *flag.Flag) {
+		typeSet = typeSet || f.Name == "database.type" || f.Name == "t"
+	})
+
+	switch {
+	case *output == "":
+		return usageError(errors.New("missing required path of the database to write"))
+	case len(mainParse.Args()) == 0:
+		return usageError(errors.New("no csv files to build from"))
+	case *recordSize != 0 && *recordSize != 24 && *recordSize != 28 && *recordSize != 32:
+		return usageError(fmt.Errorf("invalid record size %d; expected 24, 28, 32, or 0", *recordSize))
+	}
+
+	if !typeSet {
+		// left to the first directory, if any
+		*databaseType = ""
+	}
+
+	w, err := mmdb.NewWriter(*databaseType, *ipVersion)
+	if err != nil {
+		return usageError(err)
+	}
+	w.RecordSize = *recordSize
+	w.AliasIpv4 = *aliasIpv4
+	if *description != "" {
+		w.Description["en"] = *description
+	}
+	for _, language := range strings.Split(*languages, ",") {
+		if language = strings.TrimSpace(language); language != "" {
+			w.Languages = append(w.Languages, language)
+		}
+	}
+
+	for _, input := range mainParse.Args() {
+		if err = readInput(w, input); err != nil {
+			return err
+		}
+	}
+	if w.DatabaseType == "" {
+		w.DatabaseType = "GeoIP2-City"
+	}
+	if len(w.Description) == 0 {
+		// databases without a description do not pass verification
+		w.Description["en"] = w.DatabaseType + " built by maxmind build"
+	}
+
+	info, err := writeDatabase(w, *output)
+	if err != nil {
+		return err
+	}
+
+	c.Ui.Infof("Built %s: %s, IPv%d, %d nodes, %d bit records\n",
+		*output, info.Type, info.IpVersion, info.NodeCount, info.RecordSize)
+	return nil
+}
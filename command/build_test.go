@@ -0,0 +1,153 @@
+package command
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rabbitt/maxmind/mm"
+)
+
+func writeCsv(t *testing.T, dir string, name string, content string) string {
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestBuildCommand(t *testing.T) {
+	dir := t.TempDir()
+	offices := writeCsv(t, dir, "offices.csv", `network,country,country_name,city,lat,lon,site.name,site.rack:uint16
+10.0.0.0/8,US,United States,Internal,,,corp,
+10.1.0.0/16,GB,United Kingdom,London,51.5142,-0.0931,lon-1,
+10.2.0.0/16,US,United States,Ashburn,39.0438,-77.4874,iad-1,12
+fd00:1::/32,US,United States,Ashburn,39.0438,-77.4874,iad-1,12
+`)
+	overrides := writeCsv(t, dir, "overrides.csv", "network,city,site.name\n10.1.2.0/24,Reading,rdg-1\n")
+	output := filepath.Join(dir, "internal.mmdb")
+
+	var out bytes.Buffer
+	c := &BuildCommand{Ui: &BaseUi{Writer: &out, ErrorWriter: &out}}
+	err := c.Execute([]string{"-o", output, "-d", "internal networks", "-r", "28", offices, overrides})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "GeoIP2-City, IPv6") || !strings.Contains(out.String(), "28 bit records") {
+		t.Errorf("unexpected output %q", out.String())
+	}
+
+	database, err := mm.OpenDatabase(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer database.Close()
+
+	if info := database.Metadata(); info.Description["en"] != "internal networks" || info.RecordSize != 28 {
+		t.Errorf("unexpected info %+v", info)
+	}
+
+	for ip, want := range map[string]string{
+		"10.1.1.1":        "London",
+		"10.1.2.1":        "Reading",
+		"10.9.9.9":        "Internal",
+		"::ffff:10.2.0.1": "Ashburn",
+		"2002:a02:1::":    "Ashburn",
+		"fd00:1:2::3":     "Ashburn",
+	} {
+		record, err := database.Lookup(ip)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if record.City.Name != want {
+			t.Errorf("%s = %+v; want %s", ip, record, want)
+		}
+	}
+
+	record, _ := database.Lookup("10.1.1.1")
+	if record.Country.IsoCode != "GB" || record.Country.Name != "United Kingdom" || record.Location.Latitude != 51.5142 {
+		t.Errorf("unexpected record %+v", record)
+	}
+
+	var site struct {
+		Site struct {
+			Name string `maxminddb:"name"`
+			Rack uint16 `maxminddb:"rack"`
+		} `maxminddb:"site"`
+	}
+	if err = database.LookupInto(context.Background(), net.ParseIP("10.2.3.4"), &site); err != nil {
+		t.Fatal(err)
+	}
+	if site.Site.Name != "iad-1" || site.Site.Rack != 12 {
+		t.Errorf("unexpected site %+v", site)
+	}
+}
+
+func TestBuildCommandErrors(t *testing.T) {
+	dir := t.TempDir()
+	valid := writeCsv(t, dir, "valid.csv", "network,city\n10.0.0.0/8,Internal\n")
+	invalid := writeCsv(t, dir, "invalid.csv", "network,city\n10.0.0.300/8,Internal\n")
+	output := filepath.Join(dir, "internal.mmdb")
+
+	var usage *UsageError
+	var pathErr *mm.PathError
+
+	for _, test := range []struct {
+		args   []string
+		target interface{}
+	}{
+		{[]string{valid}, &usage},
+		{[]string{"-o", output}, &usage},
+		{[]string{"-o", output, "-r", "30", valid}, &usage},
+		{[]string{"-o", output, "-v", "5", valid}, &usage},
+		{[]string{"-o", output, filepath.Join(dir, "missing.csv")}, &pathErr},
+	} {
+		c := &BuildCommand{Ui: &BaseUi{Writer: &bytes.Buffer{}, ErrorWriter: &bytes.Buffer{}}}
+		if err := c.Execute(test.args); !errors.As(err, test.target) {
+			t.Errorf("Execute(%v) = %v; want a %T", test.args, err, test.target)
+		}
+	}
+
+	c := &BuildCommand{Ui: &BaseUi{Writer: &bytes.Buffer{}, ErrorWriter: &bytes.Buffer{}}}
+	if err := c.Execute([]string{"-o", output, invalid}); err == nil || !strings.Contains(err.Error(), "invalid.csv") {
+		t.Errorf("Execute with an invalid network = %v", err)
+	}
+	if _, err := os.Stat(output); !os.IsNotExist(err) {
+		t.Error("a failed build wrote the database")
+	}
+}
+
+func TestBuildCommandFromGeoLite2Csv(t *testing.T) {
+	output := filepath.Join(t.TempDir(), "GeoLite2-City.mmdb")
+
+	var out bytes.Buffer
+	c := &BuildCommand{Ui: &BaseUi{Writer: &out, ErrorWriter: &out}}
+	if err := c.Execute([]string{"-o", output, "../testdata/GeoLite2-City-CSV_20181016"}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "GeoLite2-City, IPv6") {
+		t.Errorf("unexpected output %q", out.String())
+	}
+
+	database, err := mm.OpenDatabase(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer database.Close()
+
+	if info := database.Metadata(); info.BuildTime.Format("2006-01-02") != "2018-10-16" ||
+		info.Description["en"] != "GeoLite2-City built by maxmind build" {
+		t.Errorf("unexpected info %+v", info)
+	}
+	if err = database.Reader.Verify(); err != nil {
+		t.Errorf("Verify() = %v", err)
+	}
+	if record, err := database.Lookup("89.160.20.115"); err != nil || record.Subdivision.Name != "Linköping Municipality" {
+		t.Errorf("89.160.20.115 = %+v, %v", record, err)
+	}
+}
@@ -0,0 +1,107 @@
+package command
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// CorsHandler adds CORS headers to the responses of the wrapped router, and
+// answers preflight requests for any of its routes.
+type CorsHandler struct {
+	Router  *httprouter.Router
+	Origins []string
+	Methods []string
+	Headers []string
+	MaxAge  uint32
+}
+
+// headers that browsers hide from scripts unless explicitly exposed
+var corsExposedHeaders = strings.Join([]string{
+	"Retry-After",
+	"X-RateLimit-Limit",
+	"X-RateLimit-Remaining",
+	"X-Cache",
+	"X-Request-ID",
+	"X-Uptime",
+}, ", ")
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// allowOrigin returns the value for Access-Control-Allow-Origin, or an
+// empty string if origin is not permitted.
+func (h *CorsHandler) allowOrigin(origin string) string {
+	for _, allowed := range h.Origins {
+		if allowed == "*" {
+			return "*"
+		}
+		if strings.EqualFold(allowed, origin) {
+			return origin
+		}
+	}
+	return ""
+}
+
+func (h *CorsHandler) preflight(writer http.ResponseWriter, req *http.Request, allowedOrigin string) {
+	method := req.Header.Get("Access-Control-Request-Method")
+	if !containsFold(h.Methods, method) {
+		writer.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	for _, header := range strings.Split(req.Header.Get("Access-Control-Request-Headers"), ",") {
+		if header = strings.TrimSpace(header); header != "" && !containsFold(h.Headers, header) {
+			writer.WriteHeader(http.StatusForbidden)
+			return
+		}
+	}
+
+	writer.Header().Set("Access-Control-Allow-Origin", allowedOrigin)
+	writer.Header().Set("Access-Control-Allow-Methods", strings.Join(h.Methods, ", "))
+	if len(h.Headers) > 0 {
+		writer.Header().Set("Access-Control-Allow-Headers", strings.Join(h.Headers, ", "))
+	}
+	if h.MaxAge > 0 {
+		writer.Header().Set("Access-Control-Max-Age", fmt.Sprintf("%d", h.MaxAge))
+	}
+	writer.WriteHeader(http.StatusNoContent)
+}
+
+func (h *CorsHandler) ServeHTTP(writer http.ResponseWriter, req *http.Request) {
+	origin := req.Header.Get("Origin")
+	if origin == "" {
+		h.Router.ServeHTTP(writer, req)
+		return
+	}
+
+	writer.Header().Add("Vary", "Origin")
+	allowedOrigin := h.allowOrigin(origin)
+
+	if req.Method == "OPTIONS" && req.Header.Get("Access-Control-Request-Method") != "" {
+		// only preflight routes that exist, leaving the router to 404 the rest
+		if handle, _, _ := h.Router.Lookup(req.Header.Get("Access-Control-Request-Method"), req.URL.Path); handle != nil {
+			if allowedOrigin == "" {
+				writer.WriteHeader(http.StatusForbidden)
+				return
+			}
+			h.preflight(writer, req, allowedOrigin)
+			return
+		}
+	}
+
+	if allowedOrigin != "" {
+		writer.Header().Set("Access-Control-Allow-Origin", allowedOrigin)
+		writer.Header().Set("Access-Control-Expose-Headers", corsExposedHeaders)
+	}
+
+	h.Router.ServeHTTP(writer, req)
+}
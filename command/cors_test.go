@@ -0,0 +1,140 @@
+package command
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rabbitt/maxmind/mm"
+)
+
+func testCorsHandler(t *testing.T, origins ...string) *CorsHandler {
+	c, _ := testServer(t)
+	config := mm.NewConfiguration()
+	return &CorsHandler{
+		Router:  c.routes(),
+		Origins: origins,
+		Methods: config.CorsMethods,
+		Headers: config.CorsHeaders,
+		MaxAge:  config.CorsMaxAge,
+	}
+}
+
+func serveCors(h *CorsHandler, method string, target string, header http.Header) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, nil)
+	for key, values := range header {
+		req.Header[key] = values
+	}
+
+	recorder := httptest.NewRecorder()
+	h.ServeHTTP(recorder, req)
+	return recorder
+}
+
+func TestCorsPreflight(t *testing.T) {
+	h := testCorsHandler(t, "https://maps.example.com")
+
+	for idx, test := range []struct {
+		origin, method, headers string
+		code                    int
+	}{
+		{"https://maps.example.com", "GET", "", http.StatusNoContent},
+		{"https://MAPS.example.com", "GET", "Authorization, X-API-Key", http.StatusNoContent},
+		{"https://maps.example.com", "POST", "Content-Type", http.StatusNoContent},
+		{"https://maps.example.com", "GET", "X-Secret", http.StatusForbidden},
+		{"https://evil.example.com", "GET", "", http.StatusForbidden},
+	} {
+		header := http.Header{"Origin": {test.origin}, "Access-Control-Request-Method": {test.method}}
+		if test.headers != "" {
+			header.Set("Access-Control-Request-Headers", test.headers)
+		}
+		target := "/ip/81.2.69.142"
+		if test.method == "POST" {
+			target = "/batch"
+		}
+
+		recorder := serveCors(h, "OPTIONS", target, header)
+		if recorder.Code != test.code {
+			t.Errorf("%d: preflight status = %d; want %d", idx, recorder.Code, test.code)
+		}
+		if vary := recorder.Header().Get("Vary"); vary != "Origin" {
+			t.Errorf("%d: Vary = %q", idx, vary)
+		}
+
+		allowed := recorder.Header().Get("Access-Control-Allow-Origin")
+		if test.code != http.StatusNoContent {
+			if allowed != "" {
+				t.Errorf("%d: Access-Control-Allow-Origin = %q on a rejected preflight", idx, allowed)
+			}
+			continue
+		}
+		if allowed != test.origin {
+			t.Errorf("%d: Access-Control-Allow-Origin = %q; want %q", idx, allowed, test.origin)
+		}
+		if methods := recorder.Header().Get("Access-Control-Allow-Methods"); methods != "GET, HEAD, POST" {
+			t.Errorf("%d: Access-Control-Allow-Methods = %q", idx, methods)
+		}
+		if maxAge := recorder.Header().Get("Access-Control-Max-Age"); maxAge != "600" {
+			t.Errorf("%d: Access-Control-Max-Age = %q", idx, maxAge)
+		}
+	}
+
+	// routes that do not exist are left to the router
+	header := http.Header{"Origin": {"https://maps.example.com"}, "Access-Control-Request-Method": {"GET"}}
+	if recorder := serveCors(h, "OPTIONS", "/nowhere", header); recorder.Code == http.StatusNoContent {
+		t.Errorf("preflight of a missing route = %d", recorder.Code)
+	}
+
+	// nor are methods that are not allowed, even where routes accept them
+	h.Methods = []string{"GET"}
+	postHeader := http.Header{"Origin": {"https://maps.example.com"}, "Access-Control-Request-Method": {"POST"}}
+	if recorder := serveCors(h, "OPTIONS", "/batch", postHeader); recorder.Code != http.StatusForbidden {
+		t.Errorf("preflight of a disallowed method = %d; want %d", recorder.Code, http.StatusForbidden)
+	}
+
+	// without a max age, browsers pick their own
+	h.MaxAge = 0
+	if recorder := serveCors(h, "OPTIONS", "/ip/81.2.69.142", header); recorder.Header().Get("Access-Control-Max-Age") != "" {
+		t.Errorf("Access-Control-Max-Age = %q; want none", recorder.Header().Get("Access-Control-Max-Age"))
+	}
+}
+
+func TestCorsRequests(t *testing.T) {
+	for idx, test := range []struct {
+		origins []string
+		origin  string
+		allowed string
+	}{
+		{[]string{"https://maps.example.com"}, "https://maps.example.com", "https://maps.example.com"},
+		{[]string{"https://maps.example.com"}, "https://evil.example.com", ""},
+		{[]string{"*"}, "https://evil.example.com", "*"},
+		{[]string{"*"}, "", ""},
+	} {
+		h := testCorsHandler(t, test.origins...)
+		header := http.Header{}
+		if test.origin != "" {
+			header.Set("Origin", test.origin)
+		}
+
+		recorder := serveCors(h, "GET", "/ip/81.2.69.142", header)
+		if recorder.Code != http.StatusOK {
+			t.Errorf("%d: status = %d", idx, recorder.Code)
+		}
+		if allowed := recorder.Header().Get("Access-Control-Allow-Origin"); allowed != test.allowed {
+			t.Errorf("%d: Access-Control-Allow-Origin = %q; want %q", idx, allowed, test.allowed)
+		}
+
+		// responses vary by origin whenever one is sent, allowed or not
+		if vary := recorder.Header().Values("Vary"); containsFold(vary, "Origin") != (test.origin != "") {
+			t.Errorf("%d: Vary = %q", idx, vary)
+		}
+
+		exposed := recorder.Header().Get("Access-Control-Expose-Headers")
+		if test.allowed == "" && exposed != "" {
+			t.Errorf("%d: Access-Control-Expose-Headers = %q for a disallowed origin", idx, exposed)
+		}
+		if test.allowed != "" && exposed != "Retry-After, X-RateLimit-Limit, X-RateLimit-Remaining, X-Cache, X-Request-ID, X-Uptime" {
+			t.Errorf("%d: Access-Control-Expose-Headers = %q", idx, exposed)
+		}
+	}
+}
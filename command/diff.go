@@ -0,0 +1,427 @@
+package command
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pquerna/ffjson/ffjson"
+	"github.com/rabbitt/maxmind/mm"
+)
+
+type DiffCommand struct {
+	Ui Ui
+}
+
+func formatCoordinates(data *mm.GeoData) string {
+	if data.Location.Latitude == 0 && data.Location.Longitude == 0 {
+		return ""
+	}
+	return formatFloat(data.Location.Latitude) + "," + formatFloat(data.Location.Longitude)
+}
+
+// DiffFields are the fields of the geodata the diff command compares,
+// besides the asn of ASN databases.
+var DiffFields = map[string]func(data *mm.GeoData) string{
+	"continent":           func(data *mm.GeoData) string { return data.Continent.Code },
+	"country":             func(data *mm.GeoData) string { return data.Country.IsoCode },
+	"subdivision":         func(data *mm.GeoData) string { return data.Subdivision.IsoCode },
+	"city":                func(data *mm.GeoData) string { return data.City.Name },
+	"postal":              func(data *mm.GeoData) string { return data.Postal.Code },
+	"location":            formatCoordinates,
+	"accuracy_radius":     func(data *mm.GeoData) string { return formatUint(uint64(data.Location.AccuracyRadius)) },
+	"time_zone":           func(data *mm.GeoData) string { return data.Location.TimeZone },
+	"registered_country":  func(data *mm.GeoData) string { return data.RegisteredCountry.IsoCode },
+	"represented_country": func(data *mm.GeoData) string { return data.RepresentedCountry.IsoCode },
+}
+
+// diffAsn returns the autonomous system an ASN database, such as
+// GeoLite2-ASN, has for ip; it is empty when db is nil, for other databases.
+func diffAsn(db *mm.Database, ip string) (string, error) {
+	if db == nil {
+		return "", nil
+	}
+	asn, _, err := db.LookupAsn(ip)
+	if err != nil || asn == 0 {
+		return "", err
+	}
+	return "AS" + strconv.FormatUint(uint64(asn), 10), nil
+}
+
+// the fields compared by default, and when both databases are ASN databases
+const (
+	defaultDiffFields    = "country,city"
+	defaultAsnDiffFields = "asn"
+)
+
+// kinds of DiffChange
+const (
+	DiffAdded   = "added"
+	DiffRemoved = "removed"
+	DiffChanged = "changed"
+)
+
+// DiffChange is a network whose selected fields differ between releases,
+// with their values in each; a network only one of them has is added or
+// removed.
+type DiffChange struct {
+	Network string            `json:"network"`
+	Change  string            `json:"change"`
+	Fields  []string          `json:"fields,omitempty"`
+	Old     map[string]string `json:"old,omitempty"`
+	New     map[string]string `json:"new,omitempty"`
+}
+
+type DiffCounts struct {
+	Changed int `json:"changed"`
+	Added   int `json:"added"`
+	Removed int `json:"removed"`
+}
+
+func (c *DiffCounts) count(change string) {
+	switch change {
+	case DiffAdded:
+		c.Added++
+	case DiffRemoved:
+		c.Removed++
+	default:
+		c.Changed++
+	}
+}
+
+func (c *DiffCounts) Total() int {
+	return c.Changed + c.Added + c.Removed
+}
+
+// DiffSummary counts the changed networks, overall and by country; the
+// country they were in, or are in when added.
+type DiffSummary struct {
+	DiffCounts
+	Countries map[string]*DiffCounts `json:"countries"`
+}
+
+// diffCountry names the country of networks without one in summaries.
+const diffCountry = "unknown"
+
+func (s *DiffSummary) count(change *DiffChange, country string) {
+	if country == "" {
+		country = diffCountry
+	}
+	counts, found := s.Countries[country]
+	if !found {
+		counts = &DiffCounts{}
+		s.Countries[country] = counts
+	}
+	counts.count(change.Change)
+	s.DiffCounts.count(change.Change)
+}
+
+// fieldValue returns the value of the field in the geodata for the range
+// starting at ip; the asn is looked up in asnDb, nil unless the database
+// is an ASN database.
+func fieldValue(field string, ip string, data *mm.GeoData, asnDb *mm.Database) (string, error) {
+	if field == "asn" {
+		return diffAsn(asnDb, ip)
+	}
+	return DiffFields[field](data), nil
+}
+
+// diffWriter renders the changes, as they are found, and the summary.
+type diffWriter interface {
+	Write(change *DiffChange) error
+	Close(summary *DiffSummary) error
+}
+
+type textDiffWriter struct {
+	out    *bufio.Writer
+	fields []string
+}
+
+func formatValues(fields []string, values map[string]string) string {
+	var parts []string
+	for _, field := range fields {
+		if value := values[field]; value != "" {
+			parts = append(parts, fmt.Sprintf("%s: %s", field, value))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+func (w *textDiffWriter) Write(change *DiffChange) error {
+	var detail string
+	switch change.Change {
+	case DiffAdded:
+		detail = formatValues(w.fields, change.New)
+	case DiffRemoved:
+		detail = formatValues(w.fields, change.Old)
+	default:
+		var parts []string
+		for _, field := range change.Fields {
+			parts = append(parts, fmt.Sprintf("%s: %s -> %s", field, quoteEmpty(change.Old[field]), quoteEmpty(change.New[field])))
+		}
+		detail = strings.Join(parts, ", ")
+	}
+	_, err := fmt.Fprintf(w.out, "%-40s %-8s %s\n", change.Network, change.Change, detail)
+	return err
+}
+
+func quoteEmpty(value string) string {
+	if value == "" {
+		return `""`
+	}
+	return value
+}
+
+func (w *textDiffWriter) Close(summary *DiffSummary) error {
+	if summary.Total() > 0 {
+		w.out.WriteString("\n")
+	}
+	fmt.Fprintf(w.out, "Networks:  %d changed, %d added, %d removed\n", summary.Changed, summary.Added, summary.Removed)
+
+	countries := make([]string, 0, len(summary.Countries))
+	for country := range summary.Countries {
+		countries = append(countries, country)
+	}
+	sort.Strings(countries)
+	for _, country := range countries {
+		counts := summary.Countries[country]
+		fmt.Fprintf(w.out, "  %-8s %d changed, %d added, %d removed\n", country, counts.Changed, counts.Added, counts.Removed)
+	}
+	return w.out.Flush()
+}
+
+// jsonDiffWriter streams a JSON object of the changes, followed by the
+// summary.
+type jsonDiffWriter struct {
+	out   *bufio.Writer
+	count int
+}
+
+func (w *jsonDiffWriter) Write(change *DiffChange) error {
+	j, err := ffjson.Marshal(change)
+	if err != nil {
+		return err
+	}
+	if w.count == 0 {
+		w.out.WriteString("{\"changes\":[\n")
+	} else {
+		w.out.WriteString(",\n")
+	}
+	w.count++
+	w.out.Write(j)
+	return nil
+}
+
+func (w *jsonDiffWriter) Close(summary *DiffSummary) error {
+	j, err := ffjson.Marshal(summary)
+	if err != nil {
+		return err
+	}
+	if w.count == 0 {
+		w.out.WriteString("{\"changes\":[")
+	}
+	w.out.WriteString("\n],\"summary\":")
+	w.out.Write(j)
+	w.out.WriteString("}\n")
+	return w.out.Flush()
+}
+
+// differencesError reports that the databases differ, when asked to exit
+// with a failing status if they do.
+type differencesError struct {
+	count int
+}
+
+func (e *differencesError) Error() string {
+	return fmt.Sprintf("%d networks differ", e.count)
+}
+
+func (c *DiffCommand) Help() string {
+	return fmt.Sprintf(`Usage: %s diff [options] old.mmdb new.mmdb
+
+Walks the networks of two releases of a database together, reporting the
+networks whose selected fields differ, or that only one of them has, with
+counts of the changed networks by country; e.g. to check which of your
+ranges changed country, city or ASN before rolling out a new release.
+
+Options:
+  -c, -cidr           <cidr>      Only compare the parts of networks within
+                                  cidr; may be repeated
+  -F, -fields         <fields>    Comma separated fields to compare; any of
+                                  continent, country, subdivision, city,
+                                  postal, location, accuracy_radius,
+                                  time_zone, registered_country,
+                                  represented_country, or asn (of ASN
+                                  databases) (default: %s, or %s
+                                  for ASN databases)
+  -o, -output.type    <string>    Output format; one of text or json
+                                  (default: %s)
+  -e, -exit.code      <bool>      Exit with status 1 when the databases
+                                  differ (default: %t)
+
+Either database may also be a directory of the CSV edition of a database.
+`, os.Args[0], defaultDiffFields, defaultAsnDiffFields, "text", false)
+}
+
+func (c *DiffCommand) Synopsis() string {
+	return "Report the networks that differ between two databases"
+}
+
+// Execute compares the databases named by args.
+func (c *DiffCommand) Execute(args []string) error {
+	var err error
+
+	var mainParse = flag.NewFlagSet("diff", flag.ContinueOnError)
+	var cidrs, fieldNames ListFlag
+	mainParse.Var(&cidrs, "cidr", "only compare the parts of networks within `cidr`; may be repeated")
+	mainParse.Var(&cidrs, "c", "only compare the parts of networks within `cidr`; may be repeated")
+	mainParse.Var(&fieldNames, "fields", "comma separated `fields` to compare (default "+defaultDiffFields+", or "+defaultAsnDiffFields+" for ASN databases)")
+	mainParse.Var(&fieldNames, "F", "comma separated `fields` to compare (default "+defaultDiffFields+", or "+defaultAsnDiffFields+" for ASN databases)")
+	outType := mainParse.String("output.type", "text", "output `format`; one of 'text' or 'json'")
+	mainParse.StringVar(outType, "o", "text", "output `format`; one of 'text' or 'json'")
+	exitCode := mainParse.Bool("exit.code", false, "exit with status 1 when the databases differ")
+	mainParse.BoolVar(exitCode, "e", false, "exit with status 1 when the databases differ")
+
+	mainParse.Usage = func() {
+		c.Ui.Output(c.Help())
+		mainParse.PrintDefaults()
+	}
+	if err = mainParse.Parse(args); err != nil {
+		return usageError(err)
+	}
+
+	if *outType != "text" && *outType != "json" {
+		return usageError(fmt.Errorf("invalid output type '%s'; expected one of 'text' or 'json'", *outType))
+	}
+	if mainParse.NArg() != 2 {
+		return usageError(errors.New("expected the paths of the old and new databases"))
+	}
+
+	limits, err := parseCidrs(cidrs)
+	if err != nil {
+		return usageError(err)
+	}
+
+	fields := splitList(fieldNames)
+	for _, field := range fields {
+		if DiffFields[field] == nil && field != "asn" {
+			return usageError(fmt.Errorf("unknown field '%s'", field))
+		}
+	}
+
+	var databases [2]*mm.Database
+	for idx, path := range mainParse.Args() {
+		realPath, err := mm.NewPathname(path).RealPath()
+		if err != nil {
+			return err
+		}
+		if databases[idx], err = openDatabase(realPath.Path()); err != nil {
+			return err
+		}
+		defer databases[idx].Close()
+	}
+	oldDb, newDb := databases[0], databases[1]
+
+	// the asn is only looked up in ASN databases
+	var asnDatabases [2]*mm.Database
+	for idx, database := range databases {
+		if strings.Contains(database.Metadata().Type, "ASN") {
+			asnDatabases[idx] = database
+		}
+	}
+	oldAsnDb, newAsnDb := asnDatabases[0], asnDatabases[1]
+
+	if len(fields) == 0 {
+		fields = splitList([]string{defaultDiffFields})
+		if oldAsnDb != nil && newAsnDb != nil {
+			fields = splitList([]string{defaultAsnDiffFields})
+		}
+	}
+
+	var writer diffWriter
+	out := bufio.NewWriterSize(uiWriter{c.Ui}, 64*1024)
+	if *outType == "json" {
+		writer = &jsonDiffWriter{out: out}
+	} else {
+		writer = &textDiffWriter{out: out, fields: fields}
+	}
+
+	summary := &DiffSummary{Countries: map[string]*DiffCounts{}}
+	compare := func(r mm.IPRange, oldData *mm.GeoData, newData *mm.GeoData) error {
+		first := r.First().String()
+		change := &DiffChange{Change: DiffChanged}
+
+		var country string
+		switch {
+		case oldData == nil:
+			change.Change = DiffAdded
+			country = newData.Country.IsoCode
+		case newData == nil:
+			change.Change = DiffRemoved
+			country = oldData.Country.IsoCode
+		default:
+			country = oldData.Country.IsoCode
+		}
+
+		if oldData != nil {
+			change.Old = map[string]string{}
+		}
+		if newData != nil {
+			change.New = map[string]string{}
+		}
+		for _, field := range fields {
+			var oldValue, newValue string
+			var err error
+			if oldData != nil {
+				if oldValue, err = fieldValue(field, first, oldData, oldAsnDb); err != nil {
+					return err
+				}
+				change.Old[field] = oldValue
+			}
+			if newData != nil {
+				if newValue, err = fieldValue(field, first, newData, newAsnDb); err != nil {
+					return err
+				}
+				change.New[field] = newValue
+			}
+			if oldData != nil && newData != nil && oldValue != newValue {
+				change.Fields = append(change.Fields, field)
+			}
+		}
+		if change.Change == DiffChanged && len(change.Fields) == 0 {
+			return nil
+		}
+
+		for _, network := range r.CIDRs() {
+			change.Network = network.String()
+			summary.count(change, country)
+			if err := writer.Write(change); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	// with limits, only the parts of the networks within them are walked,
+	// each once however the limits nest
+	var oldNetworks, newNetworks mm.NetworkWalker = oldDb, newDb
+	if len(limits) > 0 {
+		oldNetworks, newNetworks = oldDb.Within(limits), newDb.Within(limits)
+	}
+	if err = mm.MergeNetworks(oldNetworks, newNetworks, compare); err != nil {
+		return err
+	}
+	if err = writer.Close(summary); err != nil {
+		return err
+	}
+
+	if *exitCode && summary.Total() > 0 {
+		return &differencesError{count: summary.Total()}
+	}
+	return nil
+}
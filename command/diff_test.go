@@ -0,0 +1,166 @@
+package command
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func newDiffCommand(ui Ui) executor {
+	return &DiffCommand{Ui: ui}
+}
+
+// buildCsv builds the database of the networks of a CSV file, returning
+// its path.
+func buildCsv(t *testing.T, content string, args ...string) string {
+	dir := t.TempDir()
+	output := filepath.Join(dir, "networks.mmdb")
+
+	var out bytes.Buffer
+	c := &BuildCommand{Ui: &BaseUi{Writer: &out, ErrorWriter: &out}}
+	if err := c.Execute(append(append([]string{"-o", output}, args...), writeCsv(t, dir, "networks.csv", content))); err != nil {
+		t.Fatal(err)
+	}
+	return output
+}
+
+// testReleases returns the paths of two releases of a database: in the
+// second, a city moved, a network shrank, and a network was added.
+func testReleases(t *testing.T) (string, string) {
+	return buildCsv(t, `network,country,city
+81.2.69.142/31,GB,London
+89.160.20.112/28,SE,Linköping
+10.0.0.0/16,US,Ashburn
+`), buildCsv(t, `network,country,city
+81.2.69.142/31,GB,Islington
+89.160.20.112/28,SE,Linköping
+10.0.0.0/17,US,Ashburn
+2001:db8::/32,DE,Berlin
+`)
+}
+
+func TestDiffCommand(t *testing.T) {
+	oldPath, newPath := testReleases(t)
+
+	output, err := runCommand(newDiffCommand, oldPath, newPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"10.0.128.0/17                            removed  country: US, city: Ashburn\n",
+		"81.2.69.142/31                           changed  city: London -> Islington\n",
+		"2001:db8::/32                            added    country: DE, city: Berlin\n",
+		"Networks:  1 changed, 1 added, 1 removed\n",
+		"  DE       0 changed, 1 added, 0 removed\n",
+		"  GB       1 changed, 0 added, 0 removed\n",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("missing %q in output:\n%s", want, output)
+		}
+	}
+	if strings.Contains(output, "89.160.20.112") {
+		t.Errorf("unchanged network in output:\n%s", output)
+	}
+
+	var result struct {
+		Changes []DiffChange `json:"changes"`
+		Summary DiffSummary  `json:"summary"`
+	}
+	output, err = runCommand(newDiffCommand, "-o", "json", "-F", "country", oldPath, newPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = json.Unmarshal([]byte(output), &result); err != nil {
+		t.Fatalf("%s: %s", err, output)
+	}
+	want := []DiffChange{
+		{Network: "10.0.128.0/17", Change: DiffRemoved, Old: map[string]string{"country": "US"}},
+		{Network: "2001:db8::/32", Change: DiffAdded, New: map[string]string{"country": "DE"}},
+	}
+	if !reflect.DeepEqual(result.Changes, want) || result.Summary.Total() != 2 || result.Summary.Countries["US"].Removed != 1 {
+		t.Errorf("unexpected result %+v", result)
+	}
+}
+
+func TestDiffCommandCidrs(t *testing.T) {
+	oldPath, newPath := testReleases(t)
+
+	output, err := runCommand(newDiffCommand, "-o", "json", "-cidr", "81.2.69.0/24", "-c", "10.0.192.0/18", oldPath, newPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var result struct {
+		Changes []DiffChange `json:"changes"`
+	}
+	if err = json.Unmarshal([]byte(output), &result); err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Changes) != 2 || result.Changes[0].Network != "10.0.192.0/18" || result.Changes[1].Network != "81.2.69.142/31" ||
+		!reflect.DeepEqual(result.Changes[1].Fields, []string{"city"}) {
+		t.Errorf("unexpected changes %+v", result.Changes)
+	}
+
+	// -exit.code fails when the databases differ
+	var differs *differencesError
+	if _, err = runCommand(newDiffCommand, "-e", "-c", "81.2.69.0/24", oldPath, newPath); !errors.As(err, &differs) || differs.count != 1 {
+		t.Errorf("diff = %v; want 1 network to differ", err)
+	}
+	if output, err = runCommand(newDiffCommand, "-exit.code", "-c", "89.160.20.0/24", oldPath, newPath); err != nil || output != "Networks:  0 changed, 0 added, 0 removed\n" {
+		t.Errorf("diff = %q, %v", output, err)
+	}
+}
+
+func TestDiffCommandNestedCidrs(t *testing.T) {
+	oldPath, newPath := testReleases(t)
+
+	// a change within limits nested in another is reported once
+	output, err := runCommand(newDiffCommand, "-cidr", "81.0.0.0/8", "-cidr", "81.2.69.0/24", "-c", "81.2.69.142/32", oldPath, newPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count := strings.Count(output, "81.2.69.142/31"); count != 1 || !strings.Contains(output, "Networks:  1 changed, 0 added, 0 removed\n") {
+		t.Errorf("unexpected output:\n%s", output)
+	}
+}
+
+func TestDiffCommandAsn(t *testing.T) {
+	oldPath := buildFixture(t, "GeoLite2-ASN-Test.csv")
+	newPath := buildCsv(t, `network,autonomous_system_number,autonomous_system_organization
+1.128.0.0/11,1221,Telstra Pty Ltd
+12.81.92.0/22,7019,AT&T Services
+81.2.69.142/31,20712,Andrews & Arnold Ltd
+89.160.20.112/28,29518,Bredband2 AB
+2600:6000::/20,237,Merit Network Inc.
+`, "-t", "GeoLite2-ASN")
+
+	// ASN databases compare their autonomous systems by default
+	for _, args := range [][]string{{oldPath, newPath}, {"-F", "asn", oldPath, newPath}} {
+		output, err := runCommand(newDiffCommand, args...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(output, "12.81.92.0/22                            changed  asn: AS7018 -> AS7019\n\nNetworks:  1 changed") {
+			t.Errorf("%v: unexpected output:\n%s", args, output)
+		}
+	}
+}
+
+func TestDiffCommandErrors(t *testing.T) {
+	oldPath, newPath := testReleases(t)
+
+	for _, args := range [][]string{
+		{oldPath},
+		{"-o", "csv", oldPath, newPath},
+		{"-F", "country,site", oldPath, newPath},
+		{"-c", "10.0.0.0/33", oldPath, newPath},
+	} {
+		var usage *UsageError
+		if _, err := runCommand(newDiffCommand, args...); !errors.As(err, &usage) {
+			t.Errorf("%v = %v; want a *UsageError", args, err)
+		}
+	}
+}
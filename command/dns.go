@@ -0,0 +1,260 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/miekg/dns"
+	"github.com/rabbitt/maxmind/mm"
+)
+
+const (
+	DefaultDnsListen = "127.0.0.1:5353"
+	DefaultDnsZone   = "geo.internal."
+	DefaultDnsTtl    = 3600
+)
+
+type DnsCommand struct {
+	Ui          Ui
+	database    *mm.Database
+	asnDatabase *mm.Database
+	zone        string
+	ttl         uint32
+}
+
+// ipFromReverseName extracts the address from a reverse-ordered query name,
+// e.g. 4.3.2.1.<zone> for IPv4, or 32 nibbles ahead of <zone> for IPv6, in
+// the same style as in-addr.arpa and ip6.arpa. It returns nil if the name is
+// not within zone or is not a well formed reverse address.
+func ipFromReverseName(name, zone string) net.IP {
+	name = strings.ToLower(dns.Fqdn(name))
+	suffix := "." + strings.ToLower(dns.Fqdn(zone))
+	if !strings.HasSuffix(name, suffix) {
+		return nil
+	}
+
+	labels := strings.Split(strings.TrimSuffix(name, suffix), ".")
+
+	switch len(labels) {
+	case net.IPv4len:
+		ip := make(net.IP, net.IPv4len)
+		for idx, label := range labels {
+			octet, err := strconv.ParseUint(label, 10, 8)
+			if err != nil {
+				return nil
+			}
+			ip[net.IPv4len-1-idx] = byte(octet)
+		}
+		return net.IPv4(ip[0], ip[1], ip[2], ip[3])
+
+	case net.IPv6len * 2:
+		ip := make(net.IP, net.IPv6len)
+		for idx, label := range labels {
+			nibble, err := strconv.ParseUint(label, 16, 4)
+			if err != nil || len(label) != 1 {
+				return nil
+			}
+			pos := len(labels) - 1 - idx
+			if pos%2 == 0 {
+				ip[pos/2] |= byte(nibble) << 4
+			} else {
+				ip[pos/2] |= byte(nibble)
+			}
+		}
+		return ip
+	}
+
+	return nil
+}
+
+// txtRecord builds the TXT strings for ip: country code, subdivision and,
+// when an ASN database is configured, the autonomous system number.
+func (c *DnsCommand) txtRecord(ip net.IP) ([]string, error) {
+	record, err := c.database.LookupIP(context.Background(), ip)
+	if _, notFound := err.(*mm.NotFoundError); notFound {
+		record, err = &mm.GeoData{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	txt := []string{record.Country.IsoCode, record.Subdivision.Name}
+
+	if c.asnDatabase != nil {
+		asn, _, err := c.asnDatabase.LookupAsn(ip.String())
+		if err != nil {
+			return nil, err
+		}
+		if asn != 0 {
+			txt = append(txt, fmt.Sprintf("AS%d", asn))
+		} else {
+			txt = append(txt, "")
+		}
+	}
+
+	return txt, nil
+}
+
+func (c *DnsCommand) ServeDNS(writer dns.ResponseWriter, req *dns.Msg) {
+	msg := new(dns.Msg)
+	msg.SetReply(req)
+	msg.Authoritative = true
+
+	defer writer.WriteMsg(msg)
+
+	if len(req.Question) != 1 {
+		msg.Rcode = dns.RcodeFormatError
+		return
+	}
+
+	question := req.Question[0]
+	ip := ipFromReverseName(question.Name, c.zone)
+	if ip == nil {
+		if !dns.IsSubDomain(c.zone, question.Name) {
+			msg.Authoritative = false
+			msg.Rcode = dns.RcodeRefused
+		} else if !strings.EqualFold(dns.Fqdn(question.Name), c.zone) {
+			msg.Rcode = dns.RcodeNameError
+		}
+		return
+	}
+
+	if question.Qtype != dns.TypeTXT && question.Qtype != dns.TypeANY {
+		return
+	}
+
+	txt, err := c.txtRecord(ip)
+	if err != nil {
+		c.Ui.Errorf("failed to handle query for %s; error was: %s\n", question.Name, err)
+		msg.Rcode = dns.RcodeServerFailure
+		return
+	}
+
+	msg.Answer = append(msg.Answer, &dns.TXT{
+		Hdr: dns.RR_Header{
+			Name:   question.Name,
+			Rrtype: dns.TypeTXT,
+			Class:  dns.ClassINET,
+			Ttl:    c.ttl,
+		},
+		Txt: txt,
+	})
+}
+
+func (c *DnsCommand) Help() string {
+	return fmt.Sprintf(`Usage: %s dns [options]
+
+Run as a DNS server, answering TXT queries for reverse-ordered addresses
+within the zone, e.g. 4.3.2.1.%s for 1.2.3.4, with the country, subdivision
+and (when an ASN database is given) autonomous system of the address.
+
+Options:
+  -l, -dns.listen     <address>    Address to listen on, for both UDP and TCP
+                                   (default: %s)
+  -z, -dns.zone       <domain>     Zone suffix to answer queries for
+                                   (default: %s)
+  -t, -dns.ttl        <integer>    TTL, in seconds, of answers (default: %d)
+  -f, -database.file  <file>       Path to MaxMind Database
+                                   (default: %s)
+  -a, -asn.file       <file>       Path to MaxMind ASN Database (optional)
+`, os.Args[0], DefaultDnsZone, DefaultDnsListen, DefaultDnsZone, DefaultDnsTtl, DefaultDatabasePath)
+}
+
+func (c *DnsCommand) Synopsis() string {
+	return "Run as a DNS server answering TXT queries"
+}
+
+// Execute answers DNS queries until signalled to stop, returning an error
+// if it could not be started or stopped serving unexpectedly.
+func (c *DnsCommand) Execute(args []string) error {
+	var err error
+
+	var mainParse = flag.NewFlagSet("dns", flag.ContinueOnError)
+	listen := mainParse.String("dns.listen", DefaultDnsListen, "`address` to listen on for UDP and TCP queries")
+	mainParse.StringVar(listen, "l", DefaultDnsListen, "`address` to listen on for UDP and TCP queries")
+	zone := mainParse.String("dns.zone", DefaultDnsZone, "`domain` suffix to answer queries for")
+	mainParse.StringVar(zone, "z", DefaultDnsZone, "`domain` suffix to answer queries for")
+	ttl := mainParse.Uint("dns.ttl", DefaultDnsTtl, "TTL, in `seconds`, of answers")
+	mainParse.UintVar(ttl, "t", DefaultDnsTtl, "TTL, in `seconds`, of answers")
+	dbFile := mainParse.String("database.file", DefaultDatabasePath, "`path` to the database file that contains GeoIP information")
+	mainParse.StringVar(dbFile, "f", DefaultDatabasePath, "`path` to the database file that contains GeoIP information")
+	asnFile := mainParse.String("asn.file", "", "`path` to the database file that contains ASN information")
+	mainParse.StringVar(asnFile, "a", "", "`path` to the database file that contains ASN information")
+
+	mainParse.Usage = func() {
+		c.Ui.Output(c.Help())
+		mainParse.PrintDefaults()
+	}
+	if err := mainParse.Parse(args); err != nil {
+		return usageError(err)
+	}
+
+	if *zone == "" {
+		return usageError(errors.New("missing required dns zone"))
+	}
+	c.zone = dns.Fqdn(strings.ToLower(*zone))
+	c.ttl = uint32(*ttl)
+
+	if *dbFile == "" {
+		return usageError(errors.New("missing required path to MasterMind DB file"))
+	}
+
+	dbPath, err := mm.NewPathname(*dbFile).RealPath()
+	if err != nil {
+		return err
+	}
+
+	if c.database, err = mm.GetDatabase(dbPath.Path()); err != nil {
+		return err
+	}
+	defer mm.CloseDatabases()
+
+	if *asnFile != "" {
+		asnPath, err := mm.NewPathname(*asnFile).RealPath()
+		if err != nil {
+			return err
+		}
+
+		if c.asnDatabase, err = mm.OpenDatabase(asnPath.Path()); err != nil {
+			return err
+		}
+		defer c.asnDatabase.Close()
+	}
+
+	serveErrors := make(chan error, 2)
+	servers := []*dns.Server{
+		{Addr: *listen, Net: "udp", Handler: c},
+		{Addr: *listen, Net: "tcp", Handler: c},
+	}
+
+	for _, server := range servers {
+		go func(server *dns.Server) {
+			serveErrors <- server.ListenAndServe()
+		}(server)
+	}
+
+	c.Ui.Infof("Answering TXT queries for %s on %s (udp/tcp) ...\n", c.zone, *listen)
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	select {
+	case err = <-serveErrors:
+	case <-signals:
+	}
+
+	for _, server := range servers {
+		server.Shutdown()
+	}
+
+	return err
+}
@@ -0,0 +1,83 @@
+package command
+
+import (
+	"net"
+	"reflect"
+	"testing"
+
+	"github.com/rabbitt/maxmind/mm"
+)
+
+func TestIpFromReverseName(t *testing.T) {
+	for _, test := range []struct {
+		name string
+		want string
+	}{
+		{"142.69.2.81.geo.internal.", "81.2.69.142"},
+		{"142.69.2.81.GEO.Internal", "81.2.69.142"},
+		{"1.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.8.1.2.0.1.0.0.2.geo.internal.", "2001:218::1"},
+		{"F.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.8.1.2.0.1.0.0.2.geo.internal.", "2001:218::f"},
+
+		// not within the zone
+		{"142.69.2.81.in-addr.arpa.", ""},
+		{"geo.internal.", ""},
+		{"142.69.2.81.geo.internal.example.", ""},
+
+		// malformed IPv4
+		{"69.2.81.geo.internal.", ""},
+		{"5.142.69.2.81.geo.internal.", ""},
+		{"256.69.2.81.geo.internal.", ""},
+		{"-1.69.2.81.geo.internal.", ""},
+		{"x.69.2.81.geo.internal.", ""},
+		{"142..2.81.geo.internal.", ""},
+
+		// malformed, and truncated, IPv6 nibbles
+		{"0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.8.1.2.0.1.0.0.2.geo.internal.", ""},
+		{"10.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.8.1.2.0.1.0.0.2.geo.internal.", ""},
+		{"g.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.8.1.2.0.1.0.0.2.geo.internal.", ""},
+		{"00.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.8.1.2.0.1.0.0.2.geo.internal.", ""},
+	} {
+		got := ipFromReverseName(test.name, DefaultDnsZone)
+		if test.want == "" {
+			if got != nil {
+				t.Errorf("ipFromReverseName(%q) = %s; want nil", test.name, got)
+			}
+		} else if !got.Equal(net.ParseIP(test.want)) {
+			t.Errorf("ipFromReverseName(%q) = %s; want %s", test.name, got, test.want)
+		}
+	}
+}
+
+func TestDnsTxtRecord(t *testing.T) {
+	database, err := mm.OpenDatabase(buildFixture(t, "GeoIP2-City-Test.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(database.Close)
+	asnDatabase, err := mm.OpenDatabase(buildFixture(t, "GeoLite2-ASN-Test.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(asnDatabase.Close)
+
+	c := &DnsCommand{database: database}
+	for ip, want := range map[string][]string{
+		"81.2.69.142": {"GB", "England"},
+		"10.0.0.1":    {"", ""},
+	} {
+		if txt, err := c.txtRecord(net.ParseIP(ip)); err != nil || !reflect.DeepEqual(txt, want) {
+			t.Errorf("txtRecord(%s) = %q, %v; want %q", ip, txt, err, want)
+		}
+	}
+
+	c.asnDatabase = asnDatabase
+	for ip, want := range map[string][]string{
+		"1.128.0.1":   {"", "", "AS1221"},
+		"81.2.69.142": {"GB", "England", "AS20712"},
+		"10.0.0.1":    {"", "", ""},
+	} {
+		if txt, err := c.txtRecord(net.ParseIP(ip)); err != nil || !reflect.DeepEqual(txt, want) {
+			t.Errorf("txtRecord(%s) = %q, %v; want %q", ip, txt, err, want)
+		}
+	}
+}
@@ -0,0 +1,390 @@
+package command
+
+import (
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/pquerna/ffjson/ffjson"
+	"github.com/rabbitt/maxmind/mm"
+)
+
+type DumpCommand struct {
+	Ui Ui
+}
+
+var DumpFormats = map[string]bool{
+	"csv":    true,
+	"ndjson": true,
+	"json":   true,
+}
+
+// uiWriter writes to the output of a Ui.
+type uiWriter struct {
+	ui Ui
+}
+
+func (w uiWriter) Write(p []byte) (int, error) {
+	w.ui.Outputf("%s", p)
+	return len(p), nil
+}
+
+// recordWriter renders network records in one of the DumpFormats.
+type recordWriter interface {
+	Write(record *mm.NetworkRecord) error
+
+	// Close ends the output, and flushes it.
+	Close() error
+}
+
+// csvHeader names the columns of CSV dumps, after those the build command
+// reads.
+var csvHeader = []string{
+	"network", "continent", "country", "country_name", "subdivision", "subdivision_name", "city",
+	"postal", "latitude", "longitude", "accuracy_radius", "time_zone", "registered_country.iso_code",
+}
+
+var asnHeader = []string{"autonomous_system_number", "autonomous_system_organization"}
+
+type csvRecordWriter struct {
+	out    *bufio.Writer
+	writer *csv.Writer
+	asn    bool
+	row    []string
+}
+
+func formatFloat(value float64) string {
+	if value == 0 {
+		return ""
+	}
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+func formatUint(value uint64) string {
+	if value == 0 {
+		return ""
+	}
+	return strconv.FormatUint(value, 10)
+}
+
+// writeHeader writes the header, before the first row.
+func (w *csvRecordWriter) writeHeader() error {
+	header := csvHeader
+	if w.asn {
+		header = append(header[:len(header):len(header)], asnHeader...)
+	}
+	w.row = make([]string, len(header))
+	return w.writer.Write(header)
+}
+
+func (w *csvRecordWriter) Write(record *mm.NetworkRecord) error {
+	if w.row == nil {
+		if err := w.writeHeader(); err != nil {
+			return err
+		}
+	}
+
+	data := record.Data
+	if data == nil {
+		data = &mm.GeoData{}
+	}
+	row := append(w.row[:0],
+		record.Network,
+		data.Continent.Code,
+		data.Country.IsoCode,
+		data.Country.Name,
+		data.Subdivision.IsoCode,
+		data.Subdivision.Name,
+		data.City.Name,
+		data.Postal.Code,
+		formatFloat(data.Location.Latitude),
+		formatFloat(data.Location.Longitude),
+		formatUint(uint64(data.Location.AccuracyRadius)),
+		data.Location.TimeZone,
+		data.RegisteredCountry.IsoCode,
+	)
+	if w.asn {
+		row = append(row, formatUint(uint64(record.Asn)), record.AsOrg)
+	}
+	return w.writer.Write(row)
+}
+
+func (w *csvRecordWriter) Close() error {
+	if w.row == nil {
+		if err := w.writeHeader(); err != nil {
+			return err
+		}
+	}
+	w.writer.Flush()
+	if err := w.writer.Error(); err != nil {
+		return err
+	}
+	return w.out.Flush()
+}
+
+// jsonRecordWriter writes records one per line, either bare (NDJSON), or
+// as the elements of a JSON array.
+type jsonRecordWriter struct {
+	out   *bufio.Writer
+	array bool
+	count int
+}
+
+func (w *jsonRecordWriter) Write(record *mm.NetworkRecord) error {
+	j, err := ffjson.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	if w.array {
+		if w.count == 0 {
+			w.out.WriteString("[\n")
+		} else {
+			w.out.WriteString(",\n")
+		}
+	}
+	w.count++
+
+	w.out.Write(j)
+	if !w.array {
+		w.out.WriteString("\n")
+	}
+	return nil
+}
+
+func (w *jsonRecordWriter) Close() error {
+	if w.array {
+		if w.count == 0 {
+			w.out.WriteString("[")
+		}
+		w.out.WriteString("\n]\n")
+	}
+	return w.out.Flush()
+}
+
+// newRecordWriter returns a writer of records to out in format, one of the
+// DumpFormats; asn adds the autonomous system columns to CSV output.
+func newRecordWriter(out io.Writer, format string, asn bool) recordWriter {
+	buffered := bufio.NewWriterSize(out, 64*1024)
+	switch format {
+	case "csv":
+		return &csvRecordWriter{out: buffered, writer: csv.NewWriter(buffered), asn: asn}
+	case "json":
+		return &jsonRecordWriter{out: buffered, array: true}
+	}
+	return &jsonRecordWriter{out: buffered}
+}
+
+// parseCidrs parses networks in CIDR notation, or single addresses,
+// returning IPv4 networks in their 4 byte form.
+func parseCidrs(texts []string) ([]*net.IPNet, error) {
+	var networks []*net.IPNet
+	for _, text := range texts {
+		for _, item := range strings.Split(text, ",") {
+			if item = strings.TrimSpace(item); item == "" {
+				continue
+			}
+			if !strings.Contains(item, "/") {
+				if ip := net.ParseIP(item); ip != nil && ip.To4() != nil {
+					item += "/32"
+				} else {
+					item += "/128"
+				}
+			}
+
+			_, network, err := net.ParseCIDR(item)
+			if err != nil {
+				return nil, fmt.Errorf("invalid network '%s'", item)
+			}
+			networks = append(networks, network)
+		}
+	}
+	return networks, nil
+}
+
+// splitList splits repeated, and comma separated, flag values.
+func splitList(values []string) []string {
+	var items []string
+	for _, value := range values {
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+	}
+	return items
+}
+
+func (c *DumpCommand) Help() string {
+	return fmt.Sprintf(`Usage: %s dump [options]
+
+Walks every network of a database, writing each with what the database
+says about it, e.g. to audit what it says about your own netblocks. The
+networks are streamed, so memory use stays flat however large the database.
+
+Options:
+  -f, -database.file  <file>      Path to MaxMind Database, or directory of
+                                  its CSV edition (default: %s)
+  -o, -output.type    <string>    Output format; one of csv, ndjson, or json
+                                  (default: %s)
+  -w, -output.file    <file>      File to write to (default: stdout)
+  -n, -network        <cidr>      Only dump the parts of networks within cidr;
+                                  may be repeated
+  -c, -country        <iso code>  Only dump networks in the country; may be
+                                  repeated
+  -a, -asn            <number>    Only dump networks of the autonomous system;
+                                  may be repeated, and requires an ASN database
+  -A, -asn.file       <file>      ASN database, such as GeoLite2-ASN, to look up
+                                  the autonomous system of each network in
+                                  (default: the database, if it is one)
+
+CSV output names its columns as the build command reads them, but has only
+the most specific subdivision of each network, so that a database built from
+a dump lacks the subdivisions above it.
+`, os.Args[0], DefaultDatabasePath, "csv")
+}
+
+func (c *DumpCommand) Synopsis() string {
+	return "Dump the networks of a database to CSV, NDJSON or JSON"
+}
+
+// Execute dumps the networks of the database, as filtered by args.
+func (c *DumpCommand) Execute(args []string) error {
+	var err error
+
+	var mainParse = flag.NewFlagSet("dump", flag.ContinueOnError)
+	dbFile := mainParse.String("database.file", DefaultDatabasePath, "`path` to the database file to dump")
+	mainParse.StringVar(dbFile, "f", DefaultDatabasePath, "`path` to the database file to dump")
+	outType := mainParse.String("output.type", "csv", "output `format`; one of 'csv', 'ndjson', or 'json'")
+	mainParse.StringVar(outType, "o", "csv", "output `format`; one of 'csv', 'ndjson', or 'json'")
+	outFile := mainParse.String("output.file", "", "`path` of the file to write; stdout when empty")
+	mainParse.StringVar(outFile, "w", "", "`path` of the file to write; stdout when empty")
+	var cidrs, countries, asns ListFlag
+	mainParse.Var(&cidrs, "network", "only dump the parts of networks within `cidr`; may be repeated")
+	mainParse.Var(&cidrs, "n", "only dump the parts of networks within `cidr`; may be repeated")
+	mainParse.Var(&countries, "country", "only dump networks in the country with ISO `code`; may be repeated")
+	mainParse.Var(&countries, "c", "only dump networks in the country with ISO `code`; may be repeated")
+	mainParse.Var(&asns, "asn", "only dump networks of the autonomous system `number`; may be repeated")
+	mainParse.Var(&asns, "a", "only dump networks of the autonomous system `number`; may be repeated")
+	asnFile := mainParse.String("asn.file", "", "`path` to an ASN database to look up the autonomous system of each network in")
+	mainParse.StringVar(asnFile, "A", "", "`path` to an ASN database to look up the autonomous system of each network in")
+
+	mainParse.Usage = func() {
+		c.Ui.Output(c.Help())
+		mainParse.PrintDefaults()
+	}
+	if err = mainParse.Parse(args); err != nil {
+		return usageError(err)
+	}
+
+	if !DumpFormats[*outType] {
+		return usageError(fmt.Errorf("invalid output type '%s'; expected one of 'csv', 'ndjson', or 'json'", *outType))
+	}
+	if *dbFile == "" {
+		return usageError(errors.New("missing required path to MasterMind DB file"))
+	}
+
+	limits, err := parseCidrs(cidrs)
+	if err != nil {
+		return usageError(err)
+	}
+
+	wantCountries := map[string]bool{}
+	for _, country := range splitList(countries) {
+		wantCountries[strings.ToUpper(country)] = true
+	}
+
+	wantAsns, err := parseAsns(asns)
+	if err != nil {
+		return usageError(err)
+	}
+
+	dbPath, err := mm.NewPathname(*dbFile).RealPath()
+	if err != nil {
+		return err
+	}
+	database, err := openDatabase(dbPath.Path())
+	if err != nil {
+		return err
+	}
+	defer database.Close()
+
+	// the autonomous system of each network comes from the ASN database,
+	// or from the database itself when it is one
+	asnDatabase := database
+	asnOnly := strings.Contains(database.Metadata().Type, "ASN")
+	if *asnFile != "" {
+		asnPath, err := mm.NewPathname(*asnFile).RealPath()
+		if err != nil {
+			return err
+		}
+		if asnDatabase, err = openDatabase(asnPath.Path()); err != nil {
+			return err
+		}
+		defer asnDatabase.Close()
+	} else if !asnOnly {
+		asnDatabase = nil
+	}
+
+	if len(wantAsns) > 0 && asnDatabase == nil {
+		return usageError(errors.New("filtering by autonomous system requires an ASN database; give one with -asn.file"))
+	}
+
+	var out io.Writer = uiWriter{c.Ui}
+	if *outFile != "" {
+		file, err := os.Create(*outFile)
+		if err != nil {
+			return &mm.PathError{Path: *outFile, Err: err}
+		}
+		defer file.Close()
+		out = file
+	}
+	writer := newRecordWriter(out, *outType, asnDatabase != nil)
+
+	// the networks are split where the autonomous systems change, so that
+	// each has a single one
+	var walker, asnWalker mm.NetworkWalker = database, asnDatabase
+	if len(limits) > 0 {
+		walker = database.Within(limits)
+		if asnDatabase != nil {
+			asnWalker = asnDatabase.Within(limits)
+		}
+	}
+	if asnDatabase != nil && !asnOnly {
+		walker = mm.SplitNetworks(walker, asnWalker)
+	}
+
+	err = walker.Networks(func(network *net.IPNet, data *mm.GeoData) error {
+		if len(wantCountries) > 0 && !wantCountries[data.Country.IsoCode] {
+			return nil
+		}
+
+		record := &mm.NetworkRecord{Network: network.String(), Data: data}
+		if asnOnly {
+			record.Data = nil
+		}
+
+		if asnDatabase != nil {
+			asn, asOrg, err := asnDatabase.LookupAsn(network.IP.String())
+			if err != nil {
+				return err
+			}
+			record.Asn, record.AsOrg = asn, asOrg
+			if len(wantAsns) > 0 && !wantAsns[record.Asn] {
+				return nil
+			}
+		}
+		return writer.Write(record)
+	})
+	if err != nil {
+		return err
+	}
+	return writer.Close()
+}
@@ -0,0 +1,195 @@
+package command
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/rabbitt/maxmind/mm"
+)
+
+func newDumpCommand(ui Ui) executor {
+	return &DumpCommand{Ui: ui}
+}
+
+// dumpedNetworks returns the network column of CSV output.
+func dumpedNetworks(t *testing.T, output string) []string {
+	rows, err := csv.NewReader(strings.NewReader(output)).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var networks []string
+	for _, row := range rows[1:] {
+		networks = append(networks, row[0])
+	}
+	return networks
+}
+
+func TestDumpCommandCsv(t *testing.T) {
+	path := buildFixture(t, "GeoIP2-City-Test.json")
+	output := mustRunCommand(t, newDumpCommand, "-f", path)
+
+	rows, err := csv.NewReader(strings.NewReader(output)).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 7 || !reflect.DeepEqual(rows[0], csvHeader) {
+		t.Fatalf("unexpected output %q", output)
+	}
+	want := []string{"81.2.69.142/31", "EU", "GB", "United Kingdom", "ENG", "England", "London", "", "51.5142", "-0.0931", "10", "Europe/London", "US"}
+	for _, row := range rows[1:] {
+		if row[0] == want[0] && !reflect.DeepEqual(row, want) {
+			t.Errorf("got  %q\nwant %q", row, want)
+		}
+	}
+
+	// the dump builds back into the database it came from, but for the
+	// subdivisions above the most specific
+	dir := t.TempDir()
+	rebuilt := filepath.Join(dir, "rebuilt.mmdb")
+	c := &BuildCommand{Ui: &BaseUi{Writer: &bytes.Buffer{}, ErrorWriter: &bytes.Buffer{}}}
+	if err = c.Execute([]string{"-o", rebuilt, writeCsv(t, dir, "dump.csv", output)}); err != nil {
+		t.Fatal(err)
+	}
+	database, err := mm.OpenDatabase(rebuilt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer database.Close()
+	if record, err := database.Lookup("89.160.20.115"); err != nil || record.City.Name != "Linköping" || record.Subdivision.IsoCode != "LI" {
+		t.Errorf("89.160.20.115 = %+v, %v", record, err)
+	}
+}
+
+func TestDumpCommandJson(t *testing.T) {
+	path := buildFixture(t, "GeoIP2-City-Test.json")
+
+	lines := strings.Split(strings.TrimSpace(mustRunCommand(t, newDumpCommand, "-f", path, "-o", "ndjson")), "\n")
+	if len(lines) != 6 {
+		t.Fatalf("unexpected output %q", lines)
+	}
+	var record mm.NetworkRecord
+	if err := json.Unmarshal([]byte(lines[0]), &record); err != nil {
+		t.Fatal(err)
+	}
+	if record.Network == "" || record.Data == nil || record.Data.Country.IsoCode == "" {
+		t.Errorf("unexpected record %s", lines[0])
+	}
+
+	var records []mm.NetworkRecord
+	if err := json.Unmarshal([]byte(mustRunCommand(t, newDumpCommand, "-f", path, "-o", "json")), &records); err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 6 {
+		t.Errorf("unexpected records %+v", records)
+	}
+
+	if err := json.Unmarshal([]byte(mustRunCommand(t, newDumpCommand, "-f", path, "-o", "json", "-c", "ZZ")), &records); err != nil || len(records) != 0 {
+		t.Errorf("empty dump = %+v, %v", records, err)
+	}
+}
+
+func TestDumpCommandFilters(t *testing.T) {
+	path := buildFixture(t, "GeoIP2-City-Test.json")
+	asnPath := buildFixture(t, "GeoLite2-ASN-Test.csv")
+
+	for _, test := range []struct {
+		args []string
+		want []string
+	}{
+		{[]string{"-n", "81.2.69.0/24"}, []string{"81.2.69.142/31"}},
+		{[]string{"-network", "81.2.69.142/32,2001:218:1::/48"}, []string{"81.2.69.142/32", "2001:218:1::/48"}},
+		{[]string{"-n", "89.160.20.112/30", "-n", "81.2.69.0/24", "-n", "81.2.0.0/16"}, []string{"81.2.69.142/31", "89.160.20.112/30"}},
+		{[]string{"-n", "10.0.0.0/8"}, nil},
+		{[]string{"-c", "se", "-country", "JP"}, []string{"89.160.20.112/28", "2001:218::/32"}},
+		{[]string{"-A", asnPath, "-a", "AS20712,29518"}, []string{"81.2.69.142/31", "89.160.20.112/28"}},
+		{[]string{"-asn.file", asnPath, "-asn", "29518", "-c", "GB"}, nil},
+	} {
+		got := dumpedNetworks(t, mustRunCommand(t, newDumpCommand, append([]string{"-f", path}, test.args...)...))
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%v = %q; want %q", test.args, got, test.want)
+		}
+	}
+}
+
+func TestDumpCommandAsn(t *testing.T) {
+	asnPath := buildFixture(t, "GeoLite2-ASN-Test.csv")
+
+	// an ASN database dumps its own autonomous systems
+	var record mm.NetworkRecord
+	output := mustRunCommand(t, newDumpCommand, "-f", asnPath, "-o", "ndjson", "-n", "1.128.0.0/11")
+	if err := json.Unmarshal([]byte(output), &record); err != nil {
+		t.Fatal(err)
+	}
+	if record.Network != "1.128.0.0/11" || record.Asn != 1221 || record.AsOrg != "Telstra Pty Ltd" || record.Data != nil {
+		t.Errorf("unexpected record %q", output)
+	}
+
+	// and CSV output gains their columns
+	file := filepath.Join(t.TempDir(), "asn.csv")
+	if output = mustRunCommand(t, newDumpCommand, "-f", asnPath, "-a", "7018", "-w", file); output != "" {
+		t.Errorf("unexpected output %q", output)
+	}
+	content, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Split(strings.TrimSpace(string(content)), "\n"); len(lines) != 2 ||
+		!strings.HasSuffix(lines[0], ",autonomous_system_number,autonomous_system_organization") ||
+		!strings.HasPrefix(lines[1], "12.81.92.0/22,") || !strings.HasSuffix(lines[1], ",7018,AT&T Services") {
+		t.Errorf("unexpected output %q", content)
+	}
+}
+
+func TestDumpCommandSplitsAsns(t *testing.T) {
+	path := buildCsv(t, "network,country\n10.0.0.0/22,IR\n")
+	asnPath := buildCsv(t, `network,autonomous_system_number:uint32,autonomous_system_organization
+10.0.0.0/24,64500,First
+10.0.1.0/24,64501,Second
+`, "-t", "GeoLite2-ASN")
+
+	// each part of a network has the autonomous system it lies in
+	rows, err := csv.NewReader(strings.NewReader(mustRunCommand(t, newDumpCommand, "-f", path, "-A", asnPath))).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got [][]string
+	for _, row := range rows[1:] {
+		got = append(got, []string{row[0], row[2], row[len(row)-2], row[len(row)-1]})
+	}
+	want := [][]string{
+		{"10.0.0.0/24", "IR", "64500", "First"},
+		{"10.0.1.0/24", "IR", "64501", "Second"},
+		{"10.0.2.0/23", "IR", "", ""},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q\nwant %q", got, want)
+	}
+
+	if networks := dumpedNetworks(t, mustRunCommand(t, newDumpCommand, "-f", path, "-A", asnPath, "-a", "64501", "-n", "10.0.1.128/25")); !reflect.DeepEqual(networks, []string{"10.0.1.128/25"}) {
+		t.Errorf("got %q", networks)
+	}
+}
+
+func TestDumpCommandErrors(t *testing.T) {
+	path := buildFixture(t, "GeoIP2-City-Test.json")
+
+	for _, args := range [][]string{
+		{"-f", path, "-o", "xml"},
+		{"-f", ""},
+		{"-f", path, "-n", "10.0.0.0/33"},
+		{"-f", path, "-a", "telstra", "-A", path},
+		{"-f", path, "-a", "1221"},
+	} {
+		var usage *UsageError
+		if _, err := runCommand(newDumpCommand, args...); !errors.As(err, &usage) {
+			t.Errorf("%v = %v; want a *UsageError", args, err)
+		}
+	}
+}
@@ -0,0 +1,22 @@
+package command
+
+import "flag"
+
+// UsageError reports a command invoked with missing or invalid arguments.
+type UsageError struct {
+	Err error
+}
+
+func (e *UsageError) Error() string {
+	return e.Err.Error()
+}
+
+// usageError wraps err, as returned when parsing arguments, in a
+// UsageError. Asking for help is not an error; the usage has already been
+// printed by then.
+func usageError(err error) error {
+	if err == flag.ErrHelp {
+		return nil
+	}
+	return &UsageError{Err: err}
+}
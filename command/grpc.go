@@ -0,0 +1,257 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/rabbitt/maxmind/mm"
+	"github.com/rabbitt/maxmind/rpc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+)
+
+// GrpcService implements rpc.GeoIPServer on top of the ServerCommand's
+// database and cache.
+type GrpcService struct {
+	rpc.UnimplementedGeoIPServer
+	server *ServerCommand
+}
+
+func (s *GrpcService) lookup(ctx context.Context, ipText string) *rpc.LookupResponse {
+	response := &rpc.LookupResponse{
+		Ip:      ipText,
+		Status:  "success",
+		Message: "OK",
+	}
+
+	record, _, err := s.server.lookupRecord(ctx, ipText)
+	if err != nil {
+		response.Status = "error"
+		response.Message = err.Error()
+		withFields(s.server.Ui, "ip", ipText).Errorf("failed to handle grpc request for %s; error was: %s\n", ipText, err)
+		return response
+	}
+
+	response.Data = rpc.NewFromGeoData(record)
+	return response
+}
+
+func (s *GrpcService) Lookup(ctx context.Context, req *rpc.LookupRequest) (*rpc.LookupResponse, error) {
+	return s.lookup(ctx, req.Ip), nil
+}
+
+func (s *GrpcService) BatchLookup(ctx context.Context, req *rpc.BatchLookupRequest) (*rpc.BatchLookupResponse, error) {
+	if len(req.Ips) > mm.MaxBatchSize {
+		return nil, status.Errorf(codes.InvalidArgument, "too many ips; at most %d may be looked up per request", mm.MaxBatchSize)
+	}
+
+	// the call has already been charged for its first ip
+	if err := s.server.chargeGrpc(ctx, len(req.Ips)-1); err != nil {
+		return nil, err
+	}
+
+	results := make([]*rpc.LookupResponse, 0, len(req.Ips))
+	for _, ipText := range req.Ips {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		results = append(results, s.lookup(ctx, ipText))
+	}
+	return &rpc.BatchLookupResponse{Results: results}, nil
+}
+
+func (s *GrpcService) StreamLookup(stream rpc.GeoIP_StreamLookupServer) error {
+	for {
+		req, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err = s.server.chargeGrpc(stream.Context(), 1); err != nil {
+			return err
+		}
+
+		if err = stream.Send(s.lookup(stream.Context(), req.Ip)); err != nil {
+			return err
+		}
+	}
+}
+
+// grpcApiKey extracts the api key from either the authorization (as a
+// bearer token) or the x-api-key metadata.
+func grpcApiKey(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+
+	for _, auth := range md.Get("authorization") {
+		if fields := strings.Fields(auth); len(fields) == 2 && strings.EqualFold(fields[0], "Bearer") {
+			return fields[1]
+		}
+	}
+
+	if values := md.Get("x-api-key"); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// limitGrpc charges a gRPC call cost tokens of the server's rate limit.
+func (c *ServerCommand) limitGrpc(ctx context.Context, cost int) error {
+	if c.rateLimiter == nil || cost <= 0 {
+		return nil
+	}
+
+	var clientAddr string
+	if p, ok := peer.FromContext(ctx); ok {
+		clientAddr, _, _ = net.SplitHostPort(p.Addr.String())
+	}
+
+	if !c.rateLimiter.TakeN(c.rateLimitKey(clientAddr, grpcApiKey(ctx)), cost).Allowed {
+		return status.Error(codes.ResourceExhausted, "rate limit exceeded")
+	}
+	return nil
+}
+
+// authorizeGrpc applies the api key checks to a gRPC call, using the full
+// method name as the route, charging the key cost tokens.
+func (c *ServerCommand) authorizeGrpc(ctx context.Context, method string, cost int) error {
+	if c.apiKeys == nil {
+		return nil
+	}
+
+	key := grpcApiKey(ctx)
+	if key == "" {
+		return status.Error(codes.Unauthenticated, "missing api key")
+	}
+
+	_, _, err := c.apiKeys.AuthorizeN(key, method, cost)
+	return apiKeyGrpcError(err)
+}
+
+// chargeGrpc charges a gRPC call, already authorized, cost more tokens of
+// both the server's rate limit and its api key's.
+func (c *ServerCommand) chargeGrpc(ctx context.Context, cost int) error {
+	if err := c.limitGrpc(ctx, cost); err != nil {
+		return err
+	}
+	if c.apiKeys == nil || cost <= 0 {
+		return nil
+	}
+	_, err := c.apiKeys.ChargeN(grpcApiKey(ctx), cost)
+	return apiKeyGrpcError(err)
+}
+
+// apiKeyGrpcError returns the status of the api key error err, if any.
+func apiKeyGrpcError(err error) error {
+	switch err {
+	case nil:
+		return nil
+	case mm.ErrApiKeyUnknown:
+		return status.Error(codes.Unauthenticated, err.Error())
+	case mm.ErrApiKeyRouteDenied:
+		return status.Error(codes.PermissionDenied, err.Error())
+	default:
+		return status.Error(codes.ResourceExhausted, err.Error())
+	}
+}
+
+// guardGrpc rate limits and authorizes calls to the GeoIP service, leaving
+// the health and reflection services open. Opening a stream costs nothing;
+// its messages are charged, to both limits, as they arrive.
+func (c *ServerCommand) guardGrpc(ctx context.Context, method string, cost int) error {
+	if !strings.HasPrefix(method, "/maxmind.geoip.GeoIP/") {
+		return nil
+	}
+	if err := c.limitGrpc(ctx, cost); err != nil {
+		return err
+	}
+	return c.authorizeGrpc(ctx, method, cost)
+}
+
+func (c *ServerCommand) unaryAuthInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if err := c.guardGrpc(ctx, info.FullMethod, 1); err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (c *ServerCommand) streamAuthInterceptor(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if err := c.guardGrpc(stream.Context(), info.FullMethod, 0); err != nil {
+		return err
+	}
+	return handler(srv, stream)
+}
+
+// newGrpcServer returns a gRPC server of the GeoIP, health and reflection
+// services, and its health service.
+func (c *ServerCommand) newGrpcServer() (*grpc.Server, *health.Server) {
+	server := grpc.NewServer(
+		grpc.UnaryInterceptor(c.unaryAuthInterceptor),
+		grpc.StreamInterceptor(c.streamAuthInterceptor),
+	)
+	rpc.RegisterGeoIPServer(server, &GrpcService{server: c})
+
+	healthServer := health.NewServer()
+	healthServer.SetServingStatus("", healthpb.HealthCheckResponse_SERVING)
+	healthServer.SetServingStatus("maxmind.geoip.GeoIP", healthpb.HealthCheckResponse_SERVING)
+	healthpb.RegisterHealthServer(server, healthServer)
+
+	reflection.Register(server)
+	return server, healthServer
+}
+
+func (c *ServerCommand) startGrpcService() (*grpc.Server, *health.Server, error) {
+	address := fmt.Sprintf("%s:%d", c.Config.Ip, c.Config.GrpcPort)
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	server, healthServer := c.newGrpcServer()
+
+	c.Ui.Infof("Listening for gRPC on %s ...\n", address)
+	go func() {
+		if err := server.Serve(listener); err != nil {
+			c.Ui.Errorf("grpc server exited: %s\n", err)
+		}
+	}()
+
+	return server, healthServer, nil
+}
+
+// grpcStopTimeout is how long calls in progress, such as open streams, may
+// hold up stopping the gRPC server.
+const grpcStopTimeout = 5 * time.Second
+
+// stopGrpcService reports the services as not serving, and stops the server
+// once the calls in progress end, or closes them after timeout.
+func stopGrpcService(server *grpc.Server, healthServer *health.Server, timeout time.Duration) {
+	healthServer.Shutdown()
+
+	stopped := make(chan struct{})
+	go func() {
+		server.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-time.After(timeout):
+		server.Stop()
+		<-stopped
+	}
+}
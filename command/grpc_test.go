@@ -0,0 +1,275 @@
+package command
+
+import (
+	"context"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rabbitt/maxmind/mm"
+	"github.com/rabbitt/maxmind/rpc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func TestGrpcApiKey(t *testing.T) {
+	for _, test := range []struct {
+		md   metadata.MD
+		want string
+	}{
+		{nil, ""},
+		{metadata.Pairs("authorization", "Bearer secret"), "secret"},
+		{metadata.Pairs("authorization", "bearer secret"), "secret"},
+		{metadata.Pairs("x-api-key", "secret"), "secret"},
+		{metadata.Pairs("authorization", "Bearer secret", "x-api-key", "other"), "secret"},
+		{metadata.Pairs("authorization", "Basic c2VjcmV0", "x-api-key", "other"), "other"},
+		{metadata.Pairs("authorization", "Bearer"), ""},
+		{metadata.Pairs("authorization", "Bearer one two"), ""},
+	} {
+		ctx := context.Background()
+		if test.md != nil {
+			ctx = metadata.NewIncomingContext(ctx, test.md)
+		}
+		if got := grpcApiKey(ctx); got != test.want {
+			t.Errorf("grpcApiKey(%v) = %q; want %q", test.md, got, test.want)
+		}
+	}
+}
+
+// grpcTestClient serves c's gRPC services over an in-memory connection,
+// returning a client of the GeoIP service along with the server.
+func grpcTestClient(t *testing.T, c *ServerCommand) (rpc.GeoIPClient, *grpc.ClientConn, *grpc.Server, *health.Server) {
+	listener := bufconn.Listen(1 << 20)
+	server, healthServer := c.newGrpcServer()
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return rpc.NewGeoIPClient(conn), conn, server, healthServer
+}
+
+func withApiKey(key string) context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), "x-api-key", key)
+}
+
+func TestGrpcLookup(t *testing.T) {
+	c, _ := testServer(t)
+	client, _, _, _ := grpcTestClient(t, c)
+
+	response, err := client.Lookup(context.Background(), &rpc.LookupRequest{Ip: "81.2.69.142"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.Status != "success" || response.Data.GetCountry().GetIsoCode() != "GB" {
+		t.Errorf("Lookup(81.2.69.142) = %v", response)
+	}
+
+	response, err = client.Lookup(context.Background(), &rpc.LookupRequest{Ip: "bogus"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.Status != "error" || response.Data != nil {
+		t.Errorf("Lookup(bogus) = %v", response)
+	}
+}
+
+func TestGrpcBatchLookup(t *testing.T) {
+	c, _ := testServer(t)
+	client, _, _, _ := grpcTestClient(t, c)
+
+	response, err := client.BatchLookup(context.Background(), &rpc.BatchLookupRequest{Ips: []string{"81.2.69.142", "bogus"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(response.Results) != 2 || response.Results[0].Status != "success" || response.Results[1].Status != "error" {
+		t.Errorf("BatchLookup = %v", response)
+	}
+
+	ips := make([]string, mm.MaxBatchSize+1)
+	for idx := range ips {
+		ips[idx] = "81.2.69.142"
+	}
+	if _, err = client.BatchLookup(context.Background(), &rpc.BatchLookupRequest{Ips: ips}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("BatchLookup of %d ips = %v; want %s", len(ips), err, codes.InvalidArgument)
+	}
+}
+
+func TestGrpcStreamLookup(t *testing.T) {
+	c, _ := testServer(t)
+	client, _, _, _ := grpcTestClient(t, c)
+
+	stream, err := client.StreamLookup(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, ip := range []string{"81.2.69.142", "bogus", "81.2.69.160"} {
+		if err = stream.Send(&rpc.LookupRequest{Ip: ip}); err != nil {
+			t.Fatal(err)
+		}
+		response, err := stream.Recv()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if response.Ip != ip {
+			t.Errorf("StreamLookup answered %q for %q", response.Ip, ip)
+		}
+	}
+
+	if err = stream.CloseSend(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = stream.Recv(); err != io.EOF {
+		t.Errorf("Recv after CloseSend = %v; want EOF", err)
+	}
+}
+
+func TestGrpcApiKeys(t *testing.T) {
+	c, _ := testServer(t)
+	keysFile := filepath.Join(t.TempDir(), "keys.json")
+	keys := `{"keys": [{"key": "secret", "rate": 0.001, "burst": 3, "routes": ["/maxmind.geoip.GeoIP/Lookup", "/maxmind.geoip.GeoIP/StreamLookup"]}]}`
+	if err := os.WriteFile(keysFile, []byte(keys), 0600); err != nil {
+		t.Fatal(err)
+	}
+	var err error
+	if c.apiKeys, err = mm.LoadApiKeys(mm.NewPathname(keysFile)); err != nil {
+		t.Fatal(err)
+	}
+	client, conn, _, _ := grpcTestClient(t, c)
+	request := &rpc.LookupRequest{Ip: "81.2.69.142"}
+
+	for idx, test := range []struct {
+		ctx  context.Context
+		call func(ctx context.Context) error
+		want codes.Code
+	}{
+		{context.Background(), func(ctx context.Context) error { _, err := client.Lookup(ctx, request); return err }, codes.Unauthenticated},
+		{withApiKey("other"), func(ctx context.Context) error { _, err := client.Lookup(ctx, request); return err }, codes.Unauthenticated},
+		{withApiKey("secret"), func(ctx context.Context) error {
+			_, err := client.BatchLookup(ctx, &rpc.BatchLookupRequest{Ips: []string{request.Ip}})
+			return err
+		}, codes.PermissionDenied},
+		{withApiKey("secret"), func(ctx context.Context) error { _, err := client.Lookup(ctx, request); return err }, codes.OK},
+		{withApiKey("secret"), func(ctx context.Context) error {
+			// each message of a stream is charged, exhausting the key
+			stream, err := client.StreamLookup(ctx)
+			if err != nil {
+				return err
+			}
+			for {
+				if err = stream.Send(request); err != nil {
+					break
+				}
+				if _, err = stream.Recv(); err != nil {
+					return err
+				}
+			}
+			_, err = stream.Recv()
+			return err
+		}, codes.ResourceExhausted},
+		{withApiKey("secret"), func(ctx context.Context) error { _, err := client.Lookup(ctx, request); return err }, codes.ResourceExhausted},
+	} {
+		if err := test.call(test.ctx); status.Code(err) != test.want {
+			t.Errorf("%d: call = %v; want %s", idx, err, test.want)
+		}
+	}
+
+	// the health service needs no api key
+	response, err := healthpb.NewHealthClient(conn).Check(context.Background(), &healthpb.HealthCheckRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.Status != healthpb.HealthCheckResponse_SERVING {
+		t.Errorf("health status = %s", response.Status)
+	}
+}
+
+func TestGrpcRateLimit(t *testing.T) {
+	c, _ := testServer(t)
+	c.rateLimiter = mm.NewRateLimiter(0.001, 3)
+	client, _, _, _ := grpcTestClient(t, c)
+
+	request := &rpc.LookupRequest{Ip: "81.2.69.142"}
+	lookup := func() error { _, err := client.Lookup(context.Background(), request); return err }
+	batch := func() error {
+		// a batch is charged an ip at a time, on top of the call
+		ips := &rpc.BatchLookupRequest{Ips: []string{request.Ip, request.Ip, request.Ip}}
+		_, err := client.BatchLookup(context.Background(), ips)
+		return err
+	}
+
+	for idx, test := range []struct {
+		call func() error
+		want codes.Code
+	}{
+		{lookup, codes.OK},
+		{batch, codes.ResourceExhausted},
+		{lookup, codes.OK},
+		{lookup, codes.ResourceExhausted},
+	} {
+		if err := test.call(); status.Code(err) != test.want {
+			t.Errorf("%d: call = %v; want %s", idx, err, test.want)
+		}
+	}
+}
+
+func TestGrpcHealth(t *testing.T) {
+	c, _ := testServer(t)
+	client, conn, server, healthServer := grpcTestClient(t, c)
+
+	for _, service := range []string{"", "maxmind.geoip.GeoIP"} {
+		response, err := healthpb.NewHealthClient(conn).Check(context.Background(), &healthpb.HealthCheckRequest{Service: service})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if response.Status != healthpb.HealthCheckResponse_SERVING {
+			t.Errorf("health status of %q = %s", service, response.Status)
+		}
+	}
+
+	// an idle stream only holds up stopping until the timeout
+	stream, err := client.StreamLookup(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = stream.Send(&rpc.LookupRequest{Ip: "81.2.69.142"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = stream.Recv(); err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+	stopGrpcService(server, healthServer, 100*time.Millisecond)
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("stopping took %s", elapsed)
+	}
+	if _, err = stream.Recv(); err == nil {
+		t.Error("stream still open after stopping")
+	}
+
+	response, err := healthServer.Check(context.Background(), &healthpb.HealthCheckRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.Status != healthpb.HealthCheckResponse_NOT_SERVING {
+		t.Errorf("health status after stopping = %s", response.Status)
+	}
+}
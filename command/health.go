@@ -0,0 +1,88 @@
+package command
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/pquerna/ffjson/ffjson"
+	"github.com/rabbitt/maxmind/mm"
+)
+
+// livenessHandler answers /healthz, reporting only that the process is up
+// and serving requests.
+func (c *ServerCommand) livenessHandler(writer http.ResponseWriter, request *http.Request, _ httprouter.Params) {
+	writer.Header().Set("Content-Type", "text/plain")
+	writer.Header().Set("Cache-Control", "no-cache")
+
+	if request.Method == "GET" {
+		fmt.Fprint(writer, "ok")
+	}
+}
+
+func (c *ServerCommand) checkCanary() error {
+	if c.Config.CanaryIp == "" {
+		return nil
+	}
+
+	record, err := mm.LookupText(c.provider, c.Config.CanaryIp)
+	if err != nil {
+		return fmt.Errorf("lookup of canary %s failed: %s", c.Config.CanaryIp, err)
+	}
+
+	if c.Config.CanaryCountry != "" && record.Country.IsoCode != c.Config.CanaryCountry {
+		return fmt.Errorf("canary %s resolved to country '%s'; expected '%s'",
+			c.Config.CanaryIp, record.Country.IsoCode, c.Config.CanaryCountry)
+	}
+
+	return nil
+}
+
+func (c *ServerCommand) checkDatabaseAge() error {
+	if c.Config.DatabaseMaxAge <= 0 {
+		return nil
+	}
+
+	built := c.provider.Metadata().BuildTime
+	maxAge := time.Duration(c.Config.DatabaseMaxAge * float64(time.Second))
+	if age := time.Since(built); age > maxAge {
+		return fmt.Errorf("database built %s is %s old; maximum age is %s",
+			built.UTC().Format(time.RFC3339), age.Truncate(time.Second), maxAge)
+	}
+
+	return nil
+}
+
+// readinessHandler answers /readyz, self-testing the database and
+// explaining any failure in the response body.
+func (c *ServerCommand) readinessHandler(writer http.ResponseWriter, request *http.Request, _ httprouter.Params) {
+	writer.Header().Set("Content-Type", "application/json")
+	writer.Header().Set("Cache-Control", "no-cache")
+
+	response := mm.NewHealthResponse()
+
+	if c.provider == nil {
+		response.Check("database", errors.New("database not loaded"))
+	} else {
+		response.Check("database", nil)
+		response.Check("canary", c.checkCanary())
+		response.Check("database_age", c.checkDatabaseAge())
+	}
+
+	j, err := ffjson.Marshal(response)
+	if err != nil {
+		c.Ui.Error(err)
+		writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if !response.Ok() {
+		writer.WriteHeader(http.StatusServiceUnavailable)
+	}
+
+	if request.Method == "GET" {
+		writer.Write(j)
+	}
+}
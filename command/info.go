@@ -0,0 +1,38 @@
+package command
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/pquerna/ffjson/ffjson"
+	"github.com/rabbitt/maxmind/mm"
+)
+
+// infoHandler answers /info, describing the server and the database it
+// answers from.
+func (c *ServerCommand) infoHandler(writer http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	info := &mm.ServerInfo{
+		Started:  c.serverStart.UTC(),
+		Uptime:   time.Since(c.serverStart).Seconds(),
+		Database: c.provider.Metadata(),
+	}
+
+	if c.layers != nil {
+		info.Overlays = map[string]*mm.DatabaseInfo{}
+		for _, layer := range c.layers.Layers[:len(c.layers.Layers)-1] {
+			info.Overlays[layer.Name] = layer.Provider.Metadata()
+		}
+	}
+
+	j, err := ffjson.Marshal(info)
+	if err != nil {
+		c.Ui.Error(err)
+		writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	writer.Header().Set("Content-Type", "application/json")
+	writer.Header().Set("Cache-Control", "no-cache")
+	writer.Write(j)
+}
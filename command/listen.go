@@ -0,0 +1,120 @@
+package command
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// ListFlag is a flag.Value collecting every occurrence of a repeatable flag.
+type ListFlag []string
+
+func (l *ListFlag) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *ListFlag) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// parseListenAddress splits a listen address into its network and address,
+// accepting tcp://host:port, unix:///path/to/socket, or a bare host:port.
+func parseListenAddress(address string) (string, string, error) {
+	if !strings.Contains(address, "://") {
+		return "tcp", address, nil
+	}
+
+	u, err := url.Parse(address)
+	if err != nil {
+		return "", "", err
+	}
+
+	switch u.Scheme {
+	case "tcp", "tcp4", "tcp6":
+		return u.Scheme, u.Host, nil
+	case "unix":
+		if u.Path == "" {
+			return "", "", fmt.Errorf("missing socket path in listen address `%s`", address)
+		}
+		return u.Scheme, u.Path, nil
+	}
+
+	return "", "", fmt.Errorf("unsupported listen address `%s`; expected tcp:// or unix://", address)
+}
+
+// removeStaleSocket removes a socket file left behind by a previous process,
+// refusing to touch it if something is still accepting connections on it.
+func removeStaleSocket(path string) error {
+	info, err := os.Lstat(path)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	if info.Mode()&os.ModeSocket == 0 {
+		return fmt.Errorf("%s exists and is not a socket", path)
+	}
+
+	if conn, err := net.DialTimeout("unix", path, time.Second); err == nil {
+		conn.Close()
+		return fmt.Errorf("%s is in use by another process", path)
+	}
+
+	return os.Remove(path)
+}
+
+// listen opens a listener for address, applying mode to unix sockets.
+func listen(address string, mode os.FileMode) (net.Listener, error) {
+	network, addr, err := parseListenAddress(address)
+	if err != nil {
+		return nil, err
+	}
+
+	if network != "unix" {
+		return net.Listen(network, addr)
+	}
+
+	if err = removeStaleSocket(addr); err != nil {
+		return nil, err
+	}
+
+	listener, err := net.Listen(network, addr)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = os.Chmod(addr, mode); err != nil {
+		listener.Close()
+		return nil, err
+	}
+
+	return listener, nil
+}
+
+// listenAll opens a listener for every address, closing any already opened
+// if one of them fails.
+func listenAll(addresses []string, mode os.FileMode) ([]net.Listener, error) {
+	if len(addresses) == 0 {
+		return nil, errors.New("no listen addresses configured")
+	}
+
+	listeners := make([]net.Listener, 0, len(addresses))
+	for _, address := range addresses {
+		listener, err := listen(address, mode)
+		if err != nil {
+			for _, l := range listeners {
+				l.Close()
+			}
+			return nil, err
+		}
+		listeners = append(listeners, listener)
+	}
+
+	return listeners, nil
+}
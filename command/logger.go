@@ -0,0 +1,231 @@
+package command
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pquerna/ffjson/ffjson"
+)
+
+type LogLevel int
+
+const (
+	LevelDebug LogLevel = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+var logLevelNames = map[LogLevel]string{
+	LevelDebug: "debug",
+	LevelInfo:  "info",
+	LevelWarn:  "warn",
+	LevelError: "error",
+}
+
+func (l LogLevel) String() string {
+	return logLevelNames[l]
+}
+
+func ParseLogLevel(level string) (LogLevel, error) {
+	for l, name := range logLevelNames {
+		if strings.EqualFold(level, name) {
+			return l, nil
+		}
+	}
+	return LevelInfo, fmt.Errorf("invalid log level '%s'; expected one of 'debug', 'info', 'warn', or 'error'", level)
+}
+
+var LogFormats = map[string]bool{
+	"text":   true,
+	"json":   true,
+	"logfmt": true,
+}
+
+// FieldUi is implemented by Uis that can attach key/value fields to the
+// messages they render.
+type FieldUi interface {
+	Ui
+	With(keyValues ...interface{}) Ui
+}
+
+// withFields attaches keyValues to messages rendered through ui, if ui
+// supports fields, and otherwise returns ui unchanged.
+func withFields(ui Ui, keyValues ...interface{}) Ui {
+	if fieldUi, ok := ui.(FieldUi); ok {
+		return fieldUi.With(keyValues...)
+	}
+	return ui
+}
+
+// StructuredUi is an implementation of Ui that renders log messages, with
+// their level, timestamp and any fields, as text, JSON or logfmt lines, and
+// drops those below its level. Output and Outputf are written as is. This
+// UI is threadsafe.
+type StructuredUi struct {
+	Writer      io.Writer
+	ErrorWriter io.Writer
+	Format      string
+	Level       LogLevel
+
+	fields []interface{}
+	locker *sync.Mutex
+}
+
+// structuredUiLock serializes the writes of StructuredUis that were not
+// created by NewStructuredUi, and so have no lock of their own.
+var structuredUiLock sync.Mutex
+
+func NewStructuredUi(writer io.Writer, errorWriter io.Writer, format string, level LogLevel) *StructuredUi {
+	return &StructuredUi{
+		Writer:      writer,
+		ErrorWriter: errorWriter,
+		Format:      format,
+		Level:       level,
+		locker:      &sync.Mutex{},
+	}
+}
+
+// With returns a Ui rendering keyValues, alternating keys and values,
+// alongside every message.
+func (u *StructuredUi) With(keyValues ...interface{}) Ui {
+	fields := make([]interface{}, 0, len(u.fields)+len(keyValues))
+	fields = append(fields, u.fields...)
+	fields = append(fields, keyValues...)
+
+	return &StructuredUi{
+		Writer:      u.Writer,
+		ErrorWriter: u.ErrorWriter,
+		Format:      u.Format,
+		Level:       u.Level,
+		fields:      fields,
+		locker:      u.lock(),
+	}
+}
+
+// lock returns the mutex serializing the writes of u, and of the Uis
+// derived from it.
+func (u *StructuredUi) lock() *sync.Mutex {
+	if u.locker == nil {
+		return &structuredUiLock
+	}
+	return u.locker
+}
+
+func logfmtValue(value string) string {
+	if value == "" || strings.ContainsAny(value, " =\"\t\n") {
+		return strconv.Quote(value)
+	}
+	return value
+}
+
+func (u *StructuredUi) render(level LogLevel, message string) []byte {
+	message = strings.TrimRight(message, "\n")
+	var buf bytes.Buffer
+
+	switch u.Format {
+	case "json":
+		entry := map[string]interface{}{
+			"time":  time.Now().Format(time.RFC3339Nano),
+			"level": level.String(),
+			"msg":   message,
+		}
+		for idx := 0; idx+1 < len(u.fields); idx += 2 {
+			entry[fmt.Sprint(u.fields[idx])] = u.fields[idx+1]
+		}
+		j, err := ffjson.Marshal(entry)
+		if err != nil {
+			j, _ = ffjson.Marshal(map[string]string{"level": level.String(), "msg": message})
+		}
+		buf.Write(j)
+
+	case "logfmt":
+		fmt.Fprintf(&buf, "time=%s level=%s msg=%s",
+			time.Now().Format(time.RFC3339Nano), level, logfmtValue(message))
+		for idx := 0; idx+1 < len(u.fields); idx += 2 {
+			fmt.Fprintf(&buf, " %s=%s", u.fields[idx], logfmtValue(fmt.Sprint(u.fields[idx+1])))
+		}
+
+	default:
+		fmt.Fprintf(&buf, "%s: %s", strings.ToUpper(level.String()), message)
+		for idx := 0; idx+1 < len(u.fields); idx += 2 {
+			fmt.Fprintf(&buf, " %s=%s", u.fields[idx], logfmtValue(fmt.Sprint(u.fields[idx+1])))
+		}
+	}
+
+	buf.WriteByte('\n')
+	return buf.Bytes()
+}
+
+func (u *StructuredUi) log(level LogLevel, message string) {
+	if level < u.Level {
+		return
+	}
+
+	w := u.Writer
+	if level >= LevelWarn && u.ErrorWriter != nil {
+		w = u.ErrorWriter
+	}
+
+	line := u.render(level, message)
+
+	locker := u.lock()
+	locker.Lock()
+	defer locker.Unlock()
+
+	w.Write(line)
+}
+
+func (u *StructuredUi) Debug(v ...interface{}) {
+	u.log(LevelDebug, fmt.Sprint(v...))
+}
+
+func (u *StructuredUi) Debugf(format string, v ...interface{}) {
+	u.log(LevelDebug, fmt.Sprintf(format, v...))
+}
+
+func (u *StructuredUi) Error(v ...interface{}) {
+	u.log(LevelError, fmt.Sprint(v...))
+}
+
+func (u *StructuredUi) Errorf(format string, v ...interface{}) {
+	u.log(LevelError, fmt.Sprintf(format, v...))
+}
+
+func (u *StructuredUi) Info(v ...interface{}) {
+	u.log(LevelInfo, fmt.Sprint(v...))
+}
+
+func (u *StructuredUi) Infof(format string, v ...interface{}) {
+	u.log(LevelInfo, fmt.Sprintf(format, v...))
+}
+
+func (u *StructuredUi) Output(v ...interface{}) {
+	locker := u.lock()
+	locker.Lock()
+	defer locker.Unlock()
+
+	fmt.Fprint(u.Writer, v...)
+	fmt.Fprint(u.Writer, "\n")
+}
+
+func (u *StructuredUi) Outputf(format string, v ...interface{}) {
+	locker := u.lock()
+	locker.Lock()
+	defer locker.Unlock()
+
+	fmt.Fprintf(u.Writer, format, v...)
+}
+
+func (u *StructuredUi) Warn(v ...interface{}) {
+	u.log(LevelWarn, fmt.Sprint(v...))
+}
+
+func (u *StructuredUi) Warnf(format string, v ...interface{}) {
+	u.log(LevelWarn, fmt.Sprintf(format, v...))
+}
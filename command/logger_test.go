@@ -0,0 +1,119 @@
+package command
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStructuredUiZeroValue(t *testing.T) {
+	var output bytes.Buffer
+	ui := &StructuredUi{Writer: &output, Format: "logfmt"}
+
+	ui.Output("plain")
+	withFields(ui, "ip", "81.2.69.142").Warnf("looked up %s\n", "81.2.69.142")
+
+	lines := strings.Split(strings.TrimSpace(output.String()), "\n")
+	if len(lines) != 2 || lines[0] != "plain" ||
+		!strings.HasSuffix(lines[1], `level=warn msg="looked up 81.2.69.142" ip=81.2.69.142`) {
+		t.Errorf("unexpected output %q", output.String())
+	}
+}
+
+func TestParseLogLevel(t *testing.T) {
+	for _, test := range []struct {
+		level string
+		want  LogLevel
+		valid bool
+	}{
+		{"debug", LevelDebug, true},
+		{"INFO", LevelInfo, true},
+		{"Warn", LevelWarn, true},
+		{"error", LevelError, true},
+		{"warning", LevelInfo, false},
+		{"verbose", LevelInfo, false},
+		{"", LevelInfo, false},
+	} {
+		got, err := ParseLogLevel(test.level)
+		if got != test.want || (err == nil) != test.valid {
+			t.Errorf("ParseLogLevel(%q) = %s, %v; want %s", test.level, got, err, test.want)
+		}
+	}
+}
+
+func TestStructuredUiLevels(t *testing.T) {
+	for _, test := range []struct {
+		level          LogLevel
+		output, errors string
+	}{
+		{LevelDebug, "DEBUG: d\nINFO: i\n", "WARN: w\nERROR: e\n"},
+		{LevelInfo, "INFO: i\n", "WARN: w\nERROR: e\n"},
+		{LevelWarn, "", "WARN: w\nERROR: e\n"},
+		{LevelError, "", "ERROR: e\n"},
+	} {
+		var output, errors bytes.Buffer
+		ui := NewStructuredUi(&output, &errors, "text", test.level)
+		ui.Debug("d")
+		ui.Info("i")
+		ui.Warn("w")
+		ui.Error("e")
+
+		if output.String() != test.output || errors.String() != test.errors {
+			t.Errorf("at level %s, output = %q and errors = %q; want %q and %q",
+				test.level, output.String(), errors.String(), test.output, test.errors)
+		}
+	}
+}
+
+func TestStructuredUiFields(t *testing.T) {
+	fields := []interface{}{"ip", "81.2.69.142", "count", 3, "path", "/ip/81.2.69.142?lang=en", "agent", `curl "7.0"`, "empty", ""}
+
+	for _, test := range []struct {
+		format, want string
+	}{
+		{"text", `INFO: looked up 81.2.69.142 ip=81.2.69.142 count=3 path="/ip/81.2.69.142?lang=en" agent="curl \"7.0\"" empty=""`},
+		{"logfmt", `level=info msg="looked up 81.2.69.142" ip=81.2.69.142 count=3 path="/ip/81.2.69.142?lang=en" agent="curl \"7.0\"" empty=""`},
+	} {
+		var output bytes.Buffer
+		withFields(NewStructuredUi(&output, nil, test.format, LevelInfo), fields...).Infof("looked up %s\n", "81.2.69.142")
+
+		line := strings.TrimSuffix(output.String(), "\n")
+		if test.format == "logfmt" {
+			if !strings.HasPrefix(line, "time=") {
+				t.Errorf("%s: missing time in %q", test.format, line)
+			}
+			line = line[strings.Index(line, " ")+1:]
+		}
+		if line != test.want {
+			t.Errorf("%s: got\n%s\nwant\n%s", test.format, line, test.want)
+		}
+	}
+
+	var output bytes.Buffer
+	withFields(NewStructuredUi(&output, nil, "json", LevelInfo), fields...).Warn(`said "hi"`)
+
+	var entry map[string]interface{}
+	if err := json.Unmarshal(output.Bytes(), &entry); err != nil {
+		t.Fatalf("%s: %s", err, output.String())
+	}
+	if _, err := time.Parse(time.RFC3339Nano, fmt.Sprint(entry["time"])); err != nil {
+		t.Error(err)
+	}
+	delete(entry, "time")
+	want := map[string]interface{}{
+		"level": "warn",
+		"msg":   `said "hi"`,
+		"ip":    "81.2.69.142",
+		"count": float64(3),
+		"path":  "/ip/81.2.69.142?lang=en",
+		"agent": `curl "7.0"`,
+		"empty": "",
+	}
+	if !reflect.DeepEqual(entry, want) {
+		t.Errorf("json entry = %v; want %v", entry, want)
+	}
+}
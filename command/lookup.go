@@ -1,6 +1,7 @@
 package command
 
 import (
+	"errors"
 	"flag"
 	"fmt"
 	"os"
@@ -11,11 +12,16 @@ import (
 
 type LookupCommand struct {
 	Ui Ui
+
+	// provider, when set, answers lookups in place of the backend the
+	// options select
+	provider mm.Provider
 }
 
 var OutputTypes = map[string]bool{
-	"json":  true,
-	"table": true,
+	"json":    true,
+	"table":   true,
+	"geojson": true,
 }
 
 func (c *LookupCommand) outputAsJson(record *mm.GeoData) {
@@ -27,6 +33,24 @@ func (c *LookupCommand) outputAsJson(record *mm.GeoData) {
 	c.Ui.Output(string(j))
 }
 
+func (c *LookupCommand) outputAsGeoJson(features []*mm.Feature) {
+	var j []byte
+	var err error
+
+	// a single ip renders as a bare Feature, anything more as a collection
+	if len(features) == 1 {
+		j, err = ffjson.Marshal(features[0])
+	} else {
+		j, err = ffjson.Marshal(mm.NewFeatureCollection(features...))
+	}
+
+	if err != nil {
+		c.Ui.Error(err)
+		return
+	}
+	c.Ui.Output(string(j))
+}
+
 func (c *LookupCommand) outputAsTable(ipText string, record *mm.GeoData) {
 	c.Ui.Output("")
 	c.Ui.Outputf("[ %15s ]---------------------->\n", ipText)
@@ -77,70 +101,104 @@ func (c *LookupCommand) Help() string {
 Prints the details of the requested IPs, responding to requests for /ip/:ip, and /ping.
 
 Options:
-  -f, -database.file  <file>      Path to MaxMind Database
-                                  (default: %s)
-  -o, -output.type    <string>    Render mode (one of: json, or table)
+  -b, -database.backend <string>  What lookups are answered from; one of mmdb,
+                                  csv, or upstream (default: %s)
+  -f, -database.file  <file>      Path to MaxMind Database, or directory of
+                                  its CSV edition with -b csv (default: %s)
+  -u, -database.upstream <url>    URL of the maxmind server the upstream
+                                  backend answers from
+      -database.upstream.api_key <key>
+                                  API key sent to the upstream server
+  -o, -output.type    <string>    Render mode (one of: json, geojson, or table)
                                   requests. (default: %s)
-`, os.Args[0], DefaultDatabasePath, "table")
+`, os.Args[0], mm.BackendMmdb, DefaultDatabasePath, "table")
 }
 
 func (c *LookupCommand) Synopsis() string {
 	return "Lookup on or more IPs and exit"
 }
 
-func (c *LookupCommand) Run(args []string) int {
+// Execute looks up, and renders, each IP given in args.
+func (c *LookupCommand) Execute(args []string) error {
 	var dbPath *mm.Pathname
-	var database *mm.Database
 	var err error
 
 	var mainParse = flag.NewFlagSet("lookup", flag.ContinueOnError)
-	outType := mainParse.String("o", "table", "Output `type` for quick lookup; either 'json' or 'table'")
-	mainParse.StringVar(outType, "output.type", "table", "Output `type` for quick lookup; either 'json' or 'table'")
+	outType := mainParse.String("o", "table", "Output `type` for quick lookup; one of 'json', 'geojson', or 'table'")
+	mainParse.StringVar(outType, "output.type", "table", "Output `type` for quick lookup; one of 'json', 'geojson', or 'table'")
 	dbFile := mainParse.String("f", DefaultDatabasePath, "`path` to the database file that contains GeoIP information")
 	mainParse.StringVar(dbFile, "database.file", DefaultDatabasePath, "`path` to the database file that contains GeoIP information")
+	backend := mainParse.String("b", mm.BackendMmdb, "`backend` lookups are answered from; one of mmdb, csv, or upstream")
+	mainParse.StringVar(backend, "database.backend", mm.BackendMmdb, "`backend` lookups are answered from; one of mmdb, csv, or upstream")
+	upstream := mainParse.String("u", "", "`url` of the maxmind server the upstream backend answers from")
+	mainParse.StringVar(upstream, "database.upstream", "", "`url` of the maxmind server the upstream backend answers from")
+	upstreamKey := mainParse.String("database.upstream.api_key", "", "api `key` sent to the upstream server")
 
 	mainParse.Usage = func() {
 		c.Ui.Output(c.Help())
 		mainParse.PrintDefaults()
 	}
-	mainParse.Parse(args)
-
-	if outType != nil && OutputTypes[*outType] == false {
-		c.Ui.Fatalf("Invalid output type '%s'; expected one of 'json', or 'table'\n", *outType)
+	if err = mainParse.Parse(args); err != nil {
+		return usageError(err)
 	}
 
-	if *dbFile == "" {
-		c.Ui.Fatal("missing required path to MasterMind DB file")
-	} else {
-		dbPath, err = mm.NewPathname(*dbFile).RealPath()
-		if err != nil {
-			c.Ui.Fatal(err)
-		}
+	if outType != nil && OutputTypes[*outType] == false {
+		return usageError(fmt.Errorf("invalid output type '%s'; expected one of 'json', 'geojson', or 'table'", *outType))
 	}
 
 	if len(mainParse.Args()) <= 0 {
-		c.Ui.Fatal("no ips to look up")
+		return usageError(errors.New("no ips to look up"))
 	}
 
-	if database, err = mm.GetDatabase(dbPath.Path()); err == nil {
-		defer mm.CloseDatabases()
-	} else {
-		c.Ui.Fatal(err)
+	provider := c.provider
+	if provider == nil {
+		config := mm.NewConfiguration()
+		config.Backend, config.Upstream, config.UpstreamKey = *backend, *upstream, *upstreamKey
+
+		if !mm.Backends[config.Backend] {
+			return usageError(fmt.Errorf("invalid backend '%s'; expected one of 'mmdb', 'csv', or 'upstream'", config.Backend))
+		}
+		if config.Backend == mm.BackendUpstream {
+			if config.Upstream == "" {
+				return usageError(errors.New("missing required url of the upstream server"))
+			}
+		} else {
+			if *dbFile == "" {
+				return usageError(errors.New("missing required path to MasterMind DB file"))
+			}
+			if dbPath, err = mm.NewPathname(*dbFile).RealPath(); err != nil {
+				return err
+			}
+			config.DbPath = dbPath
+		}
+
+		if provider, err = openProvider(config); err != nil {
+			return err
+		}
+		defer provider.Close()
 	}
 
+	var features []*mm.Feature
+
 	for _, ip := range mainParse.Args() {
-		record, err := database.Lookup(ip)
+		record, err := mm.LookupText(provider, ip)
 		if err != nil {
-			c.Ui.Fatal(err)
+			return err
 		}
 
 		switch *outType {
 		case "json":
 			c.outputAsJson(record)
+		case "geojson":
+			features = append(features, mm.NewFeature(ip, record))
 		case "table":
 			c.outputAsTable(ip, record)
 		}
 	}
 
-	return 0
+	if *outType == "geojson" {
+		c.outputAsGeoJson(features)
+	}
+
+	return nil
 }
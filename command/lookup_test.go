@@ -0,0 +1,140 @@
+package command
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rabbitt/maxmind/mm"
+)
+
+func testLookup() (*LookupCommand, *bytes.Buffer, *bytes.Buffer) {
+	var output, errorOutput bytes.Buffer
+	return &LookupCommand{Ui: &BaseUi{Writer: &output, ErrorWriter: &errorOutput}}, &output, &errorOutput
+}
+
+func TestLookupCommandJson(t *testing.T) {
+	path := buildFixture(t, "GeoIP2-City-Test.json")
+	c, output, _ := testLookup()
+
+	if err := c.Execute([]string{"-f", path, "-o", "json", "81.2.69.142", "2001:218::1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(output.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("unexpected output %q", output)
+	}
+
+	var record mm.GeoData
+	if err := json.Unmarshal([]byte(lines[0]), &record); err != nil {
+		t.Fatal(err)
+	}
+	if record.City.Name != "London" {
+		t.Errorf("unexpected record %s", lines[0])
+	}
+	if err := json.Unmarshal([]byte(lines[1]), &record); err != nil {
+		t.Fatal(err)
+	}
+	if record.Country.IsoCode != "JP" {
+		t.Errorf("unexpected record %s", lines[1])
+	}
+}
+
+func TestLookupCommandGeoJson(t *testing.T) {
+	path := buildFixture(t, "GeoIP2-City-Test.json")
+	c, output, _ := testLookup()
+
+	if err := c.Execute([]string{"-database.file", path, "-output.type", "geojson", "81.2.69.142", "175.16.199.1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	var collection struct {
+		Type     string        `json:"type"`
+		Features []interface{} `json:"features"`
+	}
+	if err := json.Unmarshal(output.Bytes(), &collection); err != nil {
+		t.Fatal(err)
+	}
+	if collection.Type != "FeatureCollection" || len(collection.Features) != 2 {
+		t.Errorf("unexpected output %s", output)
+	}
+}
+
+func TestLookupCommandTable(t *testing.T) {
+	path := buildFixture(t, "GeoIP2-City-Test.json")
+	c, output, errorOutput := testLookup()
+
+	if err := c.Execute([]string{"-f", path, "89.160.20.112", "10.0.0.1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{"Sweden (SE)", "Linköping Municipality (LI)", "Postal:         [58211]", "Europe/Stockholm"} {
+		if !strings.Contains(output.String(), want) {
+			t.Errorf("output lacks %q:\n%s", want, output)
+		}
+	}
+	if !strings.Contains(errorOutput.String(), "Unable to find any valid data") {
+		t.Errorf("unexpected error output %q", errorOutput)
+	}
+}
+
+func TestLookupCommandErrors(t *testing.T) {
+	path := buildFixture(t, "GeoIP2-City-Test.json")
+	missing := filepath.Join(t.TempDir(), "missing.mmdb")
+
+	var usage *UsageError
+	var pathErr *mm.PathError
+	var invalid *mm.InvalidIpError
+
+	for _, test := range []struct {
+		args   []string
+		target interface{}
+	}{
+		{[]string{"-f", path}, &usage},
+		{[]string{"-f", path, "-o", "xml", "81.2.69.142"}, &usage},
+		{[]string{"-f", missing, "81.2.69.142"}, &pathErr},
+		{[]string{"-f", path, "not-an-ip"}, &invalid},
+	} {
+		c, _, _ := testLookup()
+		if err := c.Execute(test.args); !errors.As(err, test.target) {
+			t.Errorf("Execute(%v) = %v; want a %T", test.args, err, test.target)
+		}
+	}
+}
+
+func TestLookupCommandBackends(t *testing.T) {
+	memory := mm.NewMemoryProvider("Internal")
+	memory.Insert("10.1.0.0/16", &mm.GeoData{City: mm.City{Name: "London"}})
+
+	c, output, _ := testLookup()
+	c.provider = memory
+	if err := c.Execute([]string{"-o", "json", "10.1.2.3"}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(output.String(), `"London"`) {
+		t.Errorf("unexpected output %s", output)
+	}
+
+	c, output, _ = testLookup()
+	if err := c.Execute([]string{"-b", "csv", "-f", "../testdata/GeoLite2-City-CSV_20181016", "-o", "json", "2001:218::1"}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(output.String(), `"JP"`) {
+		t.Errorf("unexpected output %s", output)
+	}
+
+	var usage *UsageError
+	for _, args := range [][]string{
+		{"-b", "sqlite", "10.1.2.3"},
+		{"-b", "upstream", "10.1.2.3"},
+	} {
+		c, _, _ = testLookup()
+		if err := c.Execute(args); !errors.As(err, &usage) {
+			t.Errorf("Execute(%v) = %v; want a *UsageError", args, err)
+		}
+	}
+}
@@ -0,0 +1,381 @@
+package command
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/rabbitt/maxmind/mm"
+)
+
+type NetworksCommand struct {
+	Ui Ui
+}
+
+var NetworkFormats = map[string]bool{
+	"cidr":     true,
+	"nft":      true,
+	"iptables": true,
+	"json":     true,
+}
+
+// parseAsns parses autonomous system numbers, with or without their AS
+// prefix, from repeated and comma separated flag values.
+func parseAsns(values []string) (map[uint]bool, error) {
+	asns := map[uint]bool{}
+	for _, asn := range splitList(values) {
+		number, err := strconv.ParseUint(strings.TrimPrefix(strings.ToUpper(asn), "AS"), 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid autonomous system number '%s'", asn)
+		}
+		asns[uint(number)] = true
+	}
+	return asns, nil
+}
+
+// networkFilter selects networks by country, city, or autonomous system;
+// a network is selected when it matches each kind of criteria given.
+type networkFilter struct {
+	countries map[string]bool
+	cities    map[string]bool
+	asns      map[uint]bool
+
+	// the database the autonomous system of each network is looked up in
+	asnDatabase *mm.Database
+}
+
+func newNetworkFilter(countries []string, cities []string, asns []string) (*networkFilter, error) {
+	f := &networkFilter{countries: map[string]bool{}, cities: map[string]bool{}}
+	for _, country := range splitList(countries) {
+		f.countries[strings.ToUpper(country)] = true
+	}
+	for _, city := range cities {
+		if city = strings.TrimSpace(city); city != "" {
+			f.cities[strings.ToLower(city)] = true
+		}
+	}
+
+	var err error
+	if f.asns, err = parseAsns(asns); err != nil {
+		return nil, err
+	}
+	if len(f.countries) == 0 && len(f.cities) == 0 && len(f.asns) == 0 {
+		return nil, errors.New("missing a country, city, or autonomous system to list the networks of")
+	}
+	return f, nil
+}
+
+// match reports whether the network is selected; its autonomous system is
+// looked up at its first address, so it must lie within a single network
+// of the ASN database, as mm.SplitNetworks makes it.
+func (f *networkFilter) match(network *net.IPNet, data *mm.GeoData) (bool, error) {
+	if len(f.countries) > 0 && !f.countries[data.Country.IsoCode] {
+		return false, nil
+	}
+	if len(f.cities) > 0 && !f.cities[strings.ToLower(data.City.Name)] {
+		return false, nil
+	}
+	if len(f.asns) > 0 {
+		asn, _, err := f.asnDatabase.LookupAsn(network.IP.String())
+		if err != nil || !f.asns[asn] {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// networkWriter renders networks in one of the NetworkFormats.
+type networkWriter struct {
+	out    *bufio.Writer
+	format string
+
+	// the nftables table and set, or iptables chain, the networks are added
+	// to, and the target iptables rules jump to
+	table string
+	set   string
+	jump  string
+
+	count int
+}
+
+func newNetworkWriter(out io.Writer, format string, table string, set string, jump string) *networkWriter {
+	return &networkWriter{out: bufio.NewWriterSize(out, 64*1024), format: format, table: table, set: set, jump: jump}
+}
+
+func (w *networkWriter) Write(network *net.IPNet) error {
+	family := "v4"
+	if network.IP.To4() == nil {
+		family = "v6"
+	}
+
+	var err error
+	switch w.format {
+	case "nft":
+		_, err = fmt.Fprintf(w.out, "add element %s %s_%s { %s }\n", w.table, w.set, family, network)
+	case "iptables":
+		command := "iptables"
+		if family == "v6" {
+			command = "ip6tables"
+		}
+		_, err = fmt.Fprintf(w.out, "%s -A %s -s %s -j %s\n", command, w.set, network, w.jump)
+	case "json":
+		if w.count == 0 {
+			w.out.WriteString("[\n")
+		} else {
+			w.out.WriteString(",\n")
+		}
+		_, err = fmt.Fprintf(w.out, "%q", network)
+	default:
+		_, err = fmt.Fprintf(w.out, "%s\n", network)
+	}
+	w.count++
+	return err
+}
+
+// Close ends the output, and flushes it.
+func (w *networkWriter) Close() error {
+	if w.format == "json" {
+		if w.count == 0 {
+			w.out.WriteString("[")
+		}
+		w.out.WriteString("\n]\n")
+	}
+	return w.out.Flush()
+}
+
+// networksCost is what a listing of networks is charged, in lookups, as
+// it walks the whole database.
+const networksCost = 100
+
+// bodyWriter notes whether the body of a response, and so its status, has
+// been sent.
+type bodyWriter struct {
+	http.ResponseWriter
+	written bool
+}
+
+func (w *bodyWriter) Write(p []byte) (int, error) {
+	w.written = true
+	return w.ResponseWriter.Write(p)
+}
+
+// networksHandler answers GET /networks, listing the collapsed networks
+// of the database in the countries, cities, or autonomous systems of the
+// query, as the format of the query, JSON by default.
+//
+// The listing is buffered, so that a walk failing before the first 64KB of
+// it are sent is answered with an error. One failing later aborts the
+// response, leaving it visibly truncated rather than looking complete.
+func (c *ServerCommand) networksHandler(writer http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	walker, ok := c.provider.(mm.NetworkWalker)
+	if !ok {
+		writeJsonError(writer, http.StatusNotImplemented, "the database backend cannot list its networks")
+		return
+	}
+
+	query := req.URL.Query()
+	filter, err := newNetworkFilter(query["country"], query["city"], query["asn"])
+	if err != nil {
+		writeJsonError(writer, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// the walk, and the lookups of the autonomous systems, read a snapshot
+	// of the database, which neither holds up reloads nor sees them
+	database, isDatabase := walker.(*mm.Database)
+	if isDatabase {
+		database = database.Snapshot()
+		defer database.Close()
+		walker = database
+	}
+	if len(filter.asns) > 0 {
+		if !isDatabase || !strings.Contains(database.Metadata().Type, "ASN") {
+			writeJsonError(writer, http.StatusBadRequest, "the database has no autonomous systems")
+			return
+		}
+		filter.asnDatabase = database
+	}
+
+	format := query.Get("format")
+	if format == "" {
+		format = "json"
+	}
+	if !NetworkFormats[format] {
+		writeJsonError(writer, http.StatusBadRequest, fmt.Sprintf("invalid format '%s'", format))
+		return
+	}
+
+	// the request has already been charged for one lookup
+	if !c.takeRateLimit(writer, req, networksCost-1) || !c.chargeApiKey(writer, req, networksCost-1) {
+		return
+	}
+
+	if format == "json" {
+		writer.Header().Set("Content-Type", "application/json")
+	} else {
+		writer.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	}
+	metadata := c.provider.Metadata()
+	if isDatabase {
+		// the provider may have reloaded since the snapshot was taken
+		metadata = database.Metadata()
+	}
+	writer.Header().Set("Last-Modified", metadata.BuildTime.UTC().Format(http.TimeFormat))
+
+	body := &bodyWriter{ResponseWriter: writer}
+	out := newNetworkWriter(body, format, "inet filter", "geo", "DROP")
+	err = mm.CollapseNetworks(walker, func(network *net.IPNet, data *mm.GeoData) (bool, error) {
+		if err := req.Context().Err(); err != nil {
+			// the client has gone away; nobody is left to answer
+			return false, err
+		}
+		return filter.match(network, data)
+	}, out.Write)
+	if err == nil {
+		err = out.Close()
+	}
+	if err == nil || req.Context().Err() != nil {
+		return
+	}
+
+	withFields(c.Ui, "request_id", writer.Header().Get("X-Request-ID")).
+		Errorf("failed to list networks; error was: %s\n", err)
+	if body.written {
+		panic(http.ErrAbortHandler)
+	}
+	writer.Header().Del("Last-Modified")
+	writeJsonError(writer, http.StatusInternalServerError, "failed to list networks")
+}
+
+func (c *NetworksCommand) Help() string {
+	return fmt.Sprintf(`Usage: %s networks [options]
+
+Lists every network a database maps to the given countries, cities, or
+autonomous systems, collapsing adjacent networks into the fewest networks
+covering them, e.g. for firewall or compliance rules. The networks are
+streamed as the database is walked.
+
+Options:
+  -f, -database.file  <file>      Path to MaxMind Database, or directory of
+                                  its CSV edition (default: %s)
+  -c, -country        <iso code>  List the networks in the country; may be
+                                  repeated
+  -C, -city           <name>      List the networks in the city; may be
+                                  repeated
+  -a, -asn            <number>    List the networks of the autonomous system;
+                                  may be repeated, and requires an ASN database
+  -A, -asn.file       <file>      ASN database, such as GeoLite2-ASN, to look up
+                                  the autonomous system of each network in
+                                  (default: the database, if it is one)
+  -o, -format         <string>    Output format; one of cidr, nft, iptables,
+                                  or json (default: %s)
+  -t, -table          <table>     nftables table of the set the networks are
+                                  added to (default: %s)
+  -s, -set            <name>      nftables set, suffixed with _v4 or _v6, or
+                                  iptables chain the networks are added to
+                                  (default: %s)
+  -j, -jump           <target>    Target of iptables rules (default: %s)
+
+Networks matching several kinds of criteria are only listed when they match
+each kind, e.g. the networks in a country of the given autonomous systems.
+`, os.Args[0], DefaultDatabasePath, "cidr", "inet filter", "geo", "DROP")
+}
+
+func (c *NetworksCommand) Synopsis() string {
+	return "List the networks of a country, city or autonomous system"
+}
+
+// Execute lists the networks of the database selected by args.
+func (c *NetworksCommand) Execute(args []string) error {
+	var err error
+
+	var mainParse = flag.NewFlagSet("networks", flag.ContinueOnError)
+	dbFile := mainParse.String("database.file", DefaultDatabasePath, "`path` to the database file to list the networks of")
+	mainParse.StringVar(dbFile, "f", DefaultDatabasePath, "`path` to the database file to list the networks of")
+	var countries, cities, asns ListFlag
+	mainParse.Var(&countries, "country", "list the networks in the country with ISO `code`; may be repeated")
+	mainParse.Var(&countries, "c", "list the networks in the country with ISO `code`; may be repeated")
+	mainParse.Var(&cities, "city", "list the networks in the city with `name`; may be repeated")
+	mainParse.Var(&cities, "C", "list the networks in the city with `name`; may be repeated")
+	mainParse.Var(&asns, "asn", "list the networks of the autonomous system `number`; may be repeated")
+	mainParse.Var(&asns, "a", "list the networks of the autonomous system `number`; may be repeated")
+	asnFile := mainParse.String("asn.file", "", "`path` to an ASN database to look up the autonomous system of each network in")
+	mainParse.StringVar(asnFile, "A", "", "`path` to an ASN database to look up the autonomous system of each network in")
+	format := mainParse.String("format", "cidr", "output `format`; one of 'cidr', 'nft', 'iptables', or 'json'")
+	mainParse.StringVar(format, "o", "cidr", "output `format`; one of 'cidr', 'nft', 'iptables', or 'json'")
+	table := mainParse.String("table", "inet filter", "nftables `table` of the set the networks are added to")
+	mainParse.StringVar(table, "t", "inet filter", "nftables `table` of the set the networks are added to")
+	set := mainParse.String("set", "geo", "`name` of the nftables set, or iptables chain, the networks are added to")
+	mainParse.StringVar(set, "s", "geo", "`name` of the nftables set, or iptables chain, the networks are added to")
+	jump := mainParse.String("jump", "DROP", "`target` of iptables rules")
+	mainParse.StringVar(jump, "j", "DROP", "`target` of iptables rules")
+
+	mainParse.Usage = func() {
+		c.Ui.Output(c.Help())
+		mainParse.PrintDefaults()
+	}
+	if err = mainParse.Parse(args); err != nil {
+		return usageError(err)
+	}
+
+	if !NetworkFormats[*format] {
+		return usageError(fmt.Errorf("invalid format '%s'; expected one of 'cidr', 'nft', 'iptables', or 'json'", *format))
+	}
+	if *dbFile == "" {
+		return usageError(errors.New("missing required path to MasterMind DB file"))
+	}
+
+	filter, err := newNetworkFilter(countries, cities, asns)
+	if err != nil {
+		return usageError(err)
+	}
+
+	dbPath, err := mm.NewPathname(*dbFile).RealPath()
+	if err != nil {
+		return err
+	}
+	database, err := openDatabase(dbPath.Path())
+	if err != nil {
+		return err
+	}
+	defer database.Close()
+
+	if len(filter.asns) > 0 {
+		switch {
+		case *asnFile != "":
+			asnPath, err := mm.NewPathname(*asnFile).RealPath()
+			if err != nil {
+				return err
+			}
+			if filter.asnDatabase, err = openDatabase(asnPath.Path()); err != nil {
+				return err
+			}
+			defer filter.asnDatabase.Close()
+		case strings.Contains(database.Metadata().Type, "ASN"):
+			filter.asnDatabase = database
+		default:
+			return usageError(errors.New("listing the networks of autonomous systems requires an ASN database; give one with -asn.file"))
+		}
+	}
+
+	// the networks are split where the autonomous systems change, so that
+	// each has a single one
+	var walker mm.NetworkWalker = database
+	if filter.asnDatabase != nil && filter.asnDatabase != database {
+		walker = mm.SplitNetworks(database, filter.asnDatabase)
+	}
+
+	out := newNetworkWriter(uiWriter{c.Ui}, *format, *table, *set, *jump)
+	if err = mm.CollapseNetworks(walker, filter.match, out.Write); err != nil {
+		return err
+	}
+	return out.Close()
+}
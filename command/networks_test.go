@@ -0,0 +1,169 @@
+package command
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/rabbitt/maxmind/mm"
+)
+
+func newNetworksCommand(ui Ui) executor {
+	return &NetworksCommand{Ui: ui}
+}
+
+const sanctionedNetworks = `network,country,city
+10.0.0.0/24,IR,Tehran
+10.0.1.0/24,IR,Tehran
+10.0.2.0/24,US,Ashburn
+10.0.3.0/24,IR,Tabriz
+2001:db8::/33,IR,Tehran
+2001:db8:8000::/33,IR,Tehran
+`
+
+func TestNetworksCommand(t *testing.T) {
+	path := buildCsv(t, sanctionedNetworks)
+	asnPath := buildFixture(t, "GeoLite2-ASN-Test.csv")
+	cityPath := buildFixture(t, "GeoIP2-City-Test.json")
+
+	for _, test := range []struct {
+		args []string
+		want string
+	}{
+		{[]string{"-f", path, "-c", "IR"}, "10.0.0.0/23\n10.0.3.0/24\n2001:db8::/32\n"},
+		{[]string{"-f", path, "-country", "ir,us"}, "10.0.0.0/22\n2001:db8::/32\n"},
+		{[]string{"-f", path, "-c", "IR", "-C", "tehran"}, "10.0.0.0/23\n2001:db8::/32\n"},
+		{[]string{"-f", path, "-c", "KP"}, ""},
+		{[]string{"-f", path, "-c", "IR", "-o", "json"}, "[\n\"10.0.0.0/23\",\n\"10.0.3.0/24\",\n\"2001:db8::/32\"\n]\n"},
+		{[]string{"-f", path, "-c", "KP", "-format", "json"}, "[\n]\n"},
+		{[]string{"-f", path, "-city", "Tabriz", "-o", "nft", "-s", "blocked"},
+			"add element inet filter blocked_v4 { 10.0.3.0/24 }\n"},
+		{[]string{"-f", path, "-C", "Tehran", "-o", "iptables", "-j", "REJECT"},
+			"iptables -A geo -s 10.0.0.0/23 -j REJECT\nip6tables -A geo -s 2001:db8::/32 -j REJECT\n"},
+		{[]string{"-f", asnPath, "-a", "AS1221,237"}, "1.128.0.0/11\n2600:6000::/20\n"},
+		{[]string{"-f", cityPath, "-A", asnPath, "-a", "20712", "-c", "GB"}, "81.2.69.142/31\n"},
+		{[]string{"-f", path, "-A", buildCsv(t, "network,autonomous_system_number:uint32\n10.0.1.0/24,64500\n", "-t", "GeoLite2-ASN"), "-a", "64500"},
+			"10.0.1.0/24\n"},
+	} {
+		if output, err := runCommand(newNetworksCommand, test.args...); err != nil || output != test.want {
+			t.Errorf("%v = %q, %v; want %q", test.args, output, err, test.want)
+		}
+	}
+}
+
+func TestNetworksCommandErrors(t *testing.T) {
+	path := buildFixture(t, "GeoIP2-City-Test.json")
+
+	for _, args := range [][]string{
+		{"-f", path},
+		{"-f", path, "-c", "IR", "-o", "pf"},
+		{"-f", path, "-a", "AS-one"},
+		{"-f", path, "-a", "1221"},
+	} {
+		var usage *UsageError
+		if _, err := runCommand(newNetworksCommand, args...); !errors.As(err, &usage) {
+			t.Errorf("%v = %v; want a *UsageError", args, err)
+		}
+	}
+}
+
+func TestNetworksHandler(t *testing.T) {
+	c, _ := testServer(t)
+	database, err := mm.OpenDatabase(buildCsv(t, sanctionedNetworks))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(database.Close)
+	c.provider = database
+
+	var networks []string
+	recorder := serve(c, "GET", "/networks?country=IR&city=Tehran", "", nil)
+	decodeBody(t, recorder, &networks)
+	if recorder.Code != http.StatusOK || !reflect.DeepEqual(networks, []string{"10.0.0.0/23", "2001:db8::/32"}) {
+		t.Errorf("unexpected response %d %q", recorder.Code, recorder.Body)
+	}
+
+	if modified := recorder.Header().Get("Last-Modified"); modified != database.BuildTime().UTC().Format(http.TimeFormat) {
+		t.Errorf("Last-Modified = %q; want the build time of the database", modified)
+	}
+
+	recorder = serve(c, "GET", "/networks?country=IR&country=US&format=cidr", "", nil)
+	if recorder.Header().Get("Content-Type") != "text/plain; charset=utf-8" || recorder.Body.String() != "10.0.0.0/22\n2001:db8::/32\n" {
+		t.Errorf("unexpected response %q", recorder.Body)
+	}
+
+	for target, code := range map[string]int{
+		"/networks":                      http.StatusBadRequest,
+		"/networks?country=IR&format=pf": http.StatusBadRequest,
+		"/networks?asn=1221":             http.StatusBadRequest,
+	} {
+		var response mm.JsonResponse
+		recorder = serve(c, "GET", target, "", nil)
+		decodeBody(t, recorder, &response)
+		if recorder.Code != code || response.Status != "error" {
+			t.Errorf("%s = %d %+v; want %d", target, recorder.Code, response, code)
+		}
+	}
+
+	c.provider = mm.NewMemoryProvider("GeoIP2-City")
+	if recorder = serve(c, "GET", "/networks?country=IR", "", nil); recorder.Code != http.StatusNotImplemented {
+		t.Errorf("memory provider = %d; want %d", recorder.Code, http.StatusNotImplemented)
+	}
+}
+
+func TestNetworksHandlerRateLimit(t *testing.T) {
+	c, _ := testServer(t)
+	c.Config.RateLimitKey = mm.RateLimitGlobal
+	c.rateLimiter = mm.NewRateLimiter(0.001, 150)
+
+	// a listing costs networksCost lookups
+	for idx, code := range []int{http.StatusOK, http.StatusTooManyRequests} {
+		if recorder := serve(c, "GET", "/networks?country=GB", "", nil); recorder.Code != code {
+			t.Errorf("listing %d = %d; want %d", idx, recorder.Code, code)
+		}
+	}
+}
+
+// failingProvider walks count networks in IR, every other /24 from 10.0.0.0,
+// and then fails.
+type failingProvider struct {
+	*mm.MemoryProvider
+	count int
+}
+
+func (p *failingProvider) Networks(fn func(network *net.IPNet, data *mm.GeoData) error) error {
+	for idx := 0; idx < p.count; idx++ {
+		network := &net.IPNet{IP: net.IPv4(10, byte(idx>>7), byte(idx<<1), 0).To4(), Mask: net.CIDRMask(24, 32)}
+		data := &mm.GeoData{}
+		data.Country.IsoCode = "IR"
+		if err := fn(network, data); err != nil {
+			return err
+		}
+	}
+	return errors.New("corrupt database")
+}
+
+func TestNetworksHandlerWalkErrors(t *testing.T) {
+	c, _ := testServer(t)
+
+	// failing before anything is sent is answered with an error
+	c.provider = &failingProvider{MemoryProvider: mm.NewMemoryProvider("GeoIP2-City"), count: 10}
+	var response mm.JsonResponse
+	recorder := serve(c, "GET", "/networks?country=IR", "", nil)
+	decodeBody(t, recorder, &response)
+	if recorder.Code != http.StatusInternalServerError || response.Status != "error" || recorder.Header().Get("Last-Modified") != "" {
+		t.Errorf("unexpected response %d %q", recorder.Code, recorder.Body)
+	}
+
+	// failing once the listing has started aborts the response
+	c.provider = &failingProvider{MemoryProvider: mm.NewMemoryProvider("GeoIP2-City"), count: 10000}
+	defer func() {
+		if r := recover(); r != http.ErrAbortHandler {
+			t.Errorf("recovered %v; want the response aborted", r)
+		}
+	}()
+	serve(c, "GET", "/networks?country=IR", "", nil)
+	t.Error("a truncated listing was answered")
+}
@@ -0,0 +1,42 @@
+package command
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/rabbitt/maxmind/client"
+	"github.com/rabbitt/maxmind/mm"
+)
+
+// openProvider opens the lookup backend selected by database.backend: the
+// database file, the CSV edition of one, or an upstream maxmind server. The
+// caller is responsible for closing it.
+func openProvider(config *mm.Configuration) (mm.Provider, error) {
+	switch config.Backend {
+	case "", mm.BackendMmdb:
+		database, err := mm.OpenDatabase(config.DbPath.Path())
+		if err != nil {
+			return nil, err
+		}
+		return database, nil
+
+	case mm.BackendCsv:
+		database, err := mm.OpenCsvDatabase(config.DbPath.Path())
+		if err != nil {
+			return nil, err
+		}
+		return database, nil
+
+	case mm.BackendUpstream:
+		if config.Upstream == "" {
+			return nil, &mm.ConfigError{Key: "database.upstream", Err: errors.New("missing url of the upstream server")}
+		}
+		upstream, err := client.NewUpstream(config.Upstream, config.UpstreamKey)
+		if err != nil {
+			return nil, err
+		}
+		return upstream, nil
+	}
+
+	return nil, &mm.ConfigError{Key: "database.backend", Err: fmt.Errorf("'%s' is not one of 'mmdb', 'csv', or 'upstream'", config.Backend)}
+}
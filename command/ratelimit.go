@@ -0,0 +1,73 @@
+package command
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/rabbitt/maxmind/mm"
+)
+
+// clientIp returns the address of the remote end of the request, which for
+// unix socket listeners is empty.
+func clientIp(req *http.Request) string {
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		return req.RemoteAddr
+	}
+	return host
+}
+
+// rateLimitKey returns the bucket a request from clientAddr, carrying
+// apiKey, is counted against. Requests without one of the configured api
+// keys are counted per client IP when limiting by api key, so that made up
+// keys neither escape the limit nor each get a bucket of their own.
+func (c *ServerCommand) rateLimitKey(clientAddr string, apiKey string) string {
+	switch c.Config.RateLimitKey {
+	case mm.RateLimitGlobal:
+		return ""
+	case mm.RateLimitByApiKey:
+		if apiKey != "" && c.apiKeys != nil && c.apiKeys.Find(apiKey) != nil {
+			return "key:" + apiKey
+		}
+	}
+	return "ip:" + clientAddr
+}
+
+func setRateLimitHeaders(writer http.ResponseWriter, allowance mm.Allowance) {
+	writer.Header().Set("X-RateLimit-Limit", fmt.Sprintf("%d", allowance.Limit))
+	writer.Header().Set("X-RateLimit-Remaining", fmt.Sprintf("%d", allowance.Remaining))
+	if !allowance.Allowed {
+		writer.Header().Set("Retry-After", fmt.Sprintf("%.0f", math.Ceil(allowance.RetryAfter.Seconds())))
+	}
+}
+
+// takeRateLimit charges req cost tokens of the configured rate, answering
+// it with a 429, and returning false, when they are not available.
+func (c *ServerCommand) takeRateLimit(writer http.ResponseWriter, req *http.Request, cost int) bool {
+	if c.rateLimiter == nil || cost <= 0 {
+		return true
+	}
+
+	allowance := c.rateLimiter.TakeN(c.rateLimitKey(clientIp(req), requestApiKey(req)), cost)
+	setRateLimitHeaders(writer, allowance)
+
+	if !allowance.Allowed {
+		writeJsonError(writer, http.StatusTooManyRequests, "rate limit exceeded")
+		return false
+	}
+	return true
+}
+
+// rateLimit wraps handle so that requests beyond the configured rate are
+// rejected with a 429. Handlers doing more than one lookup per request
+// charge the rest themselves, with takeRateLimit.
+func (c *ServerCommand) rateLimit(handle httprouter.Handle) httprouter.Handle {
+	return func(writer http.ResponseWriter, req *http.Request, params httprouter.Params) {
+		if c.takeRateLimit(writer, req, 1) {
+			handle(writer, req, params)
+		}
+	}
+}
@@ -0,0 +1,326 @@
+package command
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/rabbitt/maxmind/mm"
+)
+
+type ExportRulesCommand struct {
+	Ui Ui
+}
+
+// RuleSet is what the templates of the export-rules command render: the
+// collapsed networks of the countries of an allow or deny list. It holds
+// nothing that varies between exports of the same data, such as the time,
+// so that the rules only change when the networks do.
+type RuleSet struct {
+	// Name names the sets, or variable, of the rules.
+	Name string
+
+	// Table is the nftables table of the sets.
+	Table string
+
+	// Action is the action of the list; allow or deny.
+	Action string
+
+	// Countries are the ISO codes of the countries of the list, sorted.
+	Countries []string
+
+	// Database is the type of the database the networks come from.
+	Database string
+
+	IPv4 []string
+	IPv6 []string
+}
+
+var ruleFuncs = template.FuncMap{
+	"join": strings.Join,
+}
+
+var (
+	// ruleNamePattern matches the names of sets, and nginx variables
+	ruleNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+	// ruleTablePattern matches nftables tables: a name, optionally preceded
+	// by its family
+	ruleTablePattern = regexp.MustCompile(`^((ip|ip6|inet|arp|bridge|netdev) )?[A-Za-z_][A-Za-z0-9_]*$`)
+)
+
+// RuleTemplates are the templates of the export-rules command, by format.
+var RuleTemplates = map[string]string{
+	// the sets are created with a fixed maxelem, well above the networks of
+	// any country, as create -exist fails on a set created with another
+	"ipset": `# {{.Action}} {{join .Countries ", "}}, from {{.Database}}; generated by maxmind export-rules
+create {{.Name}}_v4 hash:net family inet maxelem 1048576 -exist
+flush {{.Name}}_v4
+{{range .IPv4}}add {{$.Name}}_v4 {{.}}
+{{end}}create {{.Name}}_v6 hash:net family inet6 maxelem 1048576 -exist
+flush {{.Name}}_v6
+{{range .IPv6}}add {{$.Name}}_v6 {{.}}
+{{end}}`,
+
+	// the sets are declared, and flushed, before their elements are added,
+	// so that loading the rules again replaces the networks
+	"nftables": `# {{.Action}} {{join .Countries ", "}}, from {{.Database}}; generated by maxmind export-rules
+table {{.Table}} {
+	set {{.Name}}_v4 {
+		type ipv4_addr
+		flags interval
+	}
+	set {{.Name}}_v6 {
+		type ipv6_addr
+		flags interval
+	}
+}
+flush set {{.Table}} {{.Name}}_v4
+flush set {{.Table}} {{.Name}}_v6
+{{- if .IPv4}}
+add element {{.Table}} {{.Name}}_v4 {
+{{- range $idx, $network := .IPv4}}{{if $idx}},{{end}}
+	{{$network}}
+{{- end}}
+}
+{{- end}}
+{{- if .IPv6}}
+add element {{.Table}} {{.Name}}_v6 {
+{{- range $idx, $network := .IPv6}}{{if $idx}},{{end}}
+	{{$network}}
+{{- end}}
+}
+{{- end}}
+`,
+
+	// $name is 1 for clients to deny, and 0 for those to allow
+	"nginx": `# {{.Action}} {{join .Countries ", "}}, from {{.Database}}; generated by maxmind export-rules
+geo ${{.Name}} {
+	default {{if eq .Action "allow"}}1{{else}}0{{end}};
+{{- $value := "1"}}{{if eq .Action "allow"}}{{$value = "0"}}{{end}}
+{{- range .IPv4}}
+	{{.}} {{$value}};
+{{- end}}
+{{- range .IPv6}}
+	{{.}} {{$value}};
+{{- end}}
+}
+`,
+}
+
+// writeIfChanged writes content to path by way of a temporary file, unless
+// path already holds it, so that its modification time only changes with
+// its content. A file it replaces keeps its mode.
+func writeIfChanged(path string, content []byte) (bool, error) {
+	if existing, err := os.ReadFile(path); err == nil && bytes.Equal(existing, content) {
+		return false, nil
+	}
+
+	var mode os.FileMode = 0644
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+
+	file, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return false, &mm.PathError{Path: path, Err: err}
+	}
+	tmpPath := file.Name()
+	defer os.Remove(tmpPath)
+
+	_, err = file.Write(content)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmpPath, mode)
+	}
+	if err != nil {
+		return false, fmt.Errorf("unable to write %s: %s", path, err)
+	}
+
+	if err = os.Rename(tmpPath, path); err != nil {
+		return false, &mm.PathError{Path: path, Err: err}
+	}
+	return true, nil
+}
+
+func (c *ExportRulesCommand) Help() string {
+	return fmt.Sprintf(`Usage: %s export-rules [options]
+
+Renders the networks a database maps to the countries of an allow or deny
+list as ready to load rules: an ipset restore file, nftables sets, or an
+nginx geo block. The rules hold nothing but the networks, in address order,
+so that they only change when the data does; written to a file, the file is
+left untouched unless they have.
+
+Options:
+  -f, -database.file  <file>      Path to MaxMind Database, or directory of
+                                  its CSV edition (default: %s)
+  -c, -country        <iso code>  Country of the list; may be repeated
+  -m, -mode           <string>    Action of the list; allow or deny
+                                  (default: %s)
+  -F, -format         <string>    Rules to render; one of ipset, nftables,
+                                  or nginx (default: %s)
+  -T, -template       <file>      Go text/template to render in place of the
+                                  format's, given a RuleSet
+  -n, -name           <name>      Name of the sets, suffixed with _v4 or _v6,
+                                  or of the nginx variable (default: %s)
+  -t, -table          <table>     nftables table of the sets, optionally
+                                  preceded by its family (default: %s)
+  -o, -output         <file>      File to write (default: stdout)
+
+Each country must have networks in the database, lest a mistyped code
+empty the list. The nginx variable is 1 for clients to deny, and 0 for
+those to allow:
+
+  if ($geo) { return 403; }
+`, os.Args[0], DefaultDatabasePath, "deny", "nftables", "geo", "inet filter")
+}
+
+func (c *ExportRulesCommand) Synopsis() string {
+	return "Export allow or deny lists of countries as firewall or nginx rules"
+}
+
+// Execute renders the rules of the list given by args.
+func (c *ExportRulesCommand) Execute(args []string) error {
+	var err error
+
+	var mainParse = flag.NewFlagSet("export-rules", flag.ContinueOnError)
+	dbFile := mainParse.String("database.file", DefaultDatabasePath, "`path` to the database file to export the networks of")
+	mainParse.StringVar(dbFile, "f", DefaultDatabasePath, "`path` to the database file to export the networks of")
+	var countries ListFlag
+	mainParse.Var(&countries, "country", "ISO `code` of a country of the list; may be repeated")
+	mainParse.Var(&countries, "c", "ISO `code` of a country of the list; may be repeated")
+	mode := mainParse.String("mode", "deny", "`action` of the list; 'allow' or 'deny'")
+	mainParse.StringVar(mode, "m", "deny", "`action` of the list; 'allow' or 'deny'")
+	format := mainParse.String("format", "nftables", "`rules` to render; one of 'ipset', 'nftables', or 'nginx'")
+	mainParse.StringVar(format, "F", "nftables", "`rules` to render; one of 'ipset', 'nftables', or 'nginx'")
+	templateFile := mainParse.String("template", "", "`path` of a template to render in place of the format's")
+	mainParse.StringVar(templateFile, "T", "", "`path` of a template to render in place of the format's")
+	table := mainParse.String("table", "inet filter", "nftables `table` of the sets")
+	mainParse.StringVar(table, "t", "inet filter", "nftables `table` of the sets")
+	name := mainParse.String("name", "geo", "`name` of the sets, or nginx variable")
+	mainParse.StringVar(name, "n", "geo", "`name` of the sets, or nginx variable")
+	output := mainParse.String("output", "", "`path` of the file to write; stdout when empty")
+	mainParse.StringVar(output, "o", "", "`path` of the file to write; stdout when empty")
+
+	mainParse.Usage = func() {
+		c.Ui.Output(c.Help())
+		mainParse.PrintDefaults()
+	}
+	if err = mainParse.Parse(args); err != nil {
+		return usageError(err)
+	}
+
+	if *mode != "allow" && *mode != "deny" {
+		return usageError(fmt.Errorf("invalid mode '%s'; expected 'allow' or 'deny'", *mode))
+	}
+	if *dbFile == "" {
+		return usageError(errors.New("missing required path to MasterMind DB file"))
+	}
+	if !ruleNamePattern.MatchString(*name) {
+		return usageError(fmt.Errorf("invalid name '%s'; expected letters, digits, and underscores", *name))
+	}
+	if !ruleTablePattern.MatchString(*table) {
+		return usageError(fmt.Errorf("invalid table '%s'; expected a name, optionally preceded by its family", *table))
+	}
+
+	// the format is checked even when a template replaces its own
+	text, known := RuleTemplates[*format]
+	if !known {
+		return usageError(fmt.Errorf("invalid format '%s'; expected one of 'ipset', 'nftables', or 'nginx'", *format))
+	}
+	if *templateFile != "" {
+		templatePath, err := mm.NewPathname(*templateFile).RealPath()
+		if err != nil {
+			return err
+		}
+		content, err := os.ReadFile(templatePath.Path())
+		if err != nil {
+			return &mm.PathError{Path: *templateFile, Err: err}
+		}
+		text = string(content)
+	}
+	tmpl, err := template.New(*format).Funcs(ruleFuncs).Parse(text)
+	if err != nil {
+		return usageError(fmt.Errorf("invalid template: %s", err))
+	}
+
+	filter, err := newNetworkFilter(countries, nil, nil)
+	if err != nil {
+		return usageError(errors.New("missing the countries of the list"))
+	}
+
+	dbPath, err := mm.NewPathname(*dbFile).RealPath()
+	if err != nil {
+		return err
+	}
+	database, err := openDatabase(dbPath.Path())
+	if err != nil {
+		return err
+	}
+	defer database.Close()
+
+	rules := &RuleSet{Name: *name, Table: *table, Action: *mode, Database: database.Metadata().Type}
+	for country := range filter.countries {
+		rules.Countries = append(rules.Countries, country)
+	}
+	sort.Strings(rules.Countries)
+
+	// an empty list of a country, as a typo makes, would allow no one, or
+	// deny no one
+	found := map[string]bool{}
+	match := func(network *net.IPNet, data *mm.GeoData) (bool, error) {
+		matched, err := filter.match(network, data)
+		if matched {
+			found[data.Country.IsoCode] = true
+		}
+		return matched, err
+	}
+
+	err = mm.CollapseNetworks(database, match, func(network *net.IPNet) error {
+		if network.IP.To4() != nil {
+			rules.IPv4 = append(rules.IPv4, network.String())
+		} else {
+			rules.IPv6 = append(rules.IPv6, network.String())
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, country := range rules.Countries {
+		if !found[country] {
+			return fmt.Errorf("the database has no networks in '%s'", country)
+		}
+	}
+
+	var rendered bytes.Buffer
+	if err = tmpl.Execute(&rendered, rules); err != nil {
+		return fmt.Errorf("unable to render the rules: %s", err)
+	}
+
+	if *output == "" {
+		c.Ui.Outputf("%s", rendered.Bytes())
+		return nil
+	}
+	changed, err := writeIfChanged(*output, rendered.Bytes())
+	if err != nil {
+		return err
+	}
+	if changed {
+		c.Ui.Infof("Wrote %s: %d IPv4 and %d IPv6 networks\n", *output, len(rules.IPv4), len(rules.IPv6))
+	} else {
+		c.Ui.Infof("%s is unchanged\n", *output)
+	}
+	return nil
+}
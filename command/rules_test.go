@@ -0,0 +1,155 @@
+package command
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newExportRulesCommand(ui Ui) executor {
+	return &ExportRulesCommand{Ui: ui}
+}
+
+func TestExportRulesCommand(t *testing.T) {
+	path := buildCsv(t, sanctionedNetworks)
+
+	for _, test := range []struct {
+		args []string
+		want string
+	}{
+		{[]string{"-F", "ipset", "-c", "IR", "-n", "sanctioned"}, `# deny IR, from GeoIP2-City; generated by maxmind export-rules
+create sanctioned_v4 hash:net family inet maxelem 1048576 -exist
+flush sanctioned_v4
+add sanctioned_v4 10.0.0.0/23
+add sanctioned_v4 10.0.3.0/24
+create sanctioned_v6 hash:net family inet6 maxelem 1048576 -exist
+flush sanctioned_v6
+add sanctioned_v6 2001:db8::/32
+`},
+		{[]string{"-c", "us", "-c", "IR"}, `# deny IR, US, from GeoIP2-City; generated by maxmind export-rules
+table inet filter {
+	set geo_v4 {
+		type ipv4_addr
+		flags interval
+	}
+	set geo_v6 {
+		type ipv6_addr
+		flags interval
+	}
+}
+flush set inet filter geo_v4
+flush set inet filter geo_v6
+add element inet filter geo_v4 {
+	10.0.0.0/22
+}
+add element inet filter geo_v6 {
+	2001:db8::/32
+}
+`},
+		{[]string{"-format", "nftables", "-table", "inet geo", "-country", "US"}, `# deny US, from GeoIP2-City; generated by maxmind export-rules
+table inet geo {
+	set geo_v4 {
+		type ipv4_addr
+		flags interval
+	}
+	set geo_v6 {
+		type ipv6_addr
+		flags interval
+	}
+}
+flush set inet geo geo_v4
+flush set inet geo geo_v6
+add element inet geo geo_v4 {
+	10.0.2.0/24
+}
+`},
+		{[]string{"-F", "nginx", "-m", "allow", "-c", "US", "-name", "blocked"}, `# allow US, from GeoIP2-City; generated by maxmind export-rules
+geo $blocked {
+	default 1;
+	10.0.2.0/24 0;
+}
+`},
+		{[]string{"-F", "nginx", "-c", "IR"}, `# deny IR, from GeoIP2-City; generated by maxmind export-rules
+geo $geo {
+	default 0;
+	10.0.0.0/23 1;
+	10.0.3.0/24 1;
+	2001:db8::/32 1;
+}
+`},
+	} {
+		if output, err := runCommand(newExportRulesCommand, append([]string{"-f", path}, test.args...)...); err != nil || output != test.want {
+			t.Errorf("%v = %v\n%s\nwant\n%s", test.args, err, output, test.want)
+		}
+	}
+}
+
+func TestExportRulesCommandOutput(t *testing.T) {
+	path := buildCsv(t, sanctionedNetworks)
+	dir := t.TempDir()
+	output := filepath.Join(dir, "sanctioned.nft")
+
+	template := writeCsv(t, dir, "cidrs.tmpl", "{{range .IPv4}}{{.}}\n{{end}}")
+	if out, err := runCommand(newExportRulesCommand, "-f", path, "-c", "IR", "-T", template, "-o", output); err != nil || !strings.Contains(out, "Wrote "+output) {
+		t.Fatalf("export = %q, %v", out, err)
+	}
+	if content, err := os.ReadFile(output); err != nil || string(content) != "10.0.0.0/23\n10.0.3.0/24\n" {
+		t.Errorf("unexpected rules %q, %v", content, err)
+	}
+
+	// exporting the same data again leaves the file untouched
+	old := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(output, old, old); err != nil {
+		t.Fatal(err)
+	}
+	if out, err := runCommand(newExportRulesCommand, "-f", path, "-country", "IR", "-template", template, "-output", output); err != nil || !strings.Contains(out, "unchanged") {
+		t.Fatalf("export = %q, %v", out, err)
+	}
+	if info, err := os.Stat(output); err != nil || !info.ModTime().Equal(old) {
+		t.Errorf("the unchanged rules were written again: %v, %v", info.ModTime(), err)
+	}
+
+	// rewriting the file keeps its mode
+	if err := os.Chmod(output, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := runCommand(newExportRulesCommand, "-f", path, "-c", "IR", "-c", "US", "-T", template, "-o", output); err != nil {
+		t.Fatal(err)
+	}
+	if content, err := os.ReadFile(output); err != nil || string(content) != "10.0.0.0/22\n" {
+		t.Errorf("unexpected rules %q, %v", content, err)
+	}
+	if info, err := os.Stat(output); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("mode of the rewritten rules = %v, %v; want %v", info.Mode().Perm(), err, os.FileMode(0600))
+	}
+}
+
+func TestExportRulesCommandErrors(t *testing.T) {
+	path := buildFixture(t, "GeoIP2-City-Test.json")
+	template := writeCsv(t, t.TempDir(), "broken.tmpl", "{{range .IPv4}")
+	valid := writeCsv(t, t.TempDir(), "cidrs.tmpl", "{{range .IPv4}}{{.}}\n{{end}}")
+
+	for _, args := range [][]string{
+		{"-f", path},
+		{"-f", path, "-c", "IR", "-F", "pf"},
+		{"-f", path, "-c", "IR", "-F", "pf", "-T", valid},
+		{"-f", path, "-c", "IR", "-m", "block"},
+		{"-f", path, "-c", "IR", "-T", template},
+		{"-f", path, "-c", "IR", "-n", "geo; flush ruleset"},
+		{"-f", path, "-c", "IR", "-t", "inet filter { }"},
+		{"-f", path, "-c", "IR", "-t", "filter inet"},
+	} {
+		var usage *UsageError
+		if _, err := runCommand(newExportRulesCommand, args...); !errors.As(err, &usage) {
+			t.Errorf("%v = %v; want a *UsageError", args, err)
+		}
+	}
+
+	// a country without networks would empty the list
+	if _, err := runCommand(newExportRulesCommand, "-f", path, "-c", "GB", "-c", "XK", "-m", "allow"); err == nil || !strings.Contains(err.Error(), "'XK'") {
+		t.Errorf("export-rules = %v; want an error naming XK", err)
+	}
+}
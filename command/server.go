@@ -1,23 +1,34 @@
 package command
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"mime"
 	"net"
 	"net/http"
 	"os"
+	"os/signal"
 	"runtime"
+	"strings"
+	"syscall"
 	"time"
 
 	"github.com/julienschmidt/httprouter"
 	"github.com/pmylund/go-cache"
 	"github.com/pquerna/ffjson/ffjson"
+	"github.com/rabbitt/maxmind/client"
 	"github.com/rabbitt/maxmind/mm"
 )
 
 type ServerCommand struct {
 	configFile  *mm.Pathname
-	database    *mm.Database
+	provider    mm.Provider
+	layers      *mm.LayeredDatabase
+	apiKeys     *mm.ApiKeys
+	accessLog   *mm.AccessLog
+	rateLimiter *mm.RateLimiter
 	Config      *mm.Configuration
 	memCache    *cache.Cache
 	serverStart time.Time
@@ -29,8 +40,11 @@ func (c *ServerCommand) IpLookupHandler(writer http.ResponseWriter, req *http.Re
 	// Prepare the response and queue sending the record.
 	var cached []byte
 	var record interface{} = nil
+	var origins map[string]string
 	var status = "success"
 	var message = "OK"
+	var geoJson = acceptsGeoJson(req)
+	var cacheKey string
 
 	defer func() {
 		var j []byte
@@ -45,11 +59,16 @@ func (c *ServerCommand) IpLookupHandler(writer http.ResponseWriter, req *http.Re
 				data = record
 			}
 
-			j, err = ffjson.Marshal(&mm.JsonResponse{
-				Status:  status,
-				Message: message,
-				Data:    data,
-			})
+			if geoJson && data != nil {
+				j, err = ffjson.Marshal(mm.NewFeature(ipText, data))
+			} else {
+				j, err = ffjson.Marshal(&mm.JsonResponse{
+					Status:  status,
+					Message: message,
+					Data:    data,
+					Origins: origins,
+				})
+			}
 
 			if err != nil {
 				c.Ui.Error(err)
@@ -58,22 +77,29 @@ func (c *ServerCommand) IpLookupHandler(writer http.ResponseWriter, req *http.Re
 			}
 		}
 
-		if c.memCache != nil && cached == nil {
-			c.memCache.Set(ipText, j, cache.DefaultExpiration)
+		// errors are not cached, so that they are retried
+		if c.memCache != nil && cached == nil && status == "success" {
+			c.memCache.Set(cacheKey, j, cache.DefaultExpiration)
 		}
 
 		writer.Write(j)
 
 		if status != "success" {
-			c.Ui.Errorf("failed to handle request for %s; error was: %s\n", ipText, message)
+			withFields(c.Ui, "ip", ipText, "request_id", writer.Header().Get("X-Request-ID")).
+				Errorf("failed to handle request for %s; error was: %s\n", ipText, message)
 		}
 	}()
 
 	// Set headers
 	writer.Header().Set("Content-Type", "application/json")
 	writer.Header().Set("Last-Modified", c.serverStart.Format(http.TimeFormat))
+	writer.Header().Add("Vary", "Accept")
 
 	ipText = params.ByName("ip")
+	cacheKey = ipText
+	if geoJson {
+		cacheKey = "geojson:" + ipText
+	}
 
 	ip := net.ParseIP(ipText)
 	if ip == nil {
@@ -83,21 +109,125 @@ func (c *ServerCommand) IpLookupHandler(writer http.ResponseWriter, req *http.Re
 		return
 	}
 
+	if geoJson {
+		writer.Header().Set("Content-Type", mm.GeoJsonMimeType)
+	}
+
 	if c.memCache != nil {
-		v, found := c.memCache.Get(ipText)
+		v, found := c.memCache.Get(cacheKey)
 		if found {
+			writer.Header().Set("X-Cache", "HIT")
 			cached = v.([]byte)
 			return
 		}
+		writer.Header().Set("X-Cache", "MISS")
 	}
 
-	record, err := c.database.Lookup(ipText)
+	record, origins, err := c.lookupIP(req.Context(), ip)
 	if err != nil {
+		status = "error"
 		message = err.Error()
+		writer.WriteHeader(lookupErrorStatus(err))
 		return
 	}
 }
 
+// lookupErrorStatus returns the status answering a lookup that failed with
+// err: 504 when the backend timed out, 502 when an upstream server failed,
+// and 503 when the backend is otherwise unable to answer.
+func lookupErrorStatus(err error) int {
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		return http.StatusGatewayTimeout
+	}
+
+	var upstreamErr *client.Error
+	if errors.As(err, &upstreamErr) {
+		switch upstreamErr.StatusCode {
+		case http.StatusGatewayTimeout:
+			return http.StatusGatewayTimeout
+		case http.StatusTooManyRequests, http.StatusServiceUnavailable:
+			return http.StatusServiceUnavailable
+		}
+		return http.StatusBadGateway
+	}
+	if errors.As(err, &netErr) {
+		return http.StatusBadGateway
+	}
+	return http.StatusServiceUnavailable
+}
+
+// acceptsGeoJson reports whether the client asked for a GeoJSON rendering
+// of the record via the Accept header.
+func acceptsGeoJson(req *http.Request) bool {
+	for _, accept := range req.Header["Accept"] {
+		for _, mediaRange := range strings.Split(accept, ",") {
+			if mediaType, _, err := mime.ParseMediaType(mediaRange); err == nil && mediaType == mm.GeoJsonMimeType {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// lookupIP returns the record for ip, and the origin of each of its fields
+// when overlays are configured. Ips the database has no record of have an
+// empty record.
+func (c *ServerCommand) lookupIP(ctx context.Context, ip net.IP) (*mm.GeoData, map[string]string, error) {
+	var record *mm.GeoData
+	var origins map[string]string
+	var err error
+
+	if c.layers != nil {
+		record, origins, err = c.layers.LookupIP(ctx, ip)
+	} else {
+		record, err = c.provider.LookupIP(ctx, ip)
+	}
+
+	if _, notFound := err.(*mm.NotFoundError); notFound {
+		return &mm.GeoData{}, nil, nil
+	}
+	return record, origins, err
+}
+
+// cachedRecord is a record, and the origins of its fields, as cached by
+// lookupRecord.
+type cachedRecord struct {
+	record  *mm.GeoData
+	origins map[string]string
+}
+
+// lookupRecord returns the record for ipText, and the origins of its
+// fields, consulting the cache first. Records are cached separately from
+// the rendered HTTP responses so that non-HTTP frontends can share them.
+// Ips the database has no record of have an empty record.
+func (c *ServerCommand) lookupRecord(ctx context.Context, ipText string) (*mm.GeoData, map[string]string, error) {
+	cacheKey := "record:" + ipText
+
+	if c.memCache != nil {
+		if v, found := c.memCache.Get(cacheKey); found {
+			cached := v.(*cachedRecord)
+			return cached.record, cached.origins, nil
+		}
+	}
+
+	ip := net.ParseIP(ipText)
+	if ip == nil {
+		return nil, nil, &mm.InvalidIpError{Ip: ipText}
+	}
+
+	record, origins, err := c.lookupIP(ctx, ip)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if c.memCache != nil {
+		c.memCache.Set(cacheKey, &cachedRecord{record, origins}, cache.DefaultExpiration)
+	}
+
+	return record, origins, nil
+}
+
 func (c *ServerCommand) aliveHandler(writer http.ResponseWriter, request *http.Request, _ httprouter.Params) {
 	writer.Header().Set("Content-Type", "text/plain")
 	writer.Header().Set("Last-Modified", c.serverStart.Format(http.TimeFormat))
@@ -109,9 +239,31 @@ func (c *ServerCommand) aliveHandler(writer http.ResponseWriter, request *http.R
 	return
 }
 
-func (c *ServerCommand) startService() int {
+// routes returns the router serving the http api.
+func (c *ServerCommand) routes() *httprouter.Router {
+	router := httprouter.New()
+	router.GET("/ping", c.aliveHandler)
+	router.HEAD("/ping", c.aliveHandler)
+	router.GET("/healthz", c.livenessHandler)
+	router.HEAD("/healthz", c.livenessHandler)
+	router.GET("/readyz", c.readinessHandler)
+	router.HEAD("/readyz", c.readinessHandler)
+	router.GET("/ip/:ip", c.rateLimit(c.authenticate("/ip/:ip", c.IpLookupHandler)))
+	router.POST("/batch", c.rateLimit(c.authenticate("/batch", c.batchHandler)))
+	router.GET("/info", c.rateLimit(c.authenticate("/info", c.infoHandler)))
+	router.GET("/usage", c.rateLimit(c.authenticate("/usage", c.usageHandler)))
+	router.GET("/networks", c.rateLimit(c.authenticate("/networks", c.networksHandler)))
+	return router
+}
+
+func (c *ServerCommand) startService() error {
 	c.Ui.Info("Configuration:")
-	c.Ui.Infof("    Bind Address:   [ %s:%d ]\n", c.Config.Ip, c.Config.Port)
+	for _, address := range c.Config.ListenAddresses() {
+		c.Ui.Infof("    Listen Address: [ %s ]\n", address)
+	}
+	if c.Config.GrpcPort > 0 {
+		c.Ui.Infof("    gRPC Address:   [ %s:%d ]\n", c.Config.Ip, c.Config.GrpcPort)
+	}
 	if c.Config.CacheTtl > 0.0 {
 		c.Ui.Infof("    Cache TTL:      [ %.2f seconds ]\n", c.Config.CacheTtl)
 	} else {
@@ -121,7 +273,39 @@ func (c *ServerCommand) startService() int {
 	if c.configFile != nil {
 		c.Ui.Infof("    Config File:    [ %s ]\n", c.configFile)
 	}
-	c.Ui.Infof("    Database File:  [ %s ]\n", c.Config.DbPath)
+	switch c.Config.Backend {
+	case mm.BackendUpstream:
+		c.Ui.Infof("    Upstream:       [ %s ]\n", c.Config.Upstream)
+	case mm.BackendCsv:
+		c.Ui.Infof("    Database CSV:   [ %s ]\n", c.Config.DbPath)
+	default:
+		c.Ui.Infof("    Database File:  [ %s ]\n", c.Config.DbPath)
+	}
+	for _, overlay := range c.Config.Overlays {
+		mode := overlay.Mode
+		if mode == "" {
+			mode = mm.OverlayOverride
+		}
+		c.Ui.Infof("    Overlay:        [ %s (%s) ]\n", overlay.Path, mode)
+	}
+	if c.Config.CanaryIp != "" {
+		c.Ui.Infof("    Canary:         [ %s => '%s' ]\n", c.Config.CanaryIp, c.Config.CanaryCountry)
+	}
+	if c.Config.DatabaseMaxAge > 0 {
+		c.Ui.Infof("    Database Age:   [ %.0f seconds max ]\n", c.Config.DatabaseMaxAge)
+	}
+	if c.Config.ApiKeysFile != "" {
+		c.Ui.Infof("    API Keys File:  [ %s ]\n", c.Config.ApiKeysFile)
+	}
+	if c.Config.AccessLogFormat != "" {
+		c.Ui.Infof("    Access Log:     [ %s to %s ]\n", c.Config.AccessLogFormat, c.Config.AccessLogOutput)
+	}
+	if len(c.Config.CorsOrigins) > 0 {
+		c.Ui.Infof("    CORS Origins:   [ %s ]\n", strings.Join(c.Config.CorsOrigins, ", "))
+	}
+	if c.Config.RateLimitKey != "" && c.Config.RateLimitRate > 0 {
+		c.Ui.Infof("    Rate Limit:     [ %.2f/s, burst %d, per %s ]\n", c.Config.RateLimitRate, c.Config.RateLimitBurst, c.Config.RateLimitKey)
+	}
 
 	c.serverStart = time.Now()
 	runtime.GOMAXPROCS(int(c.Config.Threads))
@@ -134,51 +318,241 @@ func (c *ServerCommand) startService() int {
 	}
 
 	var err error
-	c.database, err = mm.GetDatabase(c.Config.DbPath.Path())
+	c.provider, err = openProvider(c.Config)
 	if err != nil {
-		c.Ui.Fatal(err)
+		return err
 	}
 
-	defer c.database.Close()
+	defer c.provider.Close()
 
-	router := httprouter.New()
-	router.GET("/ping", c.aliveHandler)
-	router.HEAD("/ping", c.aliveHandler)
-	router.GET("/ip/:ip", c.IpLookupHandler)
+	if len(c.Config.Overlays) > 0 {
+		if c.layers, err = mm.NewLayeredDatabase(c.provider, c.Config.Overlays); err != nil {
+			return err
+		}
+		defer mm.CloseDatabases()
+	}
+
+	if c.Config.RateLimitKey != "" && c.Config.RateLimitRate > 0 {
+		c.rateLimiter = mm.NewRateLimiter(c.Config.RateLimitRate, int(c.Config.RateLimitBurst))
+	}
+
+	if c.Config.ApiKeysFile != "" {
+		c.apiKeys, err = mm.LoadApiKeys(mm.NewPathname(c.Config.ApiKeysFile))
+		if err != nil {
+			return &mm.ConfigError{Key: "auth.keys.file", Err: err}
+		}
+	}
+
+	if c.Config.GrpcPort > 0 {
+		grpcServer, grpcHealth, err := c.startGrpcService()
+		if err != nil {
+			return err
+		}
+		defer stopGrpcService(grpcServer, grpcHealth, grpcStopTimeout)
+	}
+
+	router := c.routes()
+
+	listeners, err := systemdListeners()
+	if err != nil {
+		return err
+	}
+
+	if len(listeners) > 0 {
+		c.Ui.Infof("Using %d socket(s) passed in by systemd\n", len(listeners))
+	} else {
+		socketMode, err := c.Config.SocketFileMode()
+		if err != nil {
+			return err
+		}
+
+		listeners, err = listenAll(c.Config.ListenAddresses(), socketMode)
+		if err != nil {
+			return err
+		}
+	}
+
+	var handler http.Handler = router
+	if len(c.Config.CorsOrigins) > 0 {
+		handler = &CorsHandler{
+			Router:  router,
+			Origins: c.Config.CorsOrigins,
+			Methods: c.Config.CorsMethods,
+			Headers: c.Config.CorsHeaders,
+			MaxAge:  c.Config.CorsMaxAge,
+		}
+	}
+
+	if c.Config.AccessLogFormat != "" {
+		c.accessLog, err = mm.NewAccessLog(c.Config.AccessLogFormat, c.Config.AccessLogOutput)
+		if err != nil {
+			return &mm.ConfigError{Key: "log.access.output", Err: err}
+		}
+		defer c.accessLog.Close()
+
+		handler = &AccessLogHandler{Handler: handler, Log: c.accessLog, Ui: c.Ui}
+	}
+
+	server := &http.Server{Handler: handler}
+	serveErrs := make(chan error, len(listeners))
+
+	for _, listener := range listeners {
+		c.Ui.Infof("Listening on %s ...\n", listener.Addr())
+		go func(listener net.Listener) {
+			serveErrs <- server.Serve(listener)
+		}(listener)
+	}
+
+	sdNotify("READY=1")
+
+	stopWatchdog := make(chan struct{})
+	if interval := sdWatchdogInterval(); interval > 0 {
+		go c.watchdog(interval, stopWatchdog)
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP, syscall.SIGUSR1)
+	defer signal.Stop(signals)
+
+serve:
+	for {
+		select {
+		case err = <-serveErrs:
+			break serve
+		case sig := <-signals:
+			switch sig {
+			case syscall.SIGHUP:
+				c.reloadDatabase()
+				c.reloadApiKeys()
+			case syscall.SIGUSR1:
+				c.reopenAccessLog()
+			default:
+				break serve
+			}
+		}
+	}
+
+	sdNotify("STOPPING=1")
+	close(stopWatchdog)
+
+	// closing the server closes the listeners, which unlinks unix sockets
+	server.Close()
+
+	return err
+}
+
+// reopenAccessLog reopens the access log file, e.g. after logrotate has
+// moved it aside.
+func (c *ServerCommand) reopenAccessLog() {
+	if c.accessLog == nil {
+		return
+	}
+
+	if err := c.accessLog.Reopen(); err != nil {
+		c.Ui.Errorf("failed to reopen access log: %s\n", err)
+	}
+}
+
+// reloadDatabase reopens the database file in place, e.g. after it has
+// been replaced by a newer release, and drops any cached responses.
+func (c *ServerCommand) reloadDatabase() {
+	sdNotify("RELOADING=1")
+	defer sdNotify("READY=1")
+
+	c.Ui.Infof("Reloading database %s ...\n", c.Config.DbPath)
+
+	var err error
+	if c.layers != nil {
+		err = c.layers.Reload()
+	} else if reloader, ok := c.provider.(mm.Reloader); ok {
+		err = reloader.Reload()
+	}
+	if err != nil {
+		c.Ui.Errorf("failed to reload database; continuing with the previous one. error was: %s\n", err)
+		return
+	}
 
-	address := fmt.Sprintf("%s:%d", c.Config.Ip, c.Config.Port)
-	c.Ui.Infof("Listening on %s ...\n", address)
-	c.Ui.Fatal(http.ListenAndServe(address, router))
+	if c.memCache != nil {
+		c.memCache.Flush()
+	}
 
-	return 0
+	c.Ui.Debugf("database reloaded; built %s\n", c.provider.Metadata().BuildTime.UTC().Format(time.RFC3339))
 }
 
 func (c *ServerCommand) Help() string {
 	return fmt.Sprintf(`Usage: %s server [options]
 
-Run as a caching HTTP server, responding to requests for /ip/:ip, and /ping.
+Run as a caching HTTP server, responding to requests for /ip/:ip, /batch, /info,
+and /ping.
 
 Options:
   -c, -config-file      <file>         File containing configuration. Note: Command
                                        line options override config file options.
   -i, -ip               <ip address>   IP Address to bind to (default: %s)
   -p, -port             <integer>      Port to bind to (default: %d)
-  -f, -database-file    <file>         Path to MaxMind Database
-                                       (default: %s)
+  -l, -listen           <address>      Address to listen on; either tcp://host:port
+                                       or unix:///path/to/socket. May be repeated,
+                                       and overrides -ip and -port when given.
+  -m, -socket-mode      <octal>        Permissions of unix sockets (default: %s)
+  -g, -grpc-port        <integer>      Port to serve the gRPC GeoIP service on;
+                                       0 disables it. (default: %d)
+  -b, -database.backend <string>       What lookups are answered from; one of mmdb,
+                                       csv (the CSV edition of a database, held in
+                                       memory), or upstream (default: %s)
+  -f, -database-file    <file>         Path to MaxMind Database, or directory of
+                                       its CSV edition with -b csv (default: %s)
+  -u, -database.upstream <url>         URL of the maxmind server the upstream backend
+                                       answers from
+      -database.upstream.api_key <key> API key sent to the upstream server
+  -o, -database.overlay <file>         MaxMind Database, or CSV file of networks,
+                                       whose records override the database's; may
+                                       be repeated, the first taking precedence.
+                                       Merging overlays are configured with
+                                       database.overlays in the config file.
   -t, -cache-ttl        <float>        How long to cache response data before
                                        refetching it from the database.
                                        (default: %.2f)
   -T, -worker-threads   <integer>      Number of worker threads to handle incoming
                                        requests. (default: %d)
-
-`, os.Args[0], c.Config.Ip, c.Config.Port, c.Config.DbPath, c.Config.CacheTtl, c.Config.Threads)
+  -health.canary.ip     <ip address>   IP looked up by /readyz to verify the database
+  -health.canary.country <iso code>    Country the canary IP is expected to resolve to
+  -health.database.max_age <float>     Maximum age, in seconds, of the database build
+                                       before /readyz fails; 0 disables. (default: %.0f)
+  -a, -auth-keys-file   <file>         File of API keys required to make requests;
+                                       reloaded on SIGHUP. (default: none)
+  -ratelimit.key        <string>       What requests are rate limited by; one of ip,
+                                       apikey, or global. Empty disables rate limiting.
+  -ratelimit.rate       <float>        Requests per second allowed per rate limit key
+  -ratelimit.burst      <integer>      Requests allowed in a burst above the rate
+  -cors.origins         <origin>       Origin allowed to make cross-origin requests,
+                                       or * for any. May be repeated. (default: none)
+  -cors.methods         <method>       Method allowed in cross-origin requests. May be
+                                       repeated. (default: %s)
+  -cors.headers         <header>       Request header allowed in cross-origin requests.
+                                       May be repeated. (default: %s)
+  -cors.max_age         <integer>      Seconds browsers may cache preflight responses
+                                       (default: %d)
+  -log.level            <string>       Minimum level of log messages; one of debug,
+                                       info, warn, or error. (default: info)
+  -log.format           <string>       Log message format; one of text, json, or
+                                       logfmt. (default: text)
+  -log.access.format    <string>       Access log format; one of json, common, or
+                                       combined. Empty disables it. (default: none)
+  -log.access.output    <string>       Where to write the access log; stdout, stderr,
+                                       syslog, or a file path which is reopened on
+                                       SIGUSR1. (default: %s)
+
+`, os.Args[0], c.Config.Ip, c.Config.Port, c.Config.SocketMode, c.Config.GrpcPort, c.Config.Backend, c.Config.DbPath, c.Config.CacheTtl, c.Config.Threads, c.Config.DatabaseMaxAge,
+		strings.Join(c.Config.CorsMethods, ", "), strings.Join(c.Config.CorsHeaders, ", "), c.Config.CorsMaxAge, c.Config.AccessLogOutput)
 }
 
 func (c *ServerCommand) Synopsis() string {
 	return "Run as a server"
 }
 
-func (c *ServerCommand) Run(args []string) int {
+// Execute runs the server until it is signalled to stop, returning an error
+// if it could not be configured or started, or stopped serving unexpectedly.
+func (c *ServerCommand) Execute(args []string) error {
 
 	var err error
 	var dbPath *mm.Pathname
@@ -193,34 +567,70 @@ func (c *ServerCommand) Run(args []string) int {
 	configParse.Parse(args)
 
 	if *configPath != "" {
-		if cfgPath, err := mm.NewPathname(*configPath).RealPath(); err == nil {
-			c.Config.LoadFromJsonFile(cfgPath)
-			c.configFile = cfgPath
-		} else {
-			c.Ui.Fatal(err)
+		cfgPath, err := mm.NewPathname(*configPath).RealPath()
+		if err != nil {
+			return err
+		}
+		if err = c.Config.LoadFromJsonFile(cfgPath); err != nil {
+			return err
 		}
+		c.configFile = cfgPath
 	}
 
 	mainParse := flag.NewFlagSet("server", flag.ContinueOnError)
 	_ = mainParse.String("config.file", "", "`path` to config file ")
 	ip := mainParse.String("server.ip", c.Config.Ip, "server `IP` address; empty to bind all interfaces")
 	port := mainParse.Int("server.port", int(c.Config.Port), "server `port`")
+	var listen ListFlag
+	mainParse.Var(&listen, "server.listen", "`address` to listen on, tcp://host:port or unix:///path; may be repeated")
+	socketMode := mainParse.String("server.socket.mode", c.Config.SocketMode, "octal `permissions` of unix sockets")
+	grpcPort := mainParse.Int("grpc.port", int(c.Config.GrpcPort), "gRPC server `port`; 0 disables the gRPC service")
+	backend := mainParse.String("database.backend", c.Config.Backend, "`backend` lookups are answered from; one of mmdb, csv, or upstream")
 	dbFile := mainParse.String("database.file", c.Config.DbPath.Path(), "`path` to the database file that contains GeoIP information")
+	upstream := mainParse.String("database.upstream", c.Config.Upstream, "`url` of the maxmind server the upstream backend answers from")
+	upstreamKey := mainParse.String("database.upstream.api_key", c.Config.UpstreamKey, "api `key` sent to the upstream server")
+	var overlays ListFlag
+	mainParse.Var(&overlays, "database.overlay", "`path` to a database, or csv file, overriding the records of the database; may be repeated")
 	cacheTtl := mainParse.Float64("cache.ttl", float64(c.Config.CacheTtl), "How many `seconds` should requests be cached. Set to 0 to disable")
+	canaryIp := mainParse.String("health.canary.ip", c.Config.CanaryIp, "`IP` looked up by /readyz to verify the database")
+	canaryCountry := mainParse.String("health.canary.country", c.Config.CanaryCountry, "ISO `code` of the country the canary IP should resolve to")
+	maxAge := mainParse.Float64("health.database.max_age", c.Config.DatabaseMaxAge, "maximum age, in `seconds`, of the database build; 0 disables")
+	keysFile := mainParse.String("auth.keys.file", c.Config.ApiKeysFile, "`path` to the api keys file; empty disables authentication")
+	limitKey := mainParse.String("ratelimit.key", c.Config.RateLimitKey, "what requests are rate limited `by`; one of ip, apikey, or global")
+	limitRate := mainParse.Float64("ratelimit.rate", c.Config.RateLimitRate, "`requests` per second allowed per rate limit key")
+	limitBurst := mainParse.Int("ratelimit.burst", int(c.Config.RateLimitBurst), "`requests` allowed in a burst above the rate")
+	var corsOrigins, corsMethods, corsHeaders ListFlag
+	mainParse.Var(&corsOrigins, "cors.origins", "`origin` allowed to make cross-origin requests, or * for any; may be repeated")
+	mainParse.Var(&corsMethods, "cors.methods", "`method` allowed in cross-origin requests; may be repeated")
+	mainParse.Var(&corsHeaders, "cors.headers", "request `header` allowed in cross-origin requests; may be repeated")
+	corsMaxAge := mainParse.Int("cors.max_age", int(c.Config.CorsMaxAge), "`seconds` browsers may cache preflight responses")
+	logLevel := mainParse.String("log.level", c.Config.LogLevel, "minimum `level` of log messages; one of debug, info, warn, or error")
+	logFormat := mainParse.String("log.format", c.Config.LogFormat, "log message `format`; one of text, json, or logfmt")
+	accessFormat := mainParse.String("log.access.format", c.Config.AccessLogFormat, "access log `format`; one of json, common, or combined")
+	accessOutput := mainParse.String("log.access.output", c.Config.AccessLogOutput, "access log `output`; stdout, stderr, syslog, or a file path")
 	threads := mainParse.Int("worker.threads", int(c.Config.Threads), "Number of `threads` to use. Defaults to number of detected cores")
 
 	mainParse.StringVar(configPath, "c", "", "`path` to config file ")
 	mainParse.StringVar(ip, "i", c.Config.Ip, "server `IP` address; empty to bind all interfaces")
 	mainParse.IntVar(port, "p", int(c.Config.Port), "server `port`")
+	mainParse.Var(&listen, "l", "`address` to listen on, tcp://host:port or unix:///path; may be repeated")
+	mainParse.StringVar(socketMode, "m", c.Config.SocketMode, "octal `permissions` of unix sockets")
+	mainParse.IntVar(grpcPort, "g", int(c.Config.GrpcPort), "gRPC server `port`; 0 disables the gRPC service")
+	mainParse.StringVar(backend, "b", c.Config.Backend, "`backend` lookups are answered from; one of mmdb, csv, or upstream")
 	mainParse.StringVar(dbFile, "f", c.Config.DbPath.Path(), "`path` to the database file that contains GeoIP information")
+	mainParse.StringVar(upstream, "u", c.Config.Upstream, "`url` of the maxmind server the upstream backend answers from")
+	mainParse.Var(&overlays, "o", "`path` to a database, or csv file, overriding the records of the database; may be repeated")
 	mainParse.Float64Var(cacheTtl, "t", float64(c.Config.CacheTtl), "How many `seconds` should requests be cached. Set to 0 to disable")
+	mainParse.StringVar(keysFile, "a", c.Config.ApiKeysFile, "`path` to the api keys file; empty disables authentication")
 	mainParse.IntVar(threads, "T", int(c.Config.Threads), "Number of `threads` to use. Defaults to number of detected cores")
 
 	mainParse.Usage = func() {
 		c.Ui.Output(c.Help())
 		mainParse.PrintDefaults()
 	}
-	mainParse.Parse(args)
+	if err = mainParse.Parse(args); err != nil {
+		return usageError(err)
+	}
 
 	if *ip != c.Config.Ip {
 		c.Config.Ip = *ip
@@ -228,36 +638,141 @@ func (c *ServerCommand) Run(args []string) int {
 	if uint32(*port) != c.Config.Port {
 		c.Config.Port = uint32(*port)
 	}
+	if len(listen) > 0 {
+		c.Config.Listen = listen
+	}
+	if *socketMode != c.Config.SocketMode {
+		c.Config.SocketMode = *socketMode
+	}
+	if uint32(*grpcPort) != c.Config.GrpcPort {
+		c.Config.GrpcPort = uint32(*grpcPort)
+	}
+	if *backend != c.Config.Backend {
+		c.Config.Backend = *backend
+	}
+	if *upstream != c.Config.Upstream {
+		c.Config.Upstream = *upstream
+	}
+	if *upstreamKey != c.Config.UpstreamKey {
+		c.Config.UpstreamKey = *upstreamKey
+	}
+	if len(overlays) > 0 {
+		c.Config.Overlays = nil
+		for _, overlay := range overlays {
+			c.Config.Overlays = append(c.Config.Overlays, &mm.Overlay{Path: mm.NewPathname(overlay), Mode: mm.OverlayOverride})
+		}
+	}
 	if float64(*cacheTtl) != c.Config.CacheTtl {
 		c.Config.CacheTtl = float64(*cacheTtl)
 	}
+	if *canaryIp != c.Config.CanaryIp {
+		c.Config.CanaryIp = *canaryIp
+	}
+	if *canaryCountry != c.Config.CanaryCountry {
+		c.Config.CanaryCountry = *canaryCountry
+	}
+	if *maxAge != c.Config.DatabaseMaxAge {
+		c.Config.DatabaseMaxAge = *maxAge
+	}
+	if *keysFile != c.Config.ApiKeysFile {
+		c.Config.ApiKeysFile = *keysFile
+	}
+	if *limitKey != c.Config.RateLimitKey {
+		c.Config.RateLimitKey = *limitKey
+	}
+	if *limitRate != c.Config.RateLimitRate {
+		c.Config.RateLimitRate = *limitRate
+	}
+	if uint32(*limitBurst) != c.Config.RateLimitBurst {
+		c.Config.RateLimitBurst = uint32(*limitBurst)
+	}
+	if len(corsOrigins) > 0 {
+		c.Config.CorsOrigins = corsOrigins
+	}
+	if len(corsMethods) > 0 {
+		c.Config.CorsMethods = corsMethods
+	}
+	if len(corsHeaders) > 0 {
+		c.Config.CorsHeaders = corsHeaders
+	}
+	if uint32(*corsMaxAge) != c.Config.CorsMaxAge {
+		c.Config.CorsMaxAge = uint32(*corsMaxAge)
+	}
+	if *logLevel != c.Config.LogLevel {
+		c.Config.LogLevel = *logLevel
+	}
+	if *logFormat != c.Config.LogFormat {
+		c.Config.LogFormat = *logFormat
+	}
+	if *accessFormat != c.Config.AccessLogFormat {
+		c.Config.AccessLogFormat = *accessFormat
+	}
+	if *accessOutput != c.Config.AccessLogOutput {
+		c.Config.AccessLogOutput = *accessOutput
+	}
 	if uint8(*threads) != c.Config.Threads {
 		c.Config.Threads = uint8(*threads)
 	}
 
+	if c.Config.LogLevel != "" || c.Config.LogFormat != "" {
+		level, err := ParseLogLevel(c.Config.LogLevel)
+		if c.Config.LogLevel == "" {
+			level, err = LevelInfo, nil
+		}
+		if err != nil {
+			return &mm.ConfigError{Key: "log.level", Err: err}
+		}
+
+		format := c.Config.LogFormat
+		if format == "" {
+			format = "text"
+		} else if !LogFormats[format] {
+			return &mm.ConfigError{Key: "log.format", Err: fmt.Errorf("'%s' is not one of 'text', 'json', or 'logfmt'", format)}
+		}
+
+		c.Ui = NewStructuredUi(os.Stdout, os.Stderr, format, level)
+	}
+
 	if c.Config.Threads < 1 {
-		c.Ui.Fatal("Worker threads must be at least 1!")
+		return &mm.ConfigError{Key: "worker.threads", Err: errors.New("worker threads must be at least 1")}
 	}
 
-	if *dbFile == "" {
-		c.Ui.Fatal("missing required path to MasterMind DB file")
-	} else {
-		dbPath, err = mm.NewPathname(*dbFile).RealPath()
-		if err != nil {
-			c.Ui.Fatal(err)
-		}
+	if c.Config.AccessLogFormat != "" && !mm.AccessLogFormats[c.Config.AccessLogFormat] {
+		return &mm.ConfigError{Key: "log.access.format", Err: fmt.Errorf("'%s' is not one of 'json', 'common', or 'combined'", c.Config.AccessLogFormat)}
+	}
+
+	if c.Config.RateLimitKey != "" && !mm.RateLimitKeys[c.Config.RateLimitKey] {
+		return &mm.ConfigError{Key: "ratelimit.key", Err: fmt.Errorf("'%s' is not one of 'ip', 'apikey', or 'global'", c.Config.RateLimitKey)}
+	}
+
+	if c.Config.Backend != "" && !mm.Backends[c.Config.Backend] {
+		return &mm.ConfigError{Key: "database.backend", Err: fmt.Errorf("'%s' is not one of 'mmdb', 'csv', or 'upstream'", c.Config.Backend)}
 	}
 
-	if *dbFile == "" {
-		c.Ui.Fatal("missing required path to MasterMind DB file")
+	if c.Config.Backend == mm.BackendUpstream {
+		if c.Config.Upstream == "" {
+			return usageError(errors.New("missing required url of the upstream server"))
+		}
 	} else {
+		if *dbFile == "" {
+			return usageError(errors.New("missing required path to MasterMind DB file"))
+		}
+
 		// normalize the database file path
-		dbPath, err = mm.NewPathname(*dbFile).RealPath()
-		if err != nil {
-			c.Ui.Fatal(err)
+		if dbPath, err = mm.NewPathname(*dbFile).RealPath(); err != nil {
+			return err
 		}
 		c.Config.DbPath = dbPath
 	}
 
+	for _, overlay := range c.Config.Overlays {
+		if overlay.Path == nil {
+			continue
+		}
+		if overlay.Path, err = overlay.Path.RealPath(); err != nil {
+			return err
+		}
+	}
+
 	return c.startService()
 }
@@ -0,0 +1,492 @@
+package command
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pmylund/go-cache"
+	"github.com/rabbitt/maxmind/client"
+	"github.com/rabbitt/maxmind/mm"
+	"github.com/rabbitt/maxmind/mmdb"
+)
+
+// buildFixture writes the database described by the named file of
+// testdata into a temporary directory, returning its path.
+func buildFixture(t *testing.T, name string) string {
+	path := filepath.Join(t.TempDir(), name+".mmdb")
+	if err := mmdb.BuildFixture(filepath.Join("..", "testdata", name), path); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// executor is what the tests run of a command.
+type executor interface {
+	Execute(args []string) error
+}
+
+// runCommand executes the command newCommand creates, with args, returning
+// what it output; it is given a Ui capturing the output.
+func runCommand(newCommand func(ui Ui) executor, args ...string) (string, error) {
+	var output bytes.Buffer
+	err := newCommand(&BaseUi{Writer: &output, ErrorWriter: &output}).Execute(args)
+	return output.String(), err
+}
+
+// mustRunCommand is runCommand for commands that must succeed.
+func mustRunCommand(t *testing.T, newCommand func(ui Ui) executor, args ...string) string {
+	output, err := runCommand(newCommand, args...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return output
+}
+
+// testServer returns a server answering from the City test fixture, with
+// its output captured in ui.
+func testServer(t *testing.T) (*ServerCommand, *bytes.Buffer) {
+	database, err := mm.OpenDatabase(buildFixture(t, "GeoIP2-City-Test.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(database.Close)
+
+	var output bytes.Buffer
+	return &ServerCommand{
+		Config:      mm.NewConfiguration(),
+		provider:    database,
+		serverStart: time.Now(),
+		Ui:          &BaseUi{Writer: &output, ErrorWriter: &output},
+	}, &output
+}
+
+func serve(c *ServerCommand, method string, target string, body string, header http.Header) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	for key, values := range header {
+		req.Header[key] = values
+	}
+
+	recorder := httptest.NewRecorder()
+	c.routes().ServeHTTP(recorder, req)
+	return recorder
+}
+
+func decodeBody(t *testing.T, recorder *httptest.ResponseRecorder, v interface{}) {
+	if err := json.Unmarshal(recorder.Body.Bytes(), v); err != nil {
+		t.Fatalf("unable to decode response %q: %s", recorder.Body.String(), err)
+	}
+}
+
+func TestIpLookupHandler(t *testing.T) {
+	c, _ := testServer(t)
+
+	recorder := serve(c, "GET", "/ip/81.2.69.142", "", nil)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status = %d", recorder.Code)
+	}
+
+	var response mm.JsonResponse
+	decodeBody(t, recorder, &response)
+	if response.Status != "success" || response.Data.City.Name != "London" || response.Data.Country.IsoCode != "GB" {
+		t.Errorf("unexpected response %s", recorder.Body)
+	}
+
+	recorder = serve(c, "GET", "/ip/10.0.0.1", "", nil)
+	response = mm.JsonResponse{}
+	decodeBody(t, recorder, &response)
+	if recorder.Code != http.StatusOK || response.Status != "success" || !response.Data.Unknown() {
+		t.Errorf("unknown ip: %d %s", recorder.Code, recorder.Body)
+	}
+
+	recorder = serve(c, "GET", "/ip/not-an-ip", "", nil)
+	response = mm.JsonResponse{}
+	decodeBody(t, recorder, &response)
+	if recorder.Code != http.StatusUnprocessableEntity || response.Status != "error" {
+		t.Errorf("invalid ip: %d %s", recorder.Code, recorder.Body)
+	}
+}
+
+func TestIpLookupHandlerGeoJson(t *testing.T) {
+	c, _ := testServer(t)
+
+	recorder := serve(c, "GET", "/ip/2001:218::1", "", http.Header{"Accept": {mm.GeoJsonMimeType}})
+	if got := recorder.Header().Get("Content-Type"); got != mm.GeoJsonMimeType {
+		t.Errorf("Content-Type = %s", got)
+	}
+
+	var feature struct {
+		Type     string `json:"type"`
+		Geometry struct {
+			Coordinates []float64 `json:"coordinates"`
+		} `json:"geometry"`
+	}
+	decodeBody(t, recorder, &feature)
+	if feature.Type != "Feature" || len(feature.Geometry.Coordinates) != 2 || feature.Geometry.Coordinates[0] != 139.75309 {
+		t.Errorf("unexpected feature %s", recorder.Body)
+	}
+}
+
+func TestIpLookupHandlerCache(t *testing.T) {
+	c, _ := testServer(t)
+	c.memCache = cache.New(time.Minute, time.Minute)
+
+	for _, want := range []string{"MISS", "HIT"} {
+		recorder := serve(c, "GET", "/ip/175.16.199.1", "", nil)
+		if got := recorder.Header().Get("X-Cache"); got != want {
+			t.Errorf("X-Cache = %s; want %s", got, want)
+		}
+		if !strings.Contains(recorder.Body.String(), "Changchun") {
+			t.Errorf("unexpected response %s", recorder.Body)
+		}
+	}
+}
+
+func TestBatchHandler(t *testing.T) {
+	c, _ := testServer(t)
+
+	recorder := serve(c, "POST", "/batch", `{"ips": ["89.160.20.113", "bogus", "10.0.0.1"]}`, nil)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", recorder.Code, recorder.Body)
+	}
+
+	var response mm.BatchResponse
+	decodeBody(t, recorder, &response)
+	if len(response.Results) != 3 {
+		t.Fatalf("unexpected response %s", recorder.Body)
+	}
+	if result := response.Results[0]; result.Status != "success" || result.Data.Subdivision.IsoCode != "LI" {
+		t.Errorf("unexpected result %+v", result)
+	}
+	if result := response.Results[1]; result.Status != "error" || result.Ip != "bogus" {
+		t.Errorf("unexpected result %+v", result)
+	}
+	if result := response.Results[2]; result.Status != "success" || !result.Data.Unknown() {
+		t.Errorf("unexpected result %+v", result)
+	}
+
+	for body, code := range map[string]int{
+		`{"ips": []}`: http.StatusBadRequest,
+		`not json`:    http.StatusBadRequest,
+		`{"ips": [` + strings.Repeat(`"1.1.1.1",`, mm.MaxBatchSize) + `"1.1.1.1"]}`: http.StatusRequestEntityTooLarge,
+	} {
+		if recorder = serve(c, "POST", "/batch", body, nil); recorder.Code != code {
+			t.Errorf("status = %d; want %d", recorder.Code, code)
+		}
+	}
+}
+
+func TestInfoHandler(t *testing.T) {
+	c, _ := testServer(t)
+
+	recorder := serve(c, "GET", "/info", "", nil)
+
+	var info mm.ServerInfo
+	decodeBody(t, recorder, &info)
+	if info.Database == nil || info.Database.Type != "GeoIP2-City" || info.Database.IpVersion != 6 {
+		t.Errorf("unexpected info %s", recorder.Body)
+	}
+}
+
+func TestHealthHandlers(t *testing.T) {
+	c, _ := testServer(t)
+
+	if recorder := serve(c, "GET", "/ping", "", nil); recorder.Body.String() != "pong" {
+		t.Errorf("/ping = %q", recorder.Body)
+	}
+	if recorder := serve(c, "GET", "/healthz", "", nil); recorder.Code != http.StatusOK || recorder.Body.String() != "ok" {
+		t.Errorf("/healthz = %d %q", recorder.Code, recorder.Body)
+	}
+
+	c.Config.CanaryIp, c.Config.CanaryCountry = "81.2.69.142", "GB"
+	if recorder := serve(c, "GET", "/readyz", "", nil); recorder.Code != http.StatusOK {
+		t.Errorf("/readyz = %d %s", recorder.Code, recorder.Body)
+	}
+
+	c.Config.CanaryCountry = "US"
+	if recorder := serve(c, "GET", "/readyz", "", nil); recorder.Code != http.StatusServiceUnavailable ||
+		!strings.Contains(recorder.Body.String(), "canary") {
+		t.Errorf("/readyz with a failing canary = %d %s", recorder.Code, recorder.Body)
+	}
+
+	// the fixture was built in 2018
+	c.Config.CanaryIp = ""
+	c.Config.DatabaseMaxAge = 86400
+	if recorder := serve(c, "GET", "/readyz", "", nil); recorder.Code != http.StatusServiceUnavailable {
+		t.Errorf("/readyz with a stale database = %d %s", recorder.Code, recorder.Body)
+	}
+}
+
+func TestAuthentication(t *testing.T) {
+	c, _ := testServer(t)
+
+	keysFile := filepath.Join(t.TempDir(), "keys.json")
+	keys := `{"keys": [
+		{"name": "lookups", "key": "secret", "routes": ["/ip/:ip"]},
+		{"name": "admin", "key": "admin", "admin": true},
+		{"name": "reports", "key": "reports"}
+	]}`
+	if err := os.WriteFile(keysFile, []byte(keys), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	var err error
+	if c.apiKeys, err = mm.LoadApiKeys(mm.NewPathname(keysFile)); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range []struct {
+		target string
+		header http.Header
+		code   int
+	}{
+		{"/ip/81.2.69.142", nil, http.StatusUnauthorized},
+		{"/ip/81.2.69.142", http.Header{"X-Api-Key": {"wrong"}}, http.StatusUnauthorized},
+		{"/ip/81.2.69.142", http.Header{"Authorization": {"Bearer secret"}}, http.StatusOK},
+		{"/info", http.Header{"X-Api-Key": {"secret"}}, http.StatusForbidden},
+		{"/info", http.Header{"X-Api-Key": {"admin"}}, http.StatusOK},
+		{"/ping", nil, http.StatusOK},
+	} {
+		if recorder := serve(c, "GET", test.target, "", test.header); recorder.Code != test.code {
+			t.Errorf("%s with %v = %d; want %d", test.target, test.header, recorder.Code, test.code)
+		}
+	}
+
+	var usage []mm.ApiKeyUsage
+	decodeBody(t, serve(c, "GET", "/usage", "", http.Header{"X-Api-Key": {"admin"}}), &usage)
+	if len(usage) != 3 {
+		t.Errorf("unexpected admin usage %+v", usage)
+	}
+	for _, key := range usage {
+		if key.Name == "lookups" && (key.Requests != 2 || key.Rejected != 1) {
+			t.Errorf("unexpected usage %+v", key)
+		}
+	}
+
+	// other keys only see their own usage
+	decodeBody(t, serve(c, "GET", "/usage", "", http.Header{"X-Api-Key": {"reports"}}), &usage)
+	if len(usage) != 1 || usage[0].Name != "reports" || usage[0].Requests != 1 {
+		t.Errorf("unexpected usage %+v", usage)
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	c, _ := testServer(t)
+	c.Config.RateLimitKey = mm.RateLimitGlobal
+	c.rateLimiter = mm.NewRateLimiter(0.001, 2)
+
+	for idx, code := range []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests} {
+		recorder := serve(c, "GET", "/ip/81.2.69.142", "", nil)
+		if recorder.Code != code {
+			t.Errorf("request %d = %d; want %d", idx, recorder.Code, code)
+		}
+		if code == http.StatusTooManyRequests && recorder.Header().Get("Retry-After") == "" {
+			t.Error("rate limited response has no Retry-After")
+		}
+	}
+}
+
+func TestRateLimitByApiKey(t *testing.T) {
+	c, _ := testServer(t)
+	c.Config.RateLimitKey = mm.RateLimitByApiKey
+	c.rateLimiter = mm.NewRateLimiter(0.001, 2)
+
+	// made up keys are counted against the client IP
+	for idx, code := range []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests} {
+		header := http.Header{"X-Api-Key": {strings.Repeat("x", idx+1)}}
+		if recorder := serve(c, "GET", "/ip/81.2.69.142", "", header); recorder.Code != code {
+			t.Errorf("request %d = %d; want %d", idx, recorder.Code, code)
+		}
+	}
+}
+
+func TestRateLimitBatch(t *testing.T) {
+	c, _ := testServer(t)
+	c.Config.RateLimitKey = mm.RateLimitGlobal
+	c.rateLimiter = mm.NewRateLimiter(0.001, 3)
+
+	// each ip of a batch costs a token
+	batch := `{"ips": ["81.2.69.142", "2001:218::1"]}`
+	for idx, code := range []int{http.StatusOK, http.StatusTooManyRequests} {
+		if recorder := serve(c, "POST", "/batch", batch, nil); recorder.Code != code {
+			t.Errorf("batch %d = %d; want %d", idx, recorder.Code, code)
+		}
+	}
+}
+
+func TestApiKeyCharges(t *testing.T) {
+	c, _ := testServer(t)
+
+	keysFile := filepath.Join(t.TempDir(), "keys.json")
+	if err := os.WriteFile(keysFile, []byte(`{"keys": [{"key": "secret", "rate": 0.001, "burst": 150}]}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	var err error
+	if c.apiKeys, err = mm.LoadApiKeys(mm.NewPathname(keysFile)); err != nil {
+		t.Fatal(err)
+	}
+	header := http.Header{"X-Api-Key": {"secret"}}
+
+	// a listing of networks costs networksCost, and each ip of a batch one
+	ips := make([]string, 50)
+	for idx := range ips {
+		ips[idx] = `"81.2.69.142"`
+	}
+	batch := `{"ips": [` + strings.Join(ips, ",") + `]}`
+	for idx, test := range []struct {
+		method, target, body string
+		code                 int
+	}{
+		{"GET", "/networks?country=GB", "", http.StatusOK},
+		{"POST", "/batch", batch, http.StatusOK},
+		{"POST", "/batch", batch, http.StatusTooManyRequests},
+	} {
+		if recorder := serve(c, test.method, test.target, test.body, header); recorder.Code != test.code {
+			t.Errorf("%d: %s %s = %d; want %d", idx, test.method, test.target, recorder.Code, test.code)
+		}
+	}
+}
+
+func TestOverlayOrigins(t *testing.T) {
+	c, _ := testServer(t)
+	t.Cleanup(mm.CloseDatabases)
+
+	overlay := filepath.Join(t.TempDir(), "vpn.csv")
+	if err := os.WriteFile(overlay, []byte("network,country,city\n81.2.69.142/31,US,Ashburn\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var err error
+	c.layers, err = mm.NewLayeredDatabase(c.provider, []*mm.Overlay{{Path: mm.NewPathname(overlay), Mode: mm.OverlayMerge}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var response mm.JsonResponse
+	decodeBody(t, serve(c, "GET", "/ip/81.2.69.142", "", nil), &response)
+	if response.Data.City.Name != "Ashburn" || response.Origins["city.name"] != "vpn" ||
+		response.Origins["location.time_zone"] != c.layers.Layers[1].Name {
+		t.Errorf("unexpected response %+v", response)
+	}
+
+	var batch mm.BatchResponse
+	decodeBody(t, serve(c, "POST", "/batch", `{"ips": ["81.2.69.142", "10.0.0.1"]}`, nil), &batch)
+	if len(batch.Results) != 2 || batch.Results[0].Origins["country.iso_code"] != "vpn" || batch.Results[1].Origins != nil {
+		t.Errorf("unexpected response %+v", batch)
+	}
+}
+
+func TestUpstreamBackend(t *testing.T) {
+	upstream, _ := testServer(t)
+	server := httptest.NewServer(upstream.routes())
+	defer server.Close()
+
+	config := mm.NewConfiguration()
+	config.Backend, config.Upstream = mm.BackendUpstream, server.URL
+	provider, err := openProvider(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer provider.Close()
+
+	c, _ := testServer(t)
+	c.provider = provider
+
+	var response mm.JsonResponse
+	decodeBody(t, serve(c, "GET", "/ip/81.2.69.142", "", nil), &response)
+	if response.Data.City.Name != "London" {
+		t.Errorf("unexpected response %+v", response.Data)
+	}
+
+	response = mm.JsonResponse{}
+	decodeBody(t, serve(c, "GET", "/ip/10.0.0.1", "", nil), &response)
+	if response.Status != "success" || !response.Data.Unknown() {
+		t.Errorf("unexpected response %+v", response)
+	}
+
+	var info mm.ServerInfo
+	decodeBody(t, serve(c, "GET", "/info", "", nil), &info)
+	if info.Database == nil || info.Database.Type != "GeoIP2-City" {
+		t.Errorf("unexpected info %+v", info)
+	}
+}
+
+func TestUpstreamBackendErrors(t *testing.T) {
+	var status int
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/info" {
+			writer.Write([]byte(`{"database": {"type": "GeoIP2-City"}}`))
+			return
+		}
+		if status == 0 {
+			time.Sleep(100 * time.Millisecond)
+			return
+		}
+		writeJsonError(writer, status, "upstream failed")
+	}))
+	defer server.Close()
+
+	upstream, err := client.NewUpstream(server.URL, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer upstream.Close()
+	upstream.Client.(*client.HttpClient).Retries = 0
+	upstream.Client.(*client.HttpClient).Timeout = 10 * time.Millisecond
+
+	c, _ := testServer(t)
+	c.provider = upstream
+	c.memCache = cache.New(time.Minute, time.Minute)
+
+	for _, test := range []struct {
+		status int
+		want   int
+	}{
+		{http.StatusInternalServerError, http.StatusBadGateway},
+		{http.StatusServiceUnavailable, http.StatusServiceUnavailable},
+		{http.StatusGatewayTimeout, http.StatusGatewayTimeout},
+		{0, http.StatusGatewayTimeout},
+	} {
+		status = test.status
+
+		var response mm.JsonResponse
+		recorder := serve(c, "GET", "/ip/81.2.69.142", "", nil)
+		decodeBody(t, recorder, &response)
+		if recorder.Code != test.want || response.Status != "error" || response.Data != nil {
+			t.Errorf("upstream %d = %d %s; want %d", test.status, recorder.Code, recorder.Body, test.want)
+		}
+		if c.memCache.ItemCount() != 0 {
+			t.Errorf("upstream %d: the error was cached", test.status)
+		}
+	}
+}
+
+func TestOpenProviderErrors(t *testing.T) {
+	var configErr *mm.ConfigError
+	var dbErr *mm.DatabaseError
+
+	for _, test := range []struct {
+		backend  string
+		upstream string
+		target   interface{}
+	}{
+		{"sqlite", "", &configErr},
+		{mm.BackendUpstream, "", &configErr},
+		{mm.BackendUpstream, "ftp://example.com", &dbErr},
+		{mm.BackendCsv, "", &dbErr},
+	} {
+		config := mm.NewConfiguration()
+		config.Backend, config.Upstream = test.backend, test.upstream
+		config.DbPath = mm.NewPathname(t.TempDir())
+		if _, err := openProvider(config); !errors.As(err, test.target) {
+			t.Errorf("openProvider(%s, %q) = %v; want a %T", test.backend, test.upstream, err, test.target)
+		}
+	}
+}
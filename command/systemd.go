@@ -0,0 +1,121 @@
+package command
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rabbitt/maxmind/mm"
+)
+
+// systemd passes inherited sockets starting at this file descriptor; see
+// sd_listen_fds(3).
+const listenFdsStart = 3
+
+// systemdListeners returns the listeners passed in by systemd socket
+// activation, or nil when the process was not socket activated.
+func systemdListeners() ([]net.Listener, error) {
+	defer os.Unsetenv("LISTEN_PID")
+	defer os.Unsetenv("LISTEN_FDS")
+	defer os.Unsetenv("LISTEN_FDNAMES")
+
+	pid, err := strconv.Atoi(os.Getenv("LISTEN_PID"))
+	if err != nil || pid != os.Getpid() {
+		return nil, nil
+	}
+
+	count, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || count <= 0 {
+		return nil, nil
+	}
+
+	names := strings.Split(os.Getenv("LISTEN_FDNAMES"), ":")
+
+	listeners := make([]net.Listener, 0, count)
+	for idx := 0; idx < count; idx++ {
+		name := fmt.Sprintf("LISTEN_FD_%d", listenFdsStart+idx)
+		if idx < len(names) && names[idx] != "" {
+			name = names[idx]
+		}
+
+		file := os.NewFile(uintptr(listenFdsStart+idx), name)
+		listener, err := net.FileListener(file)
+		// FileListener dups the descriptor, so the original is closed either way
+		file.Close()
+		if err != nil {
+			for _, l := range listeners {
+				l.Close()
+			}
+			return nil, fmt.Errorf("unable to use inherited socket %s: %s", name, err)
+		}
+		listeners = append(listeners, listener)
+	}
+
+	return listeners, nil
+}
+
+// sdNotify sends state to the service manager, as described in
+// sd_notify(3). It is a no-op when not running under systemd.
+func sdNotify(state string) error {
+	socketPath := os.Getenv("NOTIFY_SOCKET")
+	if socketPath == "" {
+		return nil
+	}
+
+	// a leading @ denotes a socket in the abstract namespace
+	if strings.HasPrefix(socketPath, "@") {
+		socketPath = "\x00" + socketPath[1:]
+	}
+
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socketPath, Net: "unixgram"})
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	_, err = conn.Write([]byte(state))
+	return err
+}
+
+// sdWatchdogInterval returns how often the watchdog should be pinged, which
+// is half the timeout systemd was configured with, or zero if the watchdog
+// is disabled for this process.
+func sdWatchdogInterval() time.Duration {
+	usec, err := strconv.ParseInt(os.Getenv("WATCHDOG_USEC"), 10, 64)
+	if err != nil || usec <= 0 {
+		return 0
+	}
+
+	if pidText := os.Getenv("WATCHDOG_PID"); pidText != "" {
+		if pid, err := strconv.Atoi(pidText); err != nil || pid != os.Getpid() {
+			return 0
+		}
+	}
+
+	return time.Duration(usec) * time.Microsecond / 2
+}
+
+// watchdog pings the systemd watchdog every interval for as long as the
+// database is still answering lookups, so that a wedged process is
+// restarted rather than left running.
+func (c *ServerCommand) watchdog(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			// any address will do; only the ability to complete a lookup matters
+			if _, err := mm.LookupText(c.provider, "127.0.0.1"); err != nil {
+				c.Ui.Errorf("watchdog lookup failed: %s\n", err)
+				continue
+			}
+			sdNotify("WATCHDOG=1")
+		}
+	}
+}
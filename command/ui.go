@@ -5,7 +5,6 @@ package command
 import (
 	"fmt"
 	"io"
-	"os"
 	"sync"
 )
 
@@ -28,14 +27,15 @@ type Ui interface {
 	Error(...interface{})
 	Errorf(string, ...interface{})
 
-	// Fatal are like Error, but exit immediately after rendering their message
-	Fatal(...interface{})
-	Fatalf(string, ...interface{})
-
 	// Warn is used for any warning messages that might appear on standard
 	// error.
 	Warn(...interface{})
 	Warnf(string, ...interface{})
+
+	// Debug is used for diagnostic messages that are only of interest when
+	// troubleshooting. Ui implementors may discard them.
+	Debug(...interface{})
+	Debugf(string, ...interface{})
 }
 
 // BaseUi is an implementation of Ui that just outputs to the given
@@ -47,6 +47,11 @@ type BaseUi struct {
 	locker      sync.Mutex
 }
 
+// Debug messages are discarded by BaseUi; use a StructuredUi to render them.
+func (u *BaseUi) Debug(v ...interface{}) {}
+
+func (u *BaseUi) Debugf(format string, v ...interface{}) {}
+
 func (u *BaseUi) Error(v ...interface{}) {
 	w := u.Writer
 	if u.ErrorWriter != nil {
@@ -72,16 +77,6 @@ func (u *BaseUi) Errorf(format string, v ...interface{}) {
 	fmt.Fprintf(w, format, v...)
 }
 
-func (u *BaseUi) Fatal(v ...interface{}) {
-	u.Error(v...)
-	os.Exit(1)
-}
-
-func (u *BaseUi) Fatalf(format string, v ...interface{}) {
-	u.Errorf(format, v...)
-	os.Exit(1)
-}
-
 func (u *BaseUi) Info(v ...interface{}) {
 	u.Output(v...)
 }
@@ -127,20 +122,20 @@ func (u *PrefixedUi) PrependPrefix(prefix string, args []interface{}) []interfac
 	return newArgs
 }
 
-func (u *PrefixedUi) Error(v ...interface{}) {
-	u.Ui.Error(u.PrependPrefix("ERROR: ", v)...)
+func (u *PrefixedUi) Debug(v ...interface{}) {
+	u.Ui.Debug(u.PrependPrefix("DEBUG: ", v)...)
 }
 
-func (u *PrefixedUi) Errorf(format string, v ...interface{}) {
-	u.Ui.Errorf(fmt.Sprintf("ERROR: %s", format), v...)
+func (u *PrefixedUi) Debugf(format string, v ...interface{}) {
+	u.Ui.Debugf(fmt.Sprintf("DEBUG: %s", format), v...)
 }
 
-func (u *PrefixedUi) Fatal(v ...interface{}) {
-	u.Ui.Fatal(u.PrependPrefix("FATAL: ", v)...)
+func (u *PrefixedUi) Error(v ...interface{}) {
+	u.Ui.Error(u.PrependPrefix("ERROR: ", v)...)
 }
 
-func (u *PrefixedUi) Fatalf(format string, v ...interface{}) {
-	u.Ui.Fatalf(fmt.Sprintf("FATAL: %s", format), v...)
+func (u *PrefixedUi) Errorf(format string, v ...interface{}) {
+	u.Ui.Errorf(fmt.Sprintf("ERROR: %s", format), v...)
 }
 
 func (u *PrefixedUi) Info(v ...interface{}) {
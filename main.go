@@ -1,6 +1,7 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"os"
 
@@ -9,25 +10,100 @@ import (
 	"github.com/rabbitt/maxmind/mm"
 )
 
+// exit codes, as defined by sysexits(3)
+const (
+	exitFailure = 1
+	exitUsage   = 64
+	exitDataErr = 65
+	exitNoInput = 66
+	exitConfig  = 78
+)
+
+// executor is implemented by the commands, which report failures as errors
+// rather than exiting, so that they can be embedded in other programs.
+type executor interface {
+	Help() string
+	Synopsis() string
+	Execute(args []string) error
+}
+
+// runner adapts an executor to cli.Command, rendering the error it returns
+// and mapping it to an exit code.
+type runner struct {
+	executor
+	ui command.Ui
+}
+
+func (r *runner) Run(args []string) int {
+	err := r.Execute(args)
+	if err == nil {
+		return 0
+	}
+
+	r.ui.Error(err)
+	return exitCode(err)
+}
+
+func exitCode(err error) int {
+	var usageErr *command.UsageError
+	var dbErr *mm.DatabaseError
+	var pathErr *mm.PathError
+	var configErr *mm.ConfigError
+
+	switch {
+	case errors.As(err, &usageErr):
+		return exitUsage
+	case errors.As(err, &configErr):
+		return exitConfig
+	case errors.As(err, &pathErr):
+		return exitNoInput
+	case errors.As(err, &dbErr):
+		return exitDataErr
+	}
+	return exitFailure
+}
+
 func main() {
 
 	ui := &command.BaseUi{
 		Writer:      os.Stdout,
 		ErrorWriter: os.Stderr,
 	}
+	prefixedUi := &command.PrefixedUi{Ui: ui}
 
 	c := cli.NewCLI(mm.NewPathname(os.Args[0]).Basename().Path(), "0.0.1")
 	c.Args = os.Args[1:]
 
 	c.Commands = map[string]cli.CommandFactory{
 		"server": func() (cli.Command, error) {
-			return &command.ServerCommand{
-				Ui:     &command.PrefixedUi{Ui: ui},
+			return &runner{&command.ServerCommand{
+				Ui:     prefixedUi,
 				Config: mm.NewConfiguration(),
-			}, nil
+			}, prefixedUi}, nil
 		},
 		"lookup": func() (cli.Command, error) {
-			return &command.LookupCommand{Ui: ui}, nil
+			return &runner{&command.LookupCommand{Ui: ui}, ui}, nil
+		},
+		"bench": func() (cli.Command, error) {
+			return &runner{&command.BenchCommand{Ui: ui}, ui}, nil
+		},
+		"build": func() (cli.Command, error) {
+			return &runner{&command.BuildCommand{Ui: ui}, ui}, nil
+		},
+		"diff": func() (cli.Command, error) {
+			return &runner{&command.DiffCommand{Ui: ui}, ui}, nil
+		},
+		"dump": func() (cli.Command, error) {
+			return &runner{&command.DumpCommand{Ui: ui}, ui}, nil
+		},
+		"networks": func() (cli.Command, error) {
+			return &runner{&command.NetworksCommand{Ui: ui}, ui}, nil
+		},
+		"export-rules": func() (cli.Command, error) {
+			return &runner{&command.ExportRulesCommand{Ui: ui}, ui}, nil
+		},
+		"dns": func() (cli.Command, error) {
+			return &runner{&command.DnsCommand{Ui: prefixedUi}, prefixedUi}, nil
 		},
 	}
 
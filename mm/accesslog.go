@@ -0,0 +1,183 @@
+package mm
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/syslog"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pquerna/ffjson/ffjson"
+)
+
+const (
+	AccessLogJson     = "json"
+	AccessLogCommon   = "common"
+	AccessLogCombined = "combined"
+)
+
+var AccessLogFormats = map[string]bool{
+	AccessLogJson:     true,
+	AccessLogCommon:   true,
+	AccessLogCombined: true,
+}
+
+const clfTimeFormat = "02/Jan/2006:15:04:05 -0700"
+
+// ffjson: skip
+type AccessLogEntry struct {
+	Time      time.Time `json:"time"`
+	RequestId string    `json:"request_id"`
+	ClientIp  string    `json:"client_ip"`
+	Method    string    `json:"method"`
+	Path      string    `json:"path"`
+	Protocol  string    `json:"protocol"`
+	Status    int       `json:"status"`
+	Bytes     int64     `json:"bytes"`
+	Latency   float64   `json:"latency_ms"`
+	Cache     string    `json:"cache,omitempty"`
+	Referer   string    `json:"referer,omitempty"`
+	UserAgent string    `json:"user_agent,omitempty"`
+}
+
+func clfField(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
+
+// clfQuoted escapes value for a quoted field, as Apache does: quotes and
+// backslashes are backslash escaped, and other control and non-ASCII bytes
+// written as \xhh, so that a client cannot end the field, or the line.
+func clfQuoted(value string) string {
+	var escaped strings.Builder
+	for idx := 0; idx < len(value); idx++ {
+		switch b := value[idx]; {
+		case b == '"' || b == '\\':
+			escaped.WriteByte('\\')
+			escaped.WriteByte(b)
+		case b < 0x20 || b >= 0x7f:
+			fmt.Fprintf(&escaped, "\\x%02x", b)
+		default:
+			escaped.WriteByte(b)
+		}
+	}
+	return clfField(escaped.String())
+}
+
+// Common renders the entry in the Common Log Format.
+func (e *AccessLogEntry) Common() string {
+	bytes := "-"
+	if e.Bytes > 0 {
+		bytes = fmt.Sprintf("%d", e.Bytes)
+	}
+
+	return fmt.Sprintf(`%s - - [%s] "%s" %d %s`,
+		clfField(e.ClientIp), e.Time.Format(clfTimeFormat), clfQuoted(e.Method+" "+e.Path+" "+e.Protocol), e.Status, bytes)
+}
+
+// Combined renders the entry in the Combined Log Format.
+func (e *AccessLogEntry) Combined() string {
+	return fmt.Sprintf(`%s "%s" "%s"`, e.Common(), clfQuoted(e.Referer), clfQuoted(e.UserAgent))
+}
+
+// ffjson: skip
+type AccessLog struct {
+	format string
+	output string
+	writer io.Writer
+	file   *os.File
+	lock   sync.Mutex
+}
+
+// NewAccessLog creates an access log writing entries in format to output,
+// which is one of stdout, stderr, syslog, or the path of a file to append to.
+func NewAccessLog(format string, output string) (*AccessLog, error) {
+	if !AccessLogFormats[format] {
+		return nil, fmt.Errorf("invalid access log format `%s`; expected one of json, common, or combined", format)
+	}
+
+	al := &AccessLog{format: format, output: output}
+
+	switch output {
+	case "", "stdout":
+		al.writer = os.Stdout
+	case "stderr":
+		al.writer = os.Stderr
+	case "syslog":
+		writer, err := syslog.New(syslog.LOG_INFO|syslog.LOG_DAEMON, "maxmind")
+		if err != nil {
+			return nil, err
+		}
+		al.writer = writer
+	default:
+		if err := al.Reopen(); err != nil {
+			return nil, err
+		}
+	}
+
+	return al, nil
+}
+
+// Reopen closes and reopens the log file, so that a rotated file is
+// released. It is a no-op for the other outputs.
+func (al *AccessLog) Reopen() error {
+	switch al.output {
+	case "", "stdout", "stderr", "syslog":
+		return nil
+	}
+
+	file, err := os.OpenFile(al.output, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+
+	al.lock.Lock()
+	defer al.lock.Unlock()
+
+	if al.file != nil {
+		al.file.Close()
+	}
+	al.file = file
+	al.writer = file
+	return nil
+}
+
+func (al *AccessLog) Log(entry *AccessLogEntry) error {
+	var line []byte
+
+	switch al.format {
+	case AccessLogJson:
+		j, err := ffjson.Marshal(entry)
+		if err != nil {
+			return err
+		}
+		line = j
+	case AccessLogCommon:
+		line = []byte(entry.Common())
+	case AccessLogCombined:
+		line = []byte(entry.Combined())
+	default:
+		return errors.New("unknown access log format")
+	}
+
+	al.lock.Lock()
+	defer al.lock.Unlock()
+
+	_, err := al.writer.Write(append(line, '\n'))
+	return err
+}
+
+func (al *AccessLog) Close() error {
+	al.lock.Lock()
+	defer al.lock.Unlock()
+
+	if closer, ok := al.writer.(io.Closer); ok && al.writer != os.Stdout && al.writer != os.Stderr {
+		return closer.Close()
+	}
+	return nil
+}
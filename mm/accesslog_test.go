@@ -0,0 +1,24 @@
+package mm
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAccessLogEntryCombined(t *testing.T) {
+	entry := &AccessLogEntry{
+		Time:      time.Date(2018, 6, 1, 12, 30, 0, 0, time.UTC),
+		ClientIp:  "192.0.2.1",
+		Method:    "GET",
+		Path:      `/ip/1.1.1.1"x`,
+		Protocol:  "HTTP/1.1",
+		Status:    200,
+		Bytes:     512,
+		UserAgent: "curl\" \"evil\\\n",
+	}
+
+	want := `192.0.2.1 - - [01/Jun/2018:12:30:00 +0000] "GET /ip/1.1.1.1\"x HTTP/1.1" 200 512 "-" "curl\" \"evil\\\x0a"`
+	if got := entry.Combined(); got != want {
+		t.Errorf("Combined() = %s\nwant %s", got, want)
+	}
+}
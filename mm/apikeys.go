@@ -0,0 +1,212 @@
+package mm
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"github.com/pquerna/ffjson/ffjson"
+)
+
+// ffjson: skip
+type ApiKey struct {
+	Name   string   `json:"name"`
+	Key    string   `json:"key"`
+	Rate   float64  `json:"rate"`
+	Burst  int      `json:"burst"`
+	Routes []string `json:"routes"`
+
+	// Admin keys may see the usage of every key, rather than just their own.
+	Admin bool `json:"admin"`
+}
+
+// ffjson: skip
+type ApiKeyUsage struct {
+	Name     string `json:"name"`
+	Requests uint64 `json:"requests"`
+	Rejected uint64 `json:"rejected"`
+}
+
+// ffjson: skip
+type apiKeysFile struct {
+	Keys []*ApiKey `json:"keys"`
+}
+
+// ffjson: skip
+type apiKeyState struct {
+	*ApiKey
+	bucket *TokenBucket
+	usage  *ApiKeyUsage
+}
+
+// AllowsRoute reports whether the key may be used for route, the pattern a
+// handler was registered under. Keys without any routes may use them all.
+func (k *ApiKey) AllowsRoute(route string) bool {
+	if len(k.Routes) == 0 {
+		return true
+	}
+	for _, allowed := range k.Routes {
+		if allowed == route {
+			return true
+		}
+	}
+	return false
+}
+
+// ffjson: skip
+type ApiKeys struct {
+	path *Pathname
+	keys map[string]*apiKeyState
+	lock sync.RWMutex
+}
+
+func LoadApiKeys(path *Pathname) (*ApiKeys, error) {
+	ak := &ApiKeys{path: path}
+	if err := ak.Reload(); err != nil {
+		return nil, err
+	}
+	return ak, nil
+}
+
+// Reload rereads the keys file. Usage counters are carried over for keys
+// whose name is unchanged; rate limits start afresh.
+func (ak *ApiKeys) Reload() error {
+	data, err := ak.path.Read()
+	if err != nil {
+		return err
+	}
+
+	var file apiKeysFile
+	if err = ffjson.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("unable to parse api keys file %s: %s", ak.path, err)
+	}
+
+	ak.lock.Lock()
+	defer ak.lock.Unlock()
+
+	usage := map[string]*ApiKeyUsage{}
+	for _, state := range ak.keys {
+		usage[state.Name] = state.usage
+	}
+
+	keys := make(map[string]*apiKeyState, len(file.Keys))
+	for idx, key := range file.Keys {
+		if key.Key == "" {
+			return fmt.Errorf("api key #%d (%s) in %s has an empty key", idx, key.Name, ak.path)
+		}
+		if _, exists := keys[key.Key]; exists {
+			return fmt.Errorf("api key #%d (%s) in %s is a duplicate", idx, key.Name, ak.path)
+		}
+		if key.Name == "" {
+			key.Name = fmt.Sprintf("key-%d", idx)
+		}
+
+		state := &apiKeyState{
+			ApiKey: key,
+			bucket: NewTokenBucket(key.Rate, key.Burst),
+			usage:  usage[key.Name],
+		}
+		if state.usage == nil {
+			state.usage = &ApiKeyUsage{Name: key.Name}
+		}
+		keys[key.Key] = state
+	}
+
+	ak.keys = keys
+	return nil
+}
+
+var (
+	ErrApiKeyUnknown      = errors.New("unknown api key")
+	ErrApiKeyRouteDenied  = errors.New("api key is not permitted to use this route")
+	ErrApiKeyQuotaReached = errors.New("api key rate limit exceeded")
+)
+
+// Authorize checks that key exists, may use route, and is within its rate
+// limit, recording the request against the key's usage.
+func (ak *ApiKeys) Authorize(key string, route string) (*ApiKey, Allowance, error) {
+	return ak.AuthorizeN(key, route, 1)
+}
+
+// AuthorizeN is Authorize for requests costing cost tokens of the key's
+// rate limit, such as batches; a cost of 0 only checks the key and route,
+// leaving the request to be charged with ChargeN as it goes.
+func (ak *ApiKeys) AuthorizeN(key string, route string, cost int) (*ApiKey, Allowance, error) {
+	state := ak.state(key)
+	if state == nil {
+		return nil, Allowance{}, ErrApiKeyUnknown
+	}
+
+	atomic.AddUint64(&state.usage.Requests, 1)
+
+	if !state.AllowsRoute(route) {
+		atomic.AddUint64(&state.usage.Rejected, 1)
+		return state.ApiKey, Allowance{}, ErrApiKeyRouteDenied
+	}
+
+	allowance, err := state.take(cost)
+	return state.ApiKey, allowance, err
+}
+
+// ChargeN charges a request of key, already authorized, cost more tokens
+// of the key's rate limit, e.g. for each message of a stream.
+func (ak *ApiKeys) ChargeN(key string, cost int) (Allowance, error) {
+	state := ak.state(key)
+	if state == nil {
+		return Allowance{}, ErrApiKeyUnknown
+	}
+	return state.take(cost)
+}
+
+func (ak *ApiKeys) state(key string) *apiKeyState {
+	ak.lock.RLock()
+	defer ak.lock.RUnlock()
+
+	return ak.keys[key]
+}
+
+// take takes cost tokens from the key's bucket, counting the request as
+// rejected when they are not available.
+func (state *apiKeyState) take(cost int) (Allowance, error) {
+	allowance := state.bucket.TakeN(cost)
+	if !allowance.Allowed {
+		atomic.AddUint64(&state.usage.Rejected, 1)
+		return allowance, ErrApiKeyQuotaReached
+	}
+	return allowance, nil
+}
+
+// Find returns the api key key, or nil if there is no such key.
+func (ak *ApiKeys) Find(key string) *ApiKey {
+	ak.lock.RLock()
+	defer ak.lock.RUnlock()
+
+	if state, found := ak.keys[key]; found {
+		return state.ApiKey
+	}
+	return nil
+}
+
+// Usage returns a snapshot of the usage of every key, ordered by name; or,
+// unless caller is an admin key, of caller alone.
+func (ak *ApiKeys) Usage(caller *ApiKey) []ApiKeyUsage {
+	ak.lock.RLock()
+	defer ak.lock.RUnlock()
+
+	usage := make([]ApiKeyUsage, 0, len(ak.keys))
+	for _, state := range ak.keys {
+		if caller == nil || (!caller.Admin && state.Key != caller.Key) {
+			continue
+		}
+		usage = append(usage, ApiKeyUsage{
+			Name:     state.usage.Name,
+			Requests: atomic.LoadUint64(&state.usage.Requests),
+			Rejected: atomic.LoadUint64(&state.usage.Rejected),
+		})
+	}
+
+	sort.Slice(usage, func(i, j int) bool { return usage[i].Name < usage[j].Name })
+	return usage
+}
@@ -0,0 +1,127 @@
+package mm
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func writeApiKeys(t *testing.T, content string) *Pathname {
+	path := filepath.Join(t.TempDir(), "keys.json")
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return NewPathname(path)
+}
+
+func loadApiKeys(t *testing.T, content string) *ApiKeys {
+	keys, err := LoadApiKeys(writeApiKeys(t, content))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return keys
+}
+
+func TestLoadApiKeysErrors(t *testing.T) {
+	for content, want := range map[string]string{
+		`{"keys": [`:                "unable to parse",
+		`{"keys": [{"name": "a"}]}`: "empty key",
+		`{"keys": [{"key": "x"}, {"name": "b", "key": "x"}]}`: "duplicate",
+	} {
+		if _, err := LoadApiKeys(writeApiKeys(t, content)); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("LoadApiKeys(%s) = %v; want an error containing %q", content, err, want)
+		}
+	}
+
+	if _, err := LoadApiKeys(NewPathname(filepath.Join(t.TempDir(), "missing.json"))); err == nil {
+		t.Error("LoadApiKeys of a missing file succeeded")
+	}
+}
+
+func TestApiKeysAuthorize(t *testing.T) {
+	keys := loadApiKeys(t, `{"keys": [
+		{"name": "lookups", "key": "secret", "rate": 0.001, "burst": 3, "routes": ["/ip/:ip", "/batch"]},
+		{"key": "unlimited"}
+	]}`)
+
+	for idx, test := range []struct {
+		key   string
+		route string
+		cost  int
+		want  error
+	}{
+		{"wrong", "/ip/:ip", 1, ErrApiKeyUnknown},
+		{"secret", "/info", 1, ErrApiKeyRouteDenied},
+		{"secret", "/ip/:ip", 0, nil},
+		{"secret", "/batch", 2, nil},
+		{"secret", "/ip/:ip", 1, nil},
+		{"secret", "/ip/:ip", 1, ErrApiKeyQuotaReached},
+		{"unlimited", "/info", 1000, nil},
+	} {
+		if _, _, err := keys.AuthorizeN(test.key, test.route, test.cost); err != test.want {
+			t.Errorf("%d: AuthorizeN(%s, %s, %d) = %v; want %v", idx, test.key, test.route, test.cost, err, test.want)
+		}
+	}
+
+	if _, err := keys.ChargeN("secret", 1); err != ErrApiKeyQuotaReached {
+		t.Errorf("ChargeN of a spent key = %v; want %v", err, ErrApiKeyQuotaReached)
+	}
+	if _, err := keys.ChargeN("unlimited", 1000); err != nil {
+		t.Errorf("ChargeN of an unlimited key = %v", err)
+	}
+	if _, err := keys.ChargeN("wrong", 1); err != ErrApiKeyUnknown {
+		t.Errorf("ChargeN of an unknown key = %v; want %v", err, ErrApiKeyUnknown)
+	}
+
+	// charges are not requests, but rejected charges are rejections
+	want := []ApiKeyUsage{{Name: "key-1", Requests: 1}, {Name: "lookups", Requests: 5, Rejected: 3}}
+	if usage := keys.Usage(&ApiKey{Admin: true}); !reflect.DeepEqual(usage, want) {
+		t.Errorf("Usage = %+v; want %+v", usage, want)
+	}
+}
+
+func TestApiKeysUsage(t *testing.T) {
+	path := writeApiKeys(t, `{"keys": [
+		{"name": "a", "key": "a-key"},
+		{"name": "b", "key": "b-key", "admin": true}
+	]}`)
+	keys, err := LoadApiKeys(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys.Authorize("a-key", "/ip/:ip")
+	keys.Authorize("b-key", "/ip/:ip")
+
+	for _, test := range []struct {
+		caller *ApiKey
+		want   []string
+	}{
+		{nil, nil},
+		{keys.Find("a-key"), []string{"a"}},
+		{keys.Find("b-key"), []string{"a", "b"}},
+	} {
+		var names []string
+		for _, usage := range keys.Usage(test.caller) {
+			names = append(names, usage.Name)
+		}
+		if !reflect.DeepEqual(names, test.want) {
+			t.Errorf("Usage(%+v) = %q; want %q", test.caller, names, test.want)
+		}
+	}
+
+	// reloads carry the usage of keys over by name
+	if err = os.WriteFile(path.Path(), []byte(`{"keys": [{"name": "a", "key": "new-a-key"}]}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err = keys.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if keys.Find("a-key") != nil || keys.Find("new-a-key") == nil {
+		t.Error("Reload kept the old keys")
+	}
+	if usage := keys.Usage(&ApiKey{Admin: true}); len(usage) != 1 || usage[0].Requests != 1 {
+		t.Errorf("usage after reload = %+v", usage)
+	}
+}
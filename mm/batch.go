@@ -0,0 +1,29 @@
+package mm
+
+// MaxBatchSize is the most ips that may be looked up in a single batch
+// request.
+const MaxBatchSize = 1000
+
+// ffjson: skip
+type BatchRequest struct {
+	Ips []string `json:"ips"`
+}
+
+// LookupResult is the outcome of looking up a single ip of a batch.
+//
+// ffjson: skip
+type LookupResult struct {
+	Ip      string   `json:"ip"`
+	Status  string   `json:"status"`
+	Message string   `json:"message"`
+	Data    *GeoData `json:"data"`
+
+	Origins map[string]string `json:"origins,omitempty"`
+}
+
+// ffjson: skip
+type BatchResponse struct {
+	Status  string          `json:"status"`
+	Message string          `json:"message"`
+	Results []*LookupResult `json:"results"`
+}
@@ -1,8 +1,11 @@
 package mm
 
 import (
+	"fmt"
+	"net"
 	"os"
 	"runtime"
+	"strconv"
 
 	"github.com/pquerna/ffjson/ffjson"
 )
@@ -11,27 +14,83 @@ import (
 
 // ffjson: noencoder
 type Configuration struct {
-	Ip       string    `json:"server.ip"`
-	Port     uint32    `json:"server.port"`
-	DbPath   *Pathname `json:"database.file"`
-	Threads  uint8     `json:"worker.threads"`
-	CacheTtl float64   `json:"cache.ttl"`
+	Ip          string     `json:"server.ip"`
+	Port        uint32     `json:"server.port"`
+	Listen      []string   `json:"server.listen"`
+	SocketMode  string     `json:"server.socket.mode"`
+	Backend     string     `json:"database.backend"`
+	DbPath      *Pathname  `json:"database.file"`
+	Upstream    string     `json:"database.upstream"`
+	UpstreamKey string     `json:"database.upstream.api_key"`
+	Overlays    []*Overlay `json:"database.overlays"`
+	Threads     uint8      `json:"worker.threads"`
+	CacheTtl    float64    `json:"cache.ttl"`
+	GrpcPort    uint32     `json:"grpc.port"`
+
+	CanaryIp       string  `json:"health.canary.ip"`
+	CanaryCountry  string  `json:"health.canary.country"`
+	DatabaseMaxAge float64 `json:"health.database.max_age"`
+
+	ApiKeysFile string `json:"auth.keys.file"`
+
+	RateLimitKey   string  `json:"ratelimit.key"`
+	RateLimitRate  float64 `json:"ratelimit.rate"`
+	RateLimitBurst uint32  `json:"ratelimit.burst"`
+
+	CorsOrigins []string `json:"cors.origins"`
+	CorsMethods []string `json:"cors.methods"`
+	CorsHeaders []string `json:"cors.headers"`
+	CorsMaxAge  uint32   `json:"cors.max_age"`
+
+	AccessLogFormat string `json:"log.access.format"`
+	AccessLogOutput string `json:"log.access.output"`
+
+	LogLevel  string `json:"log.level"`
+	LogFormat string `json:"log.format"`
 }
 
 // create a new configuration with default values
 func NewConfiguration() *Configuration {
 	return &Configuration{
-		Ip:       "127.0.0.1",
-		Port:     8000,
-		DbPath:   NewPathname("/var/lib/maxminddb/GeoLite2-City.mmdb"),
-		Threads:  uint8(runtime.NumCPU()),
-		CacheTtl: float64(3600),
+		Ip:         "127.0.0.1",
+		Port:       8000,
+		SocketMode: "0660",
+		Backend:    BackendMmdb,
+		DbPath:     NewPathname("/var/lib/maxminddb/GeoLite2-City.mmdb"),
+		Threads:    uint8(runtime.NumCPU()),
+		CacheTtl:   float64(3600),
+
+		CorsMethods: []string{"GET", "HEAD", "POST"},
+		CorsHeaders: []string{"Accept", "Authorization", "Content-Type", "X-API-Key"},
+		CorsMaxAge:  600,
+
+		AccessLogOutput: "stdout",
 	}
 }
 
+// ListenAddresses returns the addresses the server should listen on, falling
+// back to a single tcp listener on server.ip and server.port when no
+// server.listen addresses were configured.
+func (c *Configuration) ListenAddresses() []string {
+	if len(c.Listen) > 0 {
+		return c.Listen
+	}
+	return []string{fmt.Sprintf("tcp://%s", net.JoinHostPort(c.Ip, strconv.Itoa(int(c.Port))))}
+}
+
+// SocketFileMode parses server.socket.mode, an octal permission string,
+// into the mode applied to unix socket listeners.
+func (c *Configuration) SocketFileMode() (os.FileMode, error) {
+	mode, err := strconv.ParseUint(c.SocketMode, 8, 32)
+	if err != nil {
+		return 0, &ConfigError{Key: "server.socket.mode", Err: fmt.Errorf("`%s` is not octal permissions, e.g. 0660", c.SocketMode)}
+	}
+	return os.FileMode(mode) & os.ModePerm, nil
+}
+
 func (c *Configuration) LoadFromJson(data []byte) error {
 	if err := ffjson.Unmarshal(data, c); err != nil {
-		return err
+		return &ConfigError{Err: err}
 	}
 	return nil
 }
@@ -46,8 +105,11 @@ func (c *Configuration) LoadFromJsonFile(configFile *Pathname) error {
 
 	b, err := configFile.Read()
 	if err != nil {
-		return err
+		return &ConfigError{File: configFile.String(), Err: err}
 	}
 
-	return c.LoadFromJson(b)
+	if err := c.LoadFromJson(b); err != nil {
+		return &ConfigError{File: configFile.String(), Err: err.(*ConfigError).Err}
+	}
+	return nil
 }
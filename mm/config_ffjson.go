@@ -18,23 +18,111 @@ const (
 
 	ffjtConfigurationPort
 
+	ffjtConfigurationListen
+
+	ffjtConfigurationSocketMode
+
+	ffjtConfigurationBackend
+
 	ffjtConfigurationDbPath
 
+	ffjtConfigurationUpstream
+
+	ffjtConfigurationUpstreamKey
+
+	ffjtConfigurationOverlays
+
 	ffjtConfigurationThreads
 
 	ffjtConfigurationCacheTtl
+
+	ffjtConfigurationGrpcPort
+
+	ffjtConfigurationCanaryIp
+
+	ffjtConfigurationCanaryCountry
+
+	ffjtConfigurationDatabaseMaxAge
+
+	ffjtConfigurationApiKeysFile
+
+	ffjtConfigurationRateLimitKey
+
+	ffjtConfigurationRateLimitRate
+
+	ffjtConfigurationRateLimitBurst
+
+	ffjtConfigurationCorsOrigins
+
+	ffjtConfigurationCorsMethods
+
+	ffjtConfigurationCorsHeaders
+
+	ffjtConfigurationCorsMaxAge
+
+	ffjtConfigurationAccessLogFormat
+
+	ffjtConfigurationAccessLogOutput
+
+	ffjtConfigurationLogLevel
+
+	ffjtConfigurationLogFormat
 )
 
 var ffjKeyConfigurationIp = []byte("server.ip")
 
 var ffjKeyConfigurationPort = []byte("server.port")
 
+var ffjKeyConfigurationListen = []byte("server.listen")
+
+var ffjKeyConfigurationSocketMode = []byte("server.socket.mode")
+
+var ffjKeyConfigurationBackend = []byte("database.backend")
+
 var ffjKeyConfigurationDbPath = []byte("database.file")
 
+var ffjKeyConfigurationUpstream = []byte("database.upstream")
+
+var ffjKeyConfigurationUpstreamKey = []byte("database.upstream.api_key")
+
+var ffjKeyConfigurationOverlays = []byte("database.overlays")
+
 var ffjKeyConfigurationThreads = []byte("worker.threads")
 
 var ffjKeyConfigurationCacheTtl = []byte("cache.ttl")
 
+var ffjKeyConfigurationGrpcPort = []byte("grpc.port")
+
+var ffjKeyConfigurationCanaryIp = []byte("health.canary.ip")
+
+var ffjKeyConfigurationCanaryCountry = []byte("health.canary.country")
+
+var ffjKeyConfigurationDatabaseMaxAge = []byte("health.database.max_age")
+
+var ffjKeyConfigurationApiKeysFile = []byte("auth.keys.file")
+
+var ffjKeyConfigurationRateLimitKey = []byte("ratelimit.key")
+
+var ffjKeyConfigurationRateLimitRate = []byte("ratelimit.rate")
+
+var ffjKeyConfigurationRateLimitBurst = []byte("ratelimit.burst")
+
+var ffjKeyConfigurationCorsOrigins = []byte("cors.origins")
+
+var ffjKeyConfigurationCorsMethods = []byte("cors.methods")
+
+var ffjKeyConfigurationCorsHeaders = []byte("cors.headers")
+
+var ffjKeyConfigurationCorsMaxAge = []byte("cors.max_age")
+
+var ffjKeyConfigurationAccessLogFormat = []byte("log.access.format")
+
+var ffjKeyConfigurationAccessLogOutput = []byte("log.access.output")
+
+var ffjKeyConfigurationLogLevel = []byte("log.level")
+
+var ffjKeyConfigurationLogFormat = []byte("log.format")
+
 // UnmarshalJSON umarshall json - template of ffjson
 func (j *Configuration) UnmarshalJSON(input []byte) error {
 	fs := fflib.NewFFLexer(input)
@@ -96,20 +184,135 @@ mainparse:
 			} else {
 				switch kn[0] {
 
+				case 'a':
+
+					if bytes.Equal(ffjKeyConfigurationApiKeysFile, kn) {
+						currentKey = ffjtConfigurationApiKeysFile
+						state = fflib.FFParse_want_colon
+						goto mainparse
+					}
+
 				case 'c':
 
 					if bytes.Equal(ffjKeyConfigurationCacheTtl, kn) {
 						currentKey = ffjtConfigurationCacheTtl
 						state = fflib.FFParse_want_colon
 						goto mainparse
+
+					} else if bytes.Equal(ffjKeyConfigurationCorsOrigins, kn) {
+						currentKey = ffjtConfigurationCorsOrigins
+						state = fflib.FFParse_want_colon
+						goto mainparse
+
+					} else if bytes.Equal(ffjKeyConfigurationCorsMethods, kn) {
+						currentKey = ffjtConfigurationCorsMethods
+						state = fflib.FFParse_want_colon
+						goto mainparse
+
+					} else if bytes.Equal(ffjKeyConfigurationCorsHeaders, kn) {
+						currentKey = ffjtConfigurationCorsHeaders
+						state = fflib.FFParse_want_colon
+						goto mainparse
+
+					} else if bytes.Equal(ffjKeyConfigurationCorsMaxAge, kn) {
+						currentKey = ffjtConfigurationCorsMaxAge
+						state = fflib.FFParse_want_colon
+						goto mainparse
 					}
 
 				case 'd':
 
-					if bytes.Equal(ffjKeyConfigurationDbPath, kn) {
+					if bytes.Equal(ffjKeyConfigurationBackend, kn) {
+						currentKey = ffjtConfigurationBackend
+						state = fflib.FFParse_want_colon
+						goto mainparse
+
+					} else if bytes.Equal(ffjKeyConfigurationDbPath, kn) {
 						currentKey = ffjtConfigurationDbPath
 						state = fflib.FFParse_want_colon
 						goto mainparse
+
+					} else if bytes.Equal(ffjKeyConfigurationUpstream, kn) {
+						currentKey = ffjtConfigurationUpstream
+						state = fflib.FFParse_want_colon
+						goto mainparse
+
+					} else if bytes.Equal(ffjKeyConfigurationUpstreamKey, kn) {
+						currentKey = ffjtConfigurationUpstreamKey
+						state = fflib.FFParse_want_colon
+						goto mainparse
+
+					} else if bytes.Equal(ffjKeyConfigurationOverlays, kn) {
+						currentKey = ffjtConfigurationOverlays
+						state = fflib.FFParse_want_colon
+						goto mainparse
+					}
+
+				case 'g':
+
+					if bytes.Equal(ffjKeyConfigurationGrpcPort, kn) {
+						currentKey = ffjtConfigurationGrpcPort
+						state = fflib.FFParse_want_colon
+						goto mainparse
+					}
+
+				case 'h':
+
+					if bytes.Equal(ffjKeyConfigurationCanaryIp, kn) {
+						currentKey = ffjtConfigurationCanaryIp
+						state = fflib.FFParse_want_colon
+						goto mainparse
+
+					} else if bytes.Equal(ffjKeyConfigurationCanaryCountry, kn) {
+						currentKey = ffjtConfigurationCanaryCountry
+						state = fflib.FFParse_want_colon
+						goto mainparse
+
+					} else if bytes.Equal(ffjKeyConfigurationDatabaseMaxAge, kn) {
+						currentKey = ffjtConfigurationDatabaseMaxAge
+						state = fflib.FFParse_want_colon
+						goto mainparse
+					}
+
+				case 'l':
+
+					if bytes.Equal(ffjKeyConfigurationAccessLogFormat, kn) {
+						currentKey = ffjtConfigurationAccessLogFormat
+						state = fflib.FFParse_want_colon
+						goto mainparse
+
+					} else if bytes.Equal(ffjKeyConfigurationAccessLogOutput, kn) {
+						currentKey = ffjtConfigurationAccessLogOutput
+						state = fflib.FFParse_want_colon
+						goto mainparse
+
+					} else if bytes.Equal(ffjKeyConfigurationLogLevel, kn) {
+						currentKey = ffjtConfigurationLogLevel
+						state = fflib.FFParse_want_colon
+						goto mainparse
+
+					} else if bytes.Equal(ffjKeyConfigurationLogFormat, kn) {
+						currentKey = ffjtConfigurationLogFormat
+						state = fflib.FFParse_want_colon
+						goto mainparse
+					}
+
+				case 'r':
+
+					if bytes.Equal(ffjKeyConfigurationRateLimitKey, kn) {
+						currentKey = ffjtConfigurationRateLimitKey
+						state = fflib.FFParse_want_colon
+						goto mainparse
+
+					} else if bytes.Equal(ffjKeyConfigurationRateLimitRate, kn) {
+						currentKey = ffjtConfigurationRateLimitRate
+						state = fflib.FFParse_want_colon
+						goto mainparse
+
+					} else if bytes.Equal(ffjKeyConfigurationRateLimitBurst, kn) {
+						currentKey = ffjtConfigurationRateLimitBurst
+						state = fflib.FFParse_want_colon
+						goto mainparse
 					}
 
 				case 's':
@@ -123,6 +326,16 @@ mainparse:
 						currentKey = ffjtConfigurationPort
 						state = fflib.FFParse_want_colon
 						goto mainparse
+
+					} else if bytes.Equal(ffjKeyConfigurationListen, kn) {
+						currentKey = ffjtConfigurationListen
+						state = fflib.FFParse_want_colon
+						goto mainparse
+
+					} else if bytes.Equal(ffjKeyConfigurationSocketMode, kn) {
+						currentKey = ffjtConfigurationSocketMode
+						state = fflib.FFParse_want_colon
+						goto mainparse
 					}
 
 				case 'w':
@@ -135,6 +348,102 @@ mainparse:
 
 				}
 
+				if fflib.AsciiEqualFold(ffjKeyConfigurationLogFormat, kn) {
+					currentKey = ffjtConfigurationLogFormat
+					state = fflib.FFParse_want_colon
+					goto mainparse
+				}
+
+				if fflib.AsciiEqualFold(ffjKeyConfigurationLogLevel, kn) {
+					currentKey = ffjtConfigurationLogLevel
+					state = fflib.FFParse_want_colon
+					goto mainparse
+				}
+
+				if fflib.EqualFoldRight(ffjKeyConfigurationAccessLogOutput, kn) {
+					currentKey = ffjtConfigurationAccessLogOutput
+					state = fflib.FFParse_want_colon
+					goto mainparse
+				}
+
+				if fflib.EqualFoldRight(ffjKeyConfigurationAccessLogFormat, kn) {
+					currentKey = ffjtConfigurationAccessLogFormat
+					state = fflib.FFParse_want_colon
+					goto mainparse
+				}
+
+				if fflib.EqualFoldRight(ffjKeyConfigurationCorsMaxAge, kn) {
+					currentKey = ffjtConfigurationCorsMaxAge
+					state = fflib.FFParse_want_colon
+					goto mainparse
+				}
+
+				if fflib.EqualFoldRight(ffjKeyConfigurationCorsHeaders, kn) {
+					currentKey = ffjtConfigurationCorsHeaders
+					state = fflib.FFParse_want_colon
+					goto mainparse
+				}
+
+				if fflib.EqualFoldRight(ffjKeyConfigurationCorsMethods, kn) {
+					currentKey = ffjtConfigurationCorsMethods
+					state = fflib.FFParse_want_colon
+					goto mainparse
+				}
+
+				if fflib.EqualFoldRight(ffjKeyConfigurationCorsOrigins, kn) {
+					currentKey = ffjtConfigurationCorsOrigins
+					state = fflib.FFParse_want_colon
+					goto mainparse
+				}
+
+				if fflib.EqualFoldRight(ffjKeyConfigurationRateLimitBurst, kn) {
+					currentKey = ffjtConfigurationRateLimitBurst
+					state = fflib.FFParse_want_colon
+					goto mainparse
+				}
+
+				if fflib.AsciiEqualFold(ffjKeyConfigurationRateLimitRate, kn) {
+					currentKey = ffjtConfigurationRateLimitRate
+					state = fflib.FFParse_want_colon
+					goto mainparse
+				}
+
+				if fflib.EqualFoldRight(ffjKeyConfigurationRateLimitKey, kn) {
+					currentKey = ffjtConfigurationRateLimitKey
+					state = fflib.FFParse_want_colon
+					goto mainparse
+				}
+
+				if fflib.EqualFoldRight(ffjKeyConfigurationApiKeysFile, kn) {
+					currentKey = ffjtConfigurationApiKeysFile
+					state = fflib.FFParse_want_colon
+					goto mainparse
+				}
+
+				if fflib.EqualFoldRight(ffjKeyConfigurationDatabaseMaxAge, kn) {
+					currentKey = ffjtConfigurationDatabaseMaxAge
+					state = fflib.FFParse_want_colon
+					goto mainparse
+				}
+
+				if fflib.AsciiEqualFold(ffjKeyConfigurationCanaryCountry, kn) {
+					currentKey = ffjtConfigurationCanaryCountry
+					state = fflib.FFParse_want_colon
+					goto mainparse
+				}
+
+				if fflib.AsciiEqualFold(ffjKeyConfigurationCanaryIp, kn) {
+					currentKey = ffjtConfigurationCanaryIp
+					state = fflib.FFParse_want_colon
+					goto mainparse
+				}
+
+				if fflib.AsciiEqualFold(ffjKeyConfigurationGrpcPort, kn) {
+					currentKey = ffjtConfigurationGrpcPort
+					state = fflib.FFParse_want_colon
+					goto mainparse
+				}
+
 				if fflib.AsciiEqualFold(ffjKeyConfigurationCacheTtl, kn) {
 					currentKey = ffjtConfigurationCacheTtl
 					state = fflib.FFParse_want_colon
@@ -147,12 +456,48 @@ mainparse:
 					goto mainparse
 				}
 
+				if fflib.EqualFoldRight(ffjKeyConfigurationOverlays, kn) {
+					currentKey = ffjtConfigurationOverlays
+					state = fflib.FFParse_want_colon
+					goto mainparse
+				}
+
+				if fflib.EqualFoldRight(ffjKeyConfigurationUpstreamKey, kn) {
+					currentKey = ffjtConfigurationUpstreamKey
+					state = fflib.FFParse_want_colon
+					goto mainparse
+				}
+
+				if fflib.EqualFoldRight(ffjKeyConfigurationUpstream, kn) {
+					currentKey = ffjtConfigurationUpstream
+					state = fflib.FFParse_want_colon
+					goto mainparse
+				}
+
 				if fflib.EqualFoldRight(ffjKeyConfigurationDbPath, kn) {
 					currentKey = ffjtConfigurationDbPath
 					state = fflib.FFParse_want_colon
 					goto mainparse
 				}
 
+				if fflib.EqualFoldRight(ffjKeyConfigurationBackend, kn) {
+					currentKey = ffjtConfigurationBackend
+					state = fflib.FFParse_want_colon
+					goto mainparse
+				}
+
+				if fflib.EqualFoldRight(ffjKeyConfigurationSocketMode, kn) {
+					currentKey = ffjtConfigurationSocketMode
+					state = fflib.FFParse_want_colon
+					goto mainparse
+				}
+
+				if fflib.EqualFoldRight(ffjKeyConfigurationListen, kn) {
+					currentKey = ffjtConfigurationListen
+					state = fflib.FFParse_want_colon
+					goto mainparse
+				}
+
 				if fflib.EqualFoldRight(ffjKeyConfigurationPort, kn) {
 					currentKey = ffjtConfigurationPort
 					state = fflib.FFParse_want_colon
@@ -188,15 +533,81 @@ mainparse:
 				case ffjtConfigurationPort:
 					goto handle_Port
 
+				case ffjtConfigurationListen:
+					goto handle_Listen
+
+				case ffjtConfigurationSocketMode:
+					goto handle_SocketMode
+
+				case ffjtConfigurationBackend:
+					goto handle_Backend
+
 				case ffjtConfigurationDbPath:
 					goto handle_DbPath
 
+				case ffjtConfigurationUpstream:
+					goto handle_Upstream
+
+				case ffjtConfigurationUpstreamKey:
+					goto handle_UpstreamKey
+
+				case ffjtConfigurationOverlays:
+					goto handle_Overlays
+
 				case ffjtConfigurationThreads:
 					goto handle_Threads
 
 				case ffjtConfigurationCacheTtl:
 					goto handle_CacheTtl
 
+				case ffjtConfigurationGrpcPort:
+					goto handle_GrpcPort
+
+				case ffjtConfigurationCanaryIp:
+					goto handle_CanaryIp
+
+				case ffjtConfigurationCanaryCountry:
+					goto handle_CanaryCountry
+
+				case ffjtConfigurationDatabaseMaxAge:
+					goto handle_DatabaseMaxAge
+
+				case ffjtConfigurationApiKeysFile:
+					goto handle_ApiKeysFile
+
+				case ffjtConfigurationRateLimitKey:
+					goto handle_RateLimitKey
+
+				case ffjtConfigurationRateLimitRate:
+					goto handle_RateLimitRate
+
+				case ffjtConfigurationRateLimitBurst:
+					goto handle_RateLimitBurst
+
+				case ffjtConfigurationCorsOrigins:
+					goto handle_CorsOrigins
+
+				case ffjtConfigurationCorsMethods:
+					goto handle_CorsMethods
+
+				case ffjtConfigurationCorsHeaders:
+					goto handle_CorsHeaders
+
+				case ffjtConfigurationCorsMaxAge:
+					goto handle_CorsMaxAge
+
+				case ffjtConfigurationAccessLogFormat:
+					goto handle_AccessLogFormat
+
+				case ffjtConfigurationAccessLogOutput:
+					goto handle_AccessLogOutput
+
+				case ffjtConfigurationLogLevel:
+					goto handle_LogLevel
+
+				case ffjtConfigurationLogFormat:
+					goto handle_LogFormat
+
 				case ffjtConfigurationnosuchkey:
 					err = fs.SkipField(tok)
 					if err != nil {
@@ -267,39 +678,237 @@ handle_Port:
 	state = fflib.FFParse_after_value
 	goto mainparse
 
-handle_DbPath:
+handle_Listen:
 
-	/* handler: j.DbPath type=mm.Pathname kind=struct quoted=false*/
+	/* handler: j.Listen type=[]string kind=slice quoted=false*/
 
 	{
-		/* Falling back. type=mm.Pathname kind=struct */
-		tbuf, err := fs.CaptureField(tok)
-		if err != nil {
-			return fs.WrapErr(err)
-		}
 
-		err = json.Unmarshal(tbuf, &j.DbPath)
-		if err != nil {
-			return fs.WrapErr(err)
+		{
+			if tok != fflib.FFTok_left_brace && tok != fflib.FFTok_null {
+				return fs.WrapErr(fmt.Errorf("cannot unmarshal %s into Go value for ", tok))
+			}
 		}
-	}
 
-	state = fflib.FFParse_after_value
-	goto mainparse
+		if tok == fflib.FFTok_null {
+			j.Listen = nil
+		} else {
 
-handle_Threads:
+			j.Listen = []string{}
 
-	/* handler: j.Threads type=uint8 kind=uint8 quoted=false*/
+			wantVal := true
 
-	{
-		if tok != fflib.FFTok_integer && tok != fflib.FFTok_null {
-			return fs.WrapErr(fmt.Errorf("cannot unmarshal %s into Go value for uint8", tok))
-		}
-	}
+			for {
 
-	{
+				var tmpJListen string
 
-		if tok == fflib.FFTok_null {
+				tok = fs.Scan()
+				if tok == fflib.FFTok_error {
+					goto tokerror
+				}
+				if tok == fflib.FFTok_right_brace {
+					break
+				}
+
+				if tok == fflib.FFTok_comma {
+					if wantVal == true {
+						// TODO(pquerna): this isn't an ideal error message, this handles
+						// things like [,,,] as an array value.
+						return fs.WrapErr(fmt.Errorf("wanted value token, but got token: %v", tok))
+					}
+					continue
+				} else {
+					wantVal = true
+				}
+
+				/* handler: tmpJListen type=string kind=string quoted=false*/
+
+				{
+
+					{
+						if tok != fflib.FFTok_string && tok != fflib.FFTok_null {
+							return fs.WrapErr(fmt.Errorf("cannot unmarshal %s into Go value for string", tok))
+						}
+					}
+
+					if tok == fflib.FFTok_null {
+
+					} else {
+
+						outBuf := fs.Output.Bytes()
+
+						tmpJListen = string(string(outBuf))
+
+					}
+				}
+
+				j.Listen = append(j.Listen, tmpJListen)
+
+				wantVal = false
+			}
+		}
+	}
+
+	state = fflib.FFParse_after_value
+	goto mainparse
+
+handle_SocketMode:
+
+	/* handler: j.SocketMode type=string kind=string quoted=false*/
+
+	{
+
+		{
+			if tok != fflib.FFTok_string && tok != fflib.FFTok_null {
+				return fs.WrapErr(fmt.Errorf("cannot unmarshal %s into Go value for string", tok))
+			}
+		}
+
+		if tok == fflib.FFTok_null {
+
+		} else {
+
+			outBuf := fs.Output.Bytes()
+
+			j.SocketMode = string(string(outBuf))
+
+		}
+	}
+
+	state = fflib.FFParse_after_value
+	goto mainparse
+
+handle_Backend:
+
+	/* handler: j.Backend type=string kind=string quoted=false*/
+
+	{
+
+		{
+			if tok != fflib.FFTok_string && tok != fflib.FFTok_null {
+				return fs.WrapErr(fmt.Errorf("cannot unmarshal %s into Go value for string", tok))
+			}
+		}
+
+		if tok == fflib.FFTok_null {
+
+		} else {
+
+			outBuf := fs.Output.Bytes()
+
+			j.Backend = string(string(outBuf))
+
+		}
+	}
+
+	state = fflib.FFParse_after_value
+	goto mainparse
+
+handle_DbPath:
+
+	/* handler: j.DbPath type=mm.Pathname kind=struct quoted=false*/
+
+	{
+		/* Falling back. type=mm.Pathname kind=struct */
+		tbuf, err := fs.CaptureField(tok)
+		if err != nil {
+			return fs.WrapErr(err)
+		}
+
+		err = json.Unmarshal(tbuf, &j.DbPath)
+		if err != nil {
+			return fs.WrapErr(err)
+		}
+	}
+
+	state = fflib.FFParse_after_value
+	goto mainparse
+
+handle_Upstream:
+
+	/* handler: j.Upstream type=string kind=string quoted=false*/
+
+	{
+
+		{
+			if tok != fflib.FFTok_string && tok != fflib.FFTok_null {
+				return fs.WrapErr(fmt.Errorf("cannot unmarshal %s into Go value for string", tok))
+			}
+		}
+
+		if tok == fflib.FFTok_null {
+
+		} else {
+
+			outBuf := fs.Output.Bytes()
+
+			j.Upstream = string(string(outBuf))
+
+		}
+	}
+
+	state = fflib.FFParse_after_value
+	goto mainparse
+
+handle_UpstreamKey:
+
+	/* handler: j.UpstreamKey type=string kind=string quoted=false*/
+
+	{
+
+		{
+			if tok != fflib.FFTok_string && tok != fflib.FFTok_null {
+				return fs.WrapErr(fmt.Errorf("cannot unmarshal %s into Go value for string", tok))
+			}
+		}
+
+		if tok == fflib.FFTok_null {
+
+		} else {
+
+			outBuf := fs.Output.Bytes()
+
+			j.UpstreamKey = string(string(outBuf))
+
+		}
+	}
+
+	state = fflib.FFParse_after_value
+	goto mainparse
+
+handle_Overlays:
+
+	/* handler: j.Overlays type=[]*Overlay kind=slice quoted=false*/
+
+	{
+		/* Falling back. type=[]*Overlay kind=slice */
+		tbuf, err := fs.CaptureField(tok)
+		if err != nil {
+			return fs.WrapErr(err)
+		}
+
+		err = json.Unmarshal(tbuf, &j.Overlays)
+		if err != nil {
+			return fs.WrapErr(err)
+		}
+	}
+
+	state = fflib.FFParse_after_value
+	goto mainparse
+
+handle_Threads:
+
+	/* handler: j.Threads type=uint8 kind=uint8 quoted=false*/
+
+	{
+		if tok != fflib.FFTok_integer && tok != fflib.FFTok_null {
+			return fs.WrapErr(fmt.Errorf("cannot unmarshal %s into Go value for uint8", tok))
+		}
+	}
+
+	{
+
+		if tok == fflib.FFTok_null {
 
 		} else {
 
@@ -347,6 +956,586 @@ handle_CacheTtl:
 	state = fflib.FFParse_after_value
 	goto mainparse
 
+handle_GrpcPort:
+
+	/* handler: j.GrpcPort type=uint32 kind=uint32 quoted=false*/
+
+	{
+		if tok != fflib.FFTok_integer && tok != fflib.FFTok_null {
+			return fs.WrapErr(fmt.Errorf("cannot unmarshal %s into Go value for uint32", tok))
+		}
+	}
+
+	{
+
+		if tok == fflib.FFTok_null {
+
+		} else {
+
+			tval, err := fflib.ParseUint(fs.Output.Bytes(), 10, 32)
+
+			if err != nil {
+				return fs.WrapErr(err)
+			}
+
+			j.GrpcPort = uint32(tval)
+
+		}
+	}
+
+	state = fflib.FFParse_after_value
+	goto mainparse
+
+handle_CanaryIp:
+
+	/* handler: j.CanaryIp type=string kind=string quoted=false*/
+
+	{
+
+		{
+			if tok != fflib.FFTok_string && tok != fflib.FFTok_null {
+				return fs.WrapErr(fmt.Errorf("cannot unmarshal %s into Go value for string", tok))
+			}
+		}
+
+		if tok == fflib.FFTok_null {
+
+		} else {
+
+			outBuf := fs.Output.Bytes()
+
+			j.CanaryIp = string(string(outBuf))
+
+		}
+	}
+
+	state = fflib.FFParse_after_value
+	goto mainparse
+
+handle_CanaryCountry:
+
+	/* handler: j.CanaryCountry type=string kind=string quoted=false*/
+
+	{
+
+		{
+			if tok != fflib.FFTok_string && tok != fflib.FFTok_null {
+				return fs.WrapErr(fmt.Errorf("cannot unmarshal %s into Go value for string", tok))
+			}
+		}
+
+		if tok == fflib.FFTok_null {
+
+		} else {
+
+			outBuf := fs.Output.Bytes()
+
+			j.CanaryCountry = string(string(outBuf))
+
+		}
+	}
+
+	state = fflib.FFParse_after_value
+	goto mainparse
+
+handle_DatabaseMaxAge:
+
+	/* handler: j.DatabaseMaxAge type=float64 kind=float64 quoted=false*/
+
+	{
+		if tok != fflib.FFTok_double && tok != fflib.FFTok_integer && tok != fflib.FFTok_null {
+			return fs.WrapErr(fmt.Errorf("cannot unmarshal %s into Go value for float64", tok))
+		}
+	}
+
+	{
+
+		if tok == fflib.FFTok_null {
+
+		} else {
+
+			tval, err := fflib.ParseFloat(fs.Output.Bytes(), 64)
+
+			if err != nil {
+				return fs.WrapErr(err)
+			}
+
+			j.DatabaseMaxAge = float64(tval)
+
+		}
+	}
+
+	state = fflib.FFParse_after_value
+	goto mainparse
+
+handle_ApiKeysFile:
+
+	/* handler: j.ApiKeysFile type=string kind=string quoted=false*/
+
+	{
+
+		{
+			if tok != fflib.FFTok_string && tok != fflib.FFTok_null {
+				return fs.WrapErr(fmt.Errorf("cannot unmarshal %s into Go value for string", tok))
+			}
+		}
+
+		if tok == fflib.FFTok_null {
+
+		} else {
+
+			outBuf := fs.Output.Bytes()
+
+			j.ApiKeysFile = string(string(outBuf))
+
+		}
+	}
+
+	state = fflib.FFParse_after_value
+	goto mainparse
+
+handle_RateLimitKey:
+
+	/* handler: j.RateLimitKey type=string kind=string quoted=false*/
+
+	{
+
+		{
+			if tok != fflib.FFTok_string && tok != fflib.FFTok_null {
+				return fs.WrapErr(fmt.Errorf("cannot unmarshal %s into Go value for string", tok))
+			}
+		}
+
+		if tok == fflib.FFTok_null {
+
+		} else {
+
+			outBuf := fs.Output.Bytes()
+
+			j.RateLimitKey = string(string(outBuf))
+
+		}
+	}
+
+	state = fflib.FFParse_after_value
+	goto mainparse
+
+handle_RateLimitRate:
+
+	/* handler: j.RateLimitRate type=float64 kind=float64 quoted=false*/
+
+	{
+		if tok != fflib.FFTok_double && tok != fflib.FFTok_integer && tok != fflib.FFTok_null {
+			return fs.WrapErr(fmt.Errorf("cannot unmarshal %s into Go value for float64", tok))
+		}
+	}
+
+	{
+
+		if tok == fflib.FFTok_null {
+
+		} else {
+
+			tval, err := fflib.ParseFloat(fs.Output.Bytes(), 64)
+
+			if err != nil {
+				return fs.WrapErr(err)
+			}
+
+			j.RateLimitRate = float64(tval)
+
+		}
+	}
+
+	state = fflib.FFParse_after_value
+	goto mainparse
+
+handle_RateLimitBurst:
+
+	/* handler: j.RateLimitBurst type=uint32 kind=uint32 quoted=false*/
+
+	{
+		if tok != fflib.FFTok_integer && tok != fflib.FFTok_null {
+			return fs.WrapErr(fmt.Errorf("cannot unmarshal %s into Go value for uint32", tok))
+		}
+	}
+
+	{
+
+		if tok == fflib.FFTok_null {
+
+		} else {
+
+			tval, err := fflib.ParseUint(fs.Output.Bytes(), 10, 32)
+
+			if err != nil {
+				return fs.WrapErr(err)
+			}
+
+			j.RateLimitBurst = uint32(tval)
+
+		}
+	}
+
+	state = fflib.FFParse_after_value
+	goto mainparse
+
+handle_CorsOrigins:
+
+	/* handler: j.CorsOrigins type=[]string kind=slice quoted=false*/
+
+	{
+
+		{
+			if tok != fflib.FFTok_left_brace && tok != fflib.FFTok_null {
+				return fs.WrapErr(fmt.Errorf("cannot unmarshal %s into Go value for ", tok))
+			}
+		}
+
+		if tok == fflib.FFTok_null {
+			j.CorsOrigins = nil
+		} else {
+
+			j.CorsOrigins = []string{}
+
+			wantVal := true
+
+			for {
+
+				var tmpJCorsOrigins string
+
+				tok = fs.Scan()
+				if tok == fflib.FFTok_error {
+					goto tokerror
+				}
+				if tok == fflib.FFTok_right_brace {
+					break
+				}
+
+				if tok == fflib.FFTok_comma {
+					if wantVal == true {
+						// TODO(pquerna): this isn't an ideal error message, this handles
+						// things like [,,,] as an array value.
+						return fs.WrapErr(fmt.Errorf("wanted value token, but got token: %v", tok))
+					}
+					continue
+				} else {
+					wantVal = true
+				}
+
+				/* handler: tmpJCorsOrigins type=string kind=string quoted=false*/
+
+				{
+
+					{
+						if tok != fflib.FFTok_string && tok != fflib.FFTok_null {
+							return fs.WrapErr(fmt.Errorf("cannot unmarshal %s into Go value for string", tok))
+						}
+					}
+
+					if tok == fflib.FFTok_null {
+
+					} else {
+
+						outBuf := fs.Output.Bytes()
+
+						tmpJCorsOrigins = string(string(outBuf))
+
+					}
+				}
+
+				j.CorsOrigins = append(j.CorsOrigins, tmpJCorsOrigins)
+
+				wantVal = false
+			}
+		}
+	}
+
+	state = fflib.FFParse_after_value
+	goto mainparse
+
+handle_CorsMethods:
+
+	/* handler: j.CorsMethods type=[]string kind=slice quoted=false*/
+
+	{
+
+		{
+			if tok != fflib.FFTok_left_brace && tok != fflib.FFTok_null {
+				return fs.WrapErr(fmt.Errorf("cannot unmarshal %s into Go value for ", tok))
+			}
+		}
+
+		if tok == fflib.FFTok_null {
+			j.CorsMethods = nil
+		} else {
+
+			j.CorsMethods = []string{}
+
+			wantVal := true
+
+			for {
+
+				var tmpJCorsMethods string
+
+				tok = fs.Scan()
+				if tok == fflib.FFTok_error {
+					goto tokerror
+				}
+				if tok == fflib.FFTok_right_brace {
+					break
+				}
+
+				if tok == fflib.FFTok_comma {
+					if wantVal == true {
+						// TODO(pquerna): this isn't an ideal error message, this handles
+						// things like [,,,] as an array value.
+						return fs.WrapErr(fmt.Errorf("wanted value token, but got token: %v", tok))
+					}
+					continue
+				} else {
+					wantVal = true
+				}
+
+				/* handler: tmpJCorsMethods type=string kind=string quoted=false*/
+
+				{
+
+					{
+						if tok != fflib.FFTok_string && tok != fflib.FFTok_null {
+							return fs.WrapErr(fmt.Errorf("cannot unmarshal %s into Go value for string", tok))
+						}
+					}
+
+					if tok == fflib.FFTok_null {
+
+					} else {
+
+						outBuf := fs.Output.Bytes()
+
+						tmpJCorsMethods = string(string(outBuf))
+
+					}
+				}
+
+				j.CorsMethods = append(j.CorsMethods, tmpJCorsMethods)
+
+				wantVal = false
+			}
+		}
+	}
+
+	state = fflib.FFParse_after_value
+	goto mainparse
+
+handle_CorsHeaders:
+
+	/* handler: j.CorsHeaders type=[]string kind=slice quoted=false*/
+
+	{
+
+		{
+			if tok != fflib.FFTok_left_brace && tok != fflib.FFTok_null {
+				return fs.WrapErr(fmt.Errorf("cannot unmarshal %s into Go value for ", tok))
+			}
+		}
+
+		if tok == fflib.FFTok_null {
+			j.CorsHeaders = nil
+		} else {
+
+			j.CorsHeaders = []string{}
+
+			wantVal := true
+
+			for {
+
+				var tmpJCorsHeaders string
+
+				tok = fs.Scan()
+				if tok == fflib.FFTok_error {
+					goto tokerror
+				}
+				if tok == fflib.FFTok_right_brace {
+					break
+				}
+
+				if tok == fflib.FFTok_comma {
+					if wantVal == true {
+						// TODO(pquerna): this isn't an ideal error message, this handles
+						// things like [,,,] as an array value.
+						return fs.WrapErr(fmt.Errorf("wanted value token, but got token: %v", tok))
+					}
+					continue
+				} else {
+					wantVal = true
+				}
+
+				/* handler: tmpJCorsHeaders type=string kind=string quoted=false*/
+
+				{
+
+					{
+						if tok != fflib.FFTok_string && tok != fflib.FFTok_null {
+							return fs.WrapErr(fmt.Errorf("cannot unmarshal %s into Go value for string", tok))
+						}
+					}
+
+					if tok == fflib.FFTok_null {
+
+					} else {
+
+						outBuf := fs.Output.Bytes()
+
+						tmpJCorsHeaders = string(string(outBuf))
+
+					}
+				}
+
+				j.CorsHeaders = append(j.CorsHeaders, tmpJCorsHeaders)
+
+				wantVal = false
+			}
+		}
+	}
+
+	state = fflib.FFParse_after_value
+	goto mainparse
+
+handle_CorsMaxAge:
+
+	/* handler: j.CorsMaxAge type=uint32 kind=uint32 quoted=false*/
+
+	{
+		if tok != fflib.FFTok_integer && tok != fflib.FFTok_null {
+			return fs.WrapErr(fmt.Errorf("cannot unmarshal %s into Go value for uint32", tok))
+		}
+	}
+
+	{
+
+		if tok == fflib.FFTok_null {
+
+		} else {
+
+			tval, err := fflib.ParseUint(fs.Output.Bytes(), 10, 32)
+
+			if err != nil {
+				return fs.WrapErr(err)
+			}
+
+			j.CorsMaxAge = uint32(tval)
+
+		}
+	}
+
+	state = fflib.FFParse_after_value
+	goto mainparse
+
+handle_AccessLogFormat:
+
+	/* handler: j.AccessLogFormat type=string kind=string quoted=false*/
+
+	{
+
+		{
+			if tok != fflib.FFTok_string && tok != fflib.FFTok_null {
+				return fs.WrapErr(fmt.Errorf("cannot unmarshal %s into Go value for string", tok))
+			}
+		}
+
+		if tok == fflib.FFTok_null {
+
+		} else {
+
+			outBuf := fs.Output.Bytes()
+
+			j.AccessLogFormat = string(string(outBuf))
+
+		}
+	}
+
+	state = fflib.FFParse_after_value
+	goto mainparse
+
+handle_AccessLogOutput:
+
+	/* handler: j.AccessLogOutput type=string kind=string quoted=false*/
+
+	{
+
+		{
+			if tok != fflib.FFTok_string && tok != fflib.FFTok_null {
+				return fs.WrapErr(fmt.Errorf("cannot unmarshal %s into Go value for string", tok))
+			}
+		}
+
+		if tok == fflib.FFTok_null {
+
+		} else {
+
+			outBuf := fs.Output.Bytes()
+
+			j.AccessLogOutput = string(string(outBuf))
+
+		}
+	}
+
+	state = fflib.FFParse_after_value
+	goto mainparse
+
+handle_LogLevel:
+
+	/* handler: j.LogLevel type=string kind=string quoted=false*/
+
+	{
+
+		{
+			if tok != fflib.FFTok_string && tok != fflib.FFTok_null {
+				return fs.WrapErr(fmt.Errorf("cannot unmarshal %s into Go value for string", tok))
+			}
+		}
+
+		if tok == fflib.FFTok_null {
+
+		} else {
+
+			outBuf := fs.Output.Bytes()
+
+			j.LogLevel = string(string(outBuf))
+
+		}
+	}
+
+	state = fflib.FFParse_after_value
+	goto mainparse
+
+handle_LogFormat:
+
+	/* handler: j.LogFormat type=string kind=string quoted=false*/
+
+	{
+
+		{
+			if tok != fflib.FFTok_string && tok != fflib.FFTok_null {
+				return fs.WrapErr(fmt.Errorf("cannot unmarshal %s into Go value for string", tok))
+			}
+		}
+
+		if tok == fflib.FFTok_null {
+
+		} else {
+
+			outBuf := fs.Output.Bytes()
+
+			j.LogFormat = string(string(outBuf))
+
+		}
+	}
+
+	state = fflib.FFParse_after_value
+	goto mainparse
+
 wantedvalue:
 	return fs.WrapErr(fmt.Errorf("wanted value token, but got token: %v", tok))
 wrongtokenerror:
@@ -0,0 +1,414 @@
+package mm
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/netip"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	geoip2 "github.com/oschwald/geoip2-golang"
+	"github.com/rabbitt/maxmind/mmdb"
+)
+
+// buildFixture writes the database described by the named file of
+// testdata into a temporary directory, returning its path.
+func buildFixture(t testing.TB, name string) string {
+	path := filepath.Join(t.TempDir(), name+".mmdb")
+	if err := mmdb.BuildFixture(filepath.Join("..", "testdata", name), path); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func openFixture(t testing.TB, name string) *Database {
+	db, err := OpenDatabase(buildFixture(t, name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(db.Close)
+	return db
+}
+
+func TestOpenDatabaseErrors(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "missing.mmdb")
+
+	var dbErr *DatabaseError
+	if _, err := OpenDatabase(path); !errors.As(err, &dbErr) || dbErr.Path != path {
+		t.Errorf("OpenDatabase(%s) = %v; want a *DatabaseError", path, err)
+	}
+	if _, err := GetDatabase(path); !errors.As(err, &dbErr) {
+		t.Errorf("GetDatabase(%s) = %v; want a *DatabaseError", path, err)
+	}
+}
+
+func TestGetDatabaseSharesInstances(t *testing.T) {
+	path := buildFixture(t, "GeoIP2-City-Test.json")
+	defer CloseDatabases()
+
+	first, err := GetDatabase(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := GetDatabase(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first != second {
+		t.Error("GetDatabase opened the same path twice")
+	}
+}
+
+func TestLookupIP(t *testing.T) {
+	db := openFixture(t, "GeoIP2-City-Test.json")
+	ctx := context.Background()
+
+	data, err := db.LookupIP(ctx, net.ParseIP("89.160.20.115"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := &GeoData{
+		City:      City{Name: "Linköping"},
+		Continent: Continent{Code: "EU", Name: "Europe"},
+		Country:   Country{IsInEuropeanUnion: true, IsoCode: "SE", Name: "Sweden"},
+		Location: Location{
+			AccuracyRadius: 76,
+			Latitude:       58.4167,
+			Longitude:      15.6167,
+			TimeZone:       "Europe/Stockholm",
+		},
+		Postal:            Postal{Code: "58211"},
+		RegisteredCountry: Country{IsInEuropeanUnion: true, IsoCode: "DE", Name: "Germany"},
+		Subdivisions: []Subdivision{
+			{IsoCode: "E", Name: "Östergötland County"},
+			{IsoCode: "LI", Name: "Linköping Municipality"},
+		},
+		Subdivision: Subdivision{IsoCode: "LI", Name: "Linköping Municipality"},
+	}
+	if !reflect.DeepEqual(data, want) {
+		t.Errorf("got %+v\nwant %+v", data, want)
+	}
+
+	data, err = db.LookupIP(ctx, net.ParseIP("67.43.156.1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data.RepresentedCountry.Type != "military" || !data.Traits.IsAnonymousProxy || data.Location.AccuracyRadius != 534 {
+		t.Errorf("unexpected geodata %+v", data)
+	}
+
+	data, err = db.LookupIP(ctx, net.ParseIP("216.160.83.60"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data.Location.MetroCode != 819 || data.Subdivision.IsoCode != "WA" {
+		t.Errorf("unexpected geodata %+v", data)
+	}
+}
+
+func TestLookupIPNotFound(t *testing.T) {
+	db := openFixture(t, "GeoIP2-City-Test.json")
+
+	var notFound *NotFoundError
+	if _, err := db.LookupIP(context.Background(), net.ParseIP("10.0.0.1")); !errors.As(err, &notFound) {
+		t.Errorf("LookupIP(10.0.0.1) = %v; want a *NotFoundError", err)
+	}
+
+	var invalid *InvalidIpError
+	if _, err := db.LookupIP(context.Background(), nil); !errors.As(err, &invalid) {
+		t.Errorf("LookupIP(nil) = %v; want an *InvalidIpError", err)
+	}
+}
+
+func TestLookupIPCancelled(t *testing.T) {
+	db := openFixture(t, "GeoIP2-City-Test.json")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := db.LookupIP(ctx, net.ParseIP("81.2.69.142")); !errors.Is(err, context.Canceled) {
+		t.Errorf("LookupIP = %v; want context.Canceled", err)
+	}
+	if _, err := db.LookupIPs(ctx, []net.IP{net.ParseIP("81.2.69.142")}); !errors.Is(err, context.Canceled) {
+		t.Errorf("LookupIPs = %v; want context.Canceled", err)
+	}
+}
+
+func TestLookupAddr(t *testing.T) {
+	db := openFixture(t, "GeoIP2-City-Test.json")
+	ctx := context.Background()
+
+	for _, text := range []string{"175.16.199.1", "::ffff:175.16.199.1"} {
+		data, err := db.LookupAddr(ctx, netip.MustParseAddr(text))
+		if err != nil {
+			t.Fatalf("LookupAddr(%s): %s", text, err)
+		}
+		if data.City.Name != "Changchun" {
+			t.Errorf("LookupAddr(%s) = %+v", text, data)
+		}
+	}
+
+	data, err := db.LookupAddr(ctx, netip.MustParseAddr("2001:218::1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data.Country.IsoCode != "JP" {
+		t.Errorf("LookupAddr(2001:218::1) = %+v", data)
+	}
+
+	var invalid *InvalidIpError
+	if _, err = db.LookupAddr(ctx, netip.Addr{}); !errors.As(err, &invalid) {
+		t.Errorf("LookupAddr of the zero Addr = %v; want an *InvalidIpError", err)
+	}
+}
+
+func TestLookupIPs(t *testing.T) {
+	db := openFixture(t, "GeoIP2-City-Test.json")
+
+	records, err := db.LookupIPs(context.Background(), []net.IP{
+		net.ParseIP("81.2.69.142"),
+		net.ParseIP("10.0.0.1"),
+		nil,
+		net.ParseIP("2001:218::1"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(records) != 4 || records[0].City.Name != "London" || records[1] != nil || records[2] != nil ||
+		records[3].Country.IsoCode != "JP" {
+		t.Errorf("unexpected records %+v", records)
+	}
+}
+
+func TestLookup(t *testing.T) {
+	db := openFixture(t, "GeoIP2-City-Test.json")
+
+	data, err := db.Lookup("81.2.69.143")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data.City.Name != "London" || data.Subdivision.IsoCode != "ENG" {
+		t.Errorf("Lookup(81.2.69.143) = %+v", data)
+	}
+
+	data, err = db.Lookup("10.0.0.1")
+	if err != nil || !data.Unknown() {
+		t.Errorf("Lookup(10.0.0.1) = %+v, %v; want empty geodata", data, err)
+	}
+
+	var invalid *InvalidIpError
+	if _, err = db.Lookup("not-an-ip"); !errors.As(err, &invalid) || invalid.Ip != "not-an-ip" {
+		t.Errorf("Lookup(not-an-ip) = %v; want an *InvalidIpError", err)
+	}
+}
+
+func TestLookupGeoDataMatchesGeoIp2(t *testing.T) {
+	db := openFixture(t, "GeoIP2-City-Test.json")
+	ctx := context.Background()
+
+	var data GeoData
+	for _, text := range []string{"81.2.69.142", "89.160.20.112", "175.16.199.255", "216.160.83.56", "2001:218::", "67.43.156.0"} {
+		ip := net.ParseIP(text)
+
+		var record geoip2.City
+		if err := db.decode(ip, &record); err != nil {
+			t.Fatal(err)
+		}
+
+		// data is reused across lookups, as callers in a loop would
+		if err := db.LookupGeoData(ctx, ip, &data); err != nil {
+			t.Fatal(err)
+		}
+		if want := NewFromGeoIp2City(&record); !reflect.DeepEqual(&data, want) {
+			t.Errorf("%s: got %+v\nwant %+v", text, data, want)
+		}
+	}
+}
+
+func TestLookupInto(t *testing.T) {
+	db := openFixture(t, "GeoIP2-City-Test.json")
+
+	var record struct {
+		Country struct {
+			Names map[string]string `maxminddb:"names"`
+		} `maxminddb:"country"`
+	}
+	if err := db.LookupInto(context.Background(), net.ParseIP("81.2.69.142"), &record); err != nil {
+		t.Fatal(err)
+	}
+	if record.Country.Names["de"] != "Vereinigtes Königreich" {
+		t.Errorf("unexpected record %+v", record)
+	}
+}
+
+func TestLookupAsn(t *testing.T) {
+	db := openFixture(t, "GeoLite2-ASN-Test.csv")
+
+	number, organization, err := db.LookupAsn("12.81.92.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if number != 7018 || organization != "AT&T Services" {
+		t.Errorf("LookupAsn(12.81.92.1) = %d, %s", number, organization)
+	}
+
+	if number, _, err = db.LookupAsn("10.0.0.1"); err != nil || number != 0 {
+		t.Errorf("LookupAsn(10.0.0.1) = %d, %v; want 0", number, err)
+	}
+}
+
+func TestInfo(t *testing.T) {
+	db := openFixture(t, "GeoIP2-City-Test.json")
+
+	info := db.Metadata()
+	if info.Type != "GeoIP2-City" || info.IpVersion != 6 || info.RecordSize != 24 ||
+		!info.BuildTime.Equal(time.Unix(1524254400, 0)) || !reflect.DeepEqual(info.Languages, []string{"en", "de"}) {
+		t.Errorf("unexpected info %+v", info)
+	}
+	if !db.BuildTime().Equal(info.BuildTime) {
+		t.Errorf("BuildTime() = %s; want %s", db.BuildTime(), info.BuildTime)
+	}
+}
+
+func TestReload(t *testing.T) {
+	path := buildFixture(t, "GeoIP2-City-Test.json")
+	db, err := OpenDatabase(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	w, _ := mmdb.NewWriter("GeoIP2-City", 6)
+	_, network, _ := net.ParseCIDR("10.0.0.0/8")
+	w.Insert(network, map[string]interface{}{"city": map[string]interface{}{"names": map[string]string{"en": "Internal"}}})
+	if err = w.WriteFile(path); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = db.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := db.Lookup("10.1.2.3"); data.City.Name != "Internal" {
+		t.Errorf("after reload, Lookup(10.1.2.3) = %+v", data)
+	}
+
+	db.path = filepath.Join(t.TempDir(), "missing.mmdb")
+	var dbErr *DatabaseError
+	if err = db.Reload(); !errors.As(err, &dbErr) {
+		t.Errorf("Reload = %v; want a *DatabaseError", err)
+	}
+	if data, _ := db.Lookup("10.1.2.3"); data.City.Name != "Internal" {
+		t.Error("a failed reload replaced the database")
+	}
+}
+
+func TestDatabaseNetworks(t *testing.T) {
+	db := openFixture(t, "GeoIP2-City-Test.json")
+
+	cities := map[string]string{}
+	err := db.Networks(func(network *net.IPNet, data *GeoData) error {
+		cities[network.String()] = data.City.Name
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the fixture's IPv4 networks are aliased, but listed once
+	want := map[string]string{
+		"67.43.156.0/24":   "",
+		"81.2.69.142/31":   "London",
+		"89.160.20.112/28": "Linköping",
+		"175.16.199.0/24":  "Changchun",
+		"216.160.83.56/29": "Milton",
+		"2001:218::/32":    "",
+	}
+	if !reflect.DeepEqual(cities, want) {
+		t.Errorf("got networks %v\nwant %v", cities, want)
+	}
+
+	stop := errors.New("stop")
+	count := 0
+	err = db.Networks(func(network *net.IPNet, data *GeoData) error {
+		count++
+		return stop
+	})
+	if err != stop || count != 1 {
+		t.Errorf("Networks = %v after %d networks; want it to stop at the first", err, count)
+	}
+}
+
+func TestDatabaseNetworksDuringReload(t *testing.T) {
+	db := openFixture(t, "GeoIP2-City-Test.json")
+	snapshot := db.Snapshot()
+	defer snapshot.Close()
+
+	w, _ := mmdb.NewWriter("GeoIP2-City", 6)
+	_, network, _ := net.ParseCIDR("10.0.0.0/8")
+	w.Insert(network, map[string]interface{}{"city": map[string]interface{}{"names": map[string]string{"en": "Internal"}}})
+	db.path = filepath.Join(t.TempDir(), "internal.mmdb")
+	if err := w.WriteFile(db.path); err != nil {
+		t.Fatal(err)
+	}
+
+	// the walk neither holds up a reload, nor sees it
+	count := 0
+	err := db.Networks(func(network *net.IPNet, data *GeoData) error {
+		if count++; count == 1 {
+			if err := db.Reload(); err != nil {
+				return err
+			}
+			if data, err := db.Lookup("10.1.2.3"); err != nil || data.City.Name != "Internal" {
+				t.Errorf("after reload, Lookup(10.1.2.3) = %+v, %v", data, err)
+			}
+		}
+		return nil
+	})
+	if err != nil || count != 6 {
+		t.Errorf("Networks = %v after %d networks; want 6", err, count)
+	}
+
+	// nor do snapshots, which stay open until they are closed
+	if data, err := snapshot.Lookup("81.2.69.142"); err != nil || data.City.Name != "London" {
+		t.Errorf("snapshot Lookup(81.2.69.142) = %+v, %v", data, err)
+	}
+	db.Close()
+	if data, err := snapshot.Lookup("81.2.69.142"); err != nil || data.City.Name != "London" {
+		t.Errorf("after close, snapshot Lookup(81.2.69.142) = %+v, %v", data, err)
+	}
+}
+
+func TestDatabaseNetworksWithin(t *testing.T) {
+	db := openFixture(t, "GeoIP2-City-Test.json")
+
+	for _, test := range []struct {
+		limits []string
+		want   []string
+	}{
+		{nil, nil},
+		{[]string{"81.2.69.0/24"}, []string{"81.2.69.142/31"}},
+		{[]string{"2001:218:1::/48", "81.2.69.143/32", "81.0.0.0/8"}, []string{"81.2.69.142/31", "2001:218:1::/48"}},
+		{[]string{"89.160.20.112/30", "89.160.20.120/30"}, []string{"89.160.20.112/30", "89.160.20.120/30"}},
+		{[]string{"10.0.0.0/8", "::ffff:0:0/96"}, nil},
+	} {
+		var limits []*net.IPNet
+		for _, limit := range test.limits {
+			_, network, _ := net.ParseCIDR(limit)
+			limits = append(limits, network)
+		}
+
+		var got []string
+		err := db.NetworksWithin(limits, func(network *net.IPNet, data *GeoData) error {
+			got = append(got, network.String())
+			return nil
+		})
+		if err != nil || !reflect.DeepEqual(got, test.want) {
+			t.Errorf("NetworksWithin(%v) = %q, %v; want %q", test.limits, got, err, test.want)
+		}
+	}
+}
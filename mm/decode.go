@@ -0,0 +1,153 @@
+package mm
+
+import (
+	"context"
+	"net"
+	"sync"
+)
+
+// englishNames decodes only the English entry of a record's names, rather
+// than a map of every language.
+type englishNames struct {
+	En string `maxminddb:"en"`
+}
+
+type countryRecord struct {
+	IsInEuropeanUnion bool         `maxminddb:"is_in_european_union"`
+	IsoCode           string       `maxminddb:"iso_code"`
+	Names             englishNames `maxminddb:"names"`
+}
+
+type subdivisionRecord struct {
+	IsoCode string       `maxminddb:"iso_code"`
+	Names   englishNames `maxminddb:"names"`
+}
+
+// cityRecord mirrors the parts of a GeoIP2 City record that GeoData
+// carries, so that decoding skips everything else.
+//
+// ffjson: skip
+type cityRecord struct {
+	City struct {
+		Names englishNames `maxminddb:"names"`
+	} `maxminddb:"city"`
+	Continent struct {
+		Code  string       `maxminddb:"code"`
+		Names englishNames `maxminddb:"names"`
+	} `maxminddb:"continent"`
+	Country  countryRecord `maxminddb:"country"`
+	Location struct {
+		AccuracyRadius uint16  `maxminddb:"accuracy_radius"`
+		Latitude       float64 `maxminddb:"latitude"`
+		Longitude      float64 `maxminddb:"longitude"`
+		MetroCode      uint    `maxminddb:"metro_code"`
+		TimeZone       string  `maxminddb:"time_zone"`
+	} `maxminddb:"location"`
+	Postal struct {
+		Code string `maxminddb:"code"`
+	} `maxminddb:"postal"`
+	RegisteredCountry  countryRecord `maxminddb:"registered_country"`
+	RepresentedCountry struct {
+		IsInEuropeanUnion bool         `maxminddb:"is_in_european_union"`
+		IsoCode           string       `maxminddb:"iso_code"`
+		Names             englishNames `maxminddb:"names"`
+		Type              string       `maxminddb:"type"`
+	} `maxminddb:"represented_country"`
+	Subdivisions []subdivisionRecord `maxminddb:"subdivisions"`
+	Traits       struct {
+		IsAnonymousProxy    bool `maxminddb:"is_anonymous_proxy"`
+		IsSatelliteProvider bool `maxminddb:"is_satellite_provider"`
+	} `maxminddb:"traits"`
+}
+
+var cityRecords = sync.Pool{
+	New: func() interface{} { return new(cityRecord) },
+}
+
+// copyTo fills data from the record, reusing data's subdivisions.
+func (r *cityRecord) copyTo(data *GeoData) {
+	subdivisions := data.Subdivisions[:0]
+	for _, sub := range r.Subdivisions {
+		subdivisions = append(subdivisions, Subdivision{IsoCode: sub.IsoCode, Name: sub.Names.En})
+	}
+	if len(subdivisions) == 0 {
+		subdivisions = nil
+	}
+
+	*data = GeoData{
+		City:      City{Name: r.City.Names.En},
+		Continent: Continent{Code: r.Continent.Code, Name: r.Continent.Names.En},
+		Country: Country{
+			IsInEuropeanUnion: r.Country.IsInEuropeanUnion,
+			IsoCode:           r.Country.IsoCode,
+			Name:              r.Country.Names.En,
+		},
+		Location: Location{
+			AccuracyRadius: r.Location.AccuracyRadius,
+			Latitude:       r.Location.Latitude,
+			Longitude:      r.Location.Longitude,
+			MetroCode:      r.Location.MetroCode,
+			TimeZone:       r.Location.TimeZone,
+		},
+		Postal: Postal{Code: r.Postal.Code},
+		RegisteredCountry: Country{
+			IsInEuropeanUnion: r.RegisteredCountry.IsInEuropeanUnion,
+			IsoCode:           r.RegisteredCountry.IsoCode,
+			Name:              r.RegisteredCountry.Names.En,
+		},
+		RepresentedCountry: RepresentedCountry{
+			IsInEuropeanUnion: r.RepresentedCountry.IsInEuropeanUnion,
+			IsoCode:           r.RepresentedCountry.IsoCode,
+			Name:              r.RepresentedCountry.Names.En,
+			Type:              r.RepresentedCountry.Type,
+		},
+		Subdivisions: subdivisions,
+		Traits: Traits{
+			IsAnonymousProxy:    r.Traits.IsAnonymousProxy,
+			IsSatelliteProvider: r.Traits.IsSatelliteProvider,
+		},
+	}
+
+	if len(subdivisions) > 0 {
+		data.Subdivision = subdivisions[len(subdivisions)-1]
+	}
+}
+
+// LookupGeoData decodes the geodata for ip into data, decoding only the
+// fields GeoData carries, and only their English names. Reusing data
+// across lookups reuses its subdivisions, so that only the strings of the
+// record are allocated.
+func (db *Database) LookupGeoData(ctx context.Context, ip net.IP, data *GeoData) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if ip == nil {
+		return &InvalidIpError{}
+	}
+
+	record := cityRecords.Get().(*cityRecord)
+	defer cityRecords.Put(record)
+
+	*record = cityRecord{}
+
+	if err := db.decode(ip, record); err != nil {
+		return err
+	}
+
+	record.copyTo(data)
+	return nil
+}
+
+// LookupInto decodes the record for ip into result, a pointer to a struct
+// whose fields are tagged with the `maxminddb` keys they are decoded from.
+// Keys without a matching field are skipped, so a struct holding only the
+// fields a caller needs is the cheapest way to look an ip up.
+func (db *Database) LookupInto(ctx context.Context, ip net.IP, result interface{}) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if ip == nil {
+		return &InvalidIpError{}
+	}
+	return db.decode(ip, result)
+}
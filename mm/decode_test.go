@@ -0,0 +1,105 @@
+package mm
+
+import (
+	"context"
+	"net"
+	"os"
+	"testing"
+
+	geoip2 "github.com/oschwald/geoip2-golang"
+)
+
+var benchmarkIps = []net.IP{
+	net.ParseIP("8.8.8.8"),
+	net.ParseIP("123.123.123.123"),
+	net.ParseIP("81.2.69.142"),
+	net.ParseIP("2001:4860:4860::8888"),
+}
+
+// benchmarkDatabase opens the City database named by $MAXMIND_DATABASE,
+// or one built from the test fixture when it is unset.
+func benchmarkDatabase(b *testing.B) *Database {
+	path := os.Getenv("MAXMIND_DATABASE")
+	if path == "" {
+		path = buildFixture(b, "GeoIP2-City-Test.json")
+	}
+
+	db, err := OpenDatabase(path)
+	if err != nil {
+		b.Fatal(err)
+	}
+	return db
+}
+
+func BenchmarkNewFromGeoIp2City(b *testing.B) {
+	db := benchmarkDatabase(b)
+	defer db.Close()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var record geoip2.City
+		if err := db.decode(benchmarkIps[i%len(benchmarkIps)], &record); err != nil {
+			if _, notFound := err.(*NotFoundError); !notFound {
+				b.Fatal(err)
+			}
+		}
+		NewFromGeoIp2City(&record)
+	}
+}
+
+func BenchmarkLookupIP(b *testing.B) {
+	db := benchmarkDatabase(b)
+	defer db.Close()
+
+	ctx := context.Background()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := db.LookupIP(ctx, benchmarkIps[i%len(benchmarkIps)]); err != nil {
+			if _, notFound := err.(*NotFoundError); !notFound {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+func BenchmarkLookupGeoData(b *testing.B) {
+	db := benchmarkDatabase(b)
+	defer db.Close()
+
+	ctx := context.Background()
+	var data GeoData
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := db.LookupGeoData(ctx, benchmarkIps[i%len(benchmarkIps)], &data); err != nil {
+			if _, notFound := err.(*NotFoundError); !notFound {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+func BenchmarkLookupInto(b *testing.B) {
+	db := benchmarkDatabase(b)
+	defer db.Close()
+
+	var record struct {
+		Location struct {
+			Latitude  float64 `maxminddb:"latitude"`
+			Longitude float64 `maxminddb:"longitude"`
+		} `maxminddb:"location"`
+	}
+
+	ctx := context.Background()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := db.LookupInto(ctx, benchmarkIps[i%len(benchmarkIps)], &record); err != nil {
+			if _, notFound := err.(*NotFoundError); !notFound {
+				b.Fatal(err)
+			}
+		}
+	}
+}
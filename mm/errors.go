@@ -0,0 +1,77 @@
+package mm
+
+import (
+	"fmt"
+	"net"
+)
+
+// DatabaseError reports a database that could not be opened or read.
+//
+// ffjson: skip
+type DatabaseError struct {
+	Path string
+	Err  error
+}
+
+func (e *DatabaseError) Error() string {
+	return fmt.Sprintf("unable to open database %s: %s", e.Path, e.Err)
+}
+
+// PathError reports a path that is empty, does not exist or cannot be
+// resolved.
+//
+// ffjson: skip
+type PathError struct {
+	Path string
+	Err  error
+}
+
+func (e *PathError) Error() string {
+	if e.Path == "" {
+		return e.Err.Error()
+	}
+	return fmt.Sprintf("invalid path %s: %s", e.Path, e.Err)
+}
+
+// ConfigError reports a configuration file that could not be loaded, or a
+// configuration value that is not valid. Key names the offending setting,
+// and File the configuration file, when known.
+//
+// ffjson: skip
+type ConfigError struct {
+	File string
+	Key  string
+	Err  error
+}
+
+func (e *ConfigError) Error() string {
+	switch {
+	case e.Key != "":
+		return fmt.Sprintf("invalid configuration for %s: %s", e.Key, e.Err)
+	case e.File != "":
+		return fmt.Sprintf("unable to load configuration from %s: %s", e.File, e.Err)
+	}
+	return fmt.Sprintf("invalid configuration: %s", e.Err)
+}
+
+// InvalidIpError reports an ip that could not be parsed.
+//
+// ffjson: skip
+type InvalidIpError struct {
+	Ip string
+}
+
+func (e *InvalidIpError) Error() string {
+	return fmt.Sprintf("unable to decode ip `%s`", e.Ip)
+}
+
+// NotFoundError reports an ip the database has no record of.
+//
+// ffjson: skip
+type NotFoundError struct {
+	Ip net.IP
+}
+
+func (e *NotFoundError) Error() string {
+	return fmt.Sprintf("no record found for %s", e.Ip)
+}
@@ -1,6 +1,7 @@
 package mm
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io/ioutil"
@@ -33,26 +34,42 @@ func (f *Pathname) String() string {
 
 func (f *Pathname) Set(value string) error {
 	if value == "" {
-		return errors.New("invalid path; path empty!")
+		return &PathError{Err: errors.New("invalid path; path empty!")}
 	} else {
 		*f = Pathname{path: value}
 	}
 	return nil
 }
 
+// UnmarshalJSON sets the path from a JSON string, so that Pathnames can be
+// read from configuration files.
+func (f *Pathname) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	return f.Set(value)
+}
+
+// RealPath resolves the path to an absolute path with no symlinks,
+// reporting failures as a *PathError.
 func (f *Pathname) RealPath() (*Pathname, error) {
 	var err error
 	var fpath string
 
+	if f.path == "" {
+		return f, &PathError{Err: errors.New("invalid path; path empty!")}
+	}
+
 	// normalize the database file path
 	if fpath, err = realpath.Realpath(f.path); err != nil {
 		if !f.Exists() {
 			err = errors.New(fmt.Sprintf("%s path does not exist", f.path))
 		}
-		return f, err
+		return f, &PathError{Path: f.path, Err: err}
 	}
 
-	return NewPathname(fpath), err
+	return NewPathname(fpath), nil
 }
 
 func (f *Pathname) Join(paths ...interface{}) *Pathname {
@@ -1,13 +1,15 @@
 package mm
 
 import (
+	"context"
 	"errors"
-	"fmt"
-	"log"
 	"net"
+	"net/netip"
 	"sync"
+	"time"
 
 	geoip2 "github.com/oschwald/geoip2-golang"
+	maxminddb "github.com/oschwald/maxminddb-golang"
 )
 
 //go:generate ffjson --nodecoder $GOFILE
@@ -73,6 +75,10 @@ type JsonResponse struct {
 	Status  string   `json:"status"`
 	Message string   `json:"message"`
 	Data    *GeoData `json:"data"`
+
+	// Origins names the database each field of Data came from, by its
+	// dotted path, when overlays are configured.
+	Origins map[string]string `json:"origins,omitempty"`
 }
 
 func NewFromGeoIp2City(record *geoip2.City) *GeoData {
@@ -149,47 +155,268 @@ func (gd *GeoData) Unknown() bool {
 	return false
 }
 
+// sharedReader counts the references to a reader: the database it is the
+// reader of, its snapshots, and the walks in progress. The reader is closed
+// when the last of them is released, so that a reload never closes it
+// under a walk.
+type sharedReader struct {
+	reader *maxminddb.Reader
+	lock   sync.Mutex
+	refs   int
+}
+
+func (r *sharedReader) acquire() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.refs++
+}
+
+func (r *sharedReader) release() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.refs--; r.refs == 0 {
+		r.reader.Close()
+	}
+}
+
 // ffjson: skip
 type Database struct {
-	Reader *geoip2.Reader
+	Reader *maxminddb.Reader
+	path   string
+	lock   sync.RWMutex
+
+	// the references to Reader
+	shared *sharedReader
+
+	// compiled databases are compiled from CSV, by CompileDatabase, when
+	// they are opened and reloaded
+	compiled bool
 }
 
 var dbInstances map[string]*Database = map[string]*Database{}
 
-var once sync.Once
+var dbInstancesLock sync.Mutex
 
+// GetDatabase returns the shared instance of the database at path, opening
+// it on first use; CSV files and directories are compiled, as by
+// CompileDatabase. Failures are reported as a *DatabaseError, and are not
+// remembered, so a later call may succeed.
 func GetDatabase(path string) (*Database, error) {
-	once.Do(func() {
-		database, err := geoip2.Open(path)
-		if err != nil {
-			log.Fatal(err)
-		}
-		dbInstances[path] = &Database{Reader: database}
-	})
-	return dbInstances[path], nil
+	dbInstancesLock.Lock()
+	defer dbInstancesLock.Unlock()
+
+	if database, found := dbInstances[path]; found {
+		return database, nil
+	}
+
+	open := OpenDatabase
+	if IsCsv(path) {
+		open = CompileDatabase
+	}
+	database, err := open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	dbInstances[path] = database
+	return database, nil
+}
+
+// errCsvEdition reports a directory holding the CSV edition of a database
+// given where a MaxMind database file is expected.
+var errCsvEdition = errors.New("is the CSV edition of a database; build it into a MaxMind database, or use the csv backend")
+
+// openReader opens the database at path, compiling it from CSV when it is
+// a compiled database.
+func (db *Database) openReader() (*maxminddb.Reader, error) {
+	if db.compiled {
+		return compileReader(db.path)
+	}
+	if IsCsvDatabase(db.path) {
+		return nil, errCsvEdition
+	}
+	return maxminddb.Open(db.path)
+}
+
+// OpenDatabase opens the MaxMind database file at path without registering
+// it as a shared instance; the caller is responsible for closing it.
+func OpenDatabase(path string) (*Database, error) {
+	db := &Database{path: path}
+	reader, err := db.openReader()
+	if err != nil {
+		return nil, &DatabaseError{Path: path, Err: err}
+	}
+	db.setReader(reader)
+	return db, nil
+}
+
+// setReader makes reader the reader of the database, holding a reference
+// to it; the caller holds the lock, or has yet to share the database.
+func (db *Database) setReader(reader *maxminddb.Reader) {
+	db.Reader = reader
+	db.shared = &sharedReader{reader: reader, refs: 1}
+}
+
+// acquire returns the reader of the database, and a func releasing it; the
+// reader stays open, however the database is reloaded or closed, until it
+// is released.
+func (db *Database) acquire() (*maxminddb.Reader, func()) {
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+
+	shared := db.shared
+	shared.acquire()
+	return shared.reader, shared.release
+}
+
+// Snapshot returns a database reading from the reader db has now, which
+// stays open, however db is reloaded, until the snapshot is closed. Walks
+// and lookups in a snapshot agree with each other, and never wait on a
+// reload of db.
+func (db *Database) Snapshot() *Database {
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+
+	db.shared.acquire()
+	return &Database{Reader: db.Reader, path: db.path, compiled: db.compiled, shared: db.shared}
 }
 
 func CloseDatabases() {
-	// Not really thread safe, but doesn't really matter in this app
-	for _, db := range dbInstances {
+	dbInstancesLock.Lock()
+	defer dbInstancesLock.Unlock()
+
+	for path, db := range dbInstances {
 		db.Close()
+		delete(dbInstances, path)
 	}
 }
 
+// Close releases the reader of the database, which is closed once the
+// walks, and snapshots, still using it are done.
 func (db *Database) Close() {
-	db.Reader.Close()
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	if db.shared != nil {
+		db.shared.release()
+		db.shared = nil
+	}
+}
+
+// Reload reopens the database file, swapping in the new reader once it has
+// been opened successfully so that in-flight lookups are never interrupted;
+// the old reader is closed once the walks using it are done.
+func (db *Database) Reload() error {
+	swap, _, err := db.stageReload()
+	if err != nil {
+		return err
+	}
+	swap()
+	return nil
+}
+
+func (db *Database) stageReload() (func(), func(), error) {
+	reader, err := db.openReader()
+	if err != nil {
+		return nil, nil, &DatabaseError{Path: db.path, Err: err}
+	}
+
+	swap := func() {
+		db.lock.Lock()
+		defer db.lock.Unlock()
+
+		if db.shared != nil {
+			db.shared.release()
+		}
+		db.setReader(reader)
+	}
+	return swap, func() { reader.Close() }, nil
+}
+
+// BuildTime returns when the database was built, according to its metadata.
+func (db *Database) BuildTime() time.Time {
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+
+	return time.Unix(int64(db.Reader.Metadata.BuildEpoch), 0)
 }
 
+// decode finds the record for ip and decodes it into result, reporting a
+// *NotFoundError when the database has no record of ip.
+func (db *Database) decode(ip net.IP, result interface{}) error {
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+
+	offset, err := db.Reader.LookupOffset(ip)
+	if err != nil {
+		return err
+	}
+	if offset == maxminddb.NotFound {
+		return &NotFoundError{Ip: ip}
+	}
+
+	return db.Reader.Decode(offset, result)
+}
+
+// LookupIP returns the geodata for ip, or a *NotFoundError if the database
+// has no record of it.
+func (db *Database) LookupIP(ctx context.Context, ip net.IP) (*GeoData, error) {
+	data := &GeoData{}
+	if err := db.LookupGeoData(ctx, ip, data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// LookupAddr is LookupIP for a netip.Addr.
+func (db *Database) LookupAddr(ctx context.Context, addr netip.Addr) (*GeoData, error) {
+	if !addr.IsValid() {
+		return nil, &InvalidIpError{}
+	}
+	return db.LookupIP(ctx, net.IP(addr.Unmap().AsSlice()))
+}
+
+// LookupIPs looks up each of ips, in order, returning their geodata in the
+// same order. Ips the database has no record of have a nil entry. It stops
+// early, returning the context's error, if ctx is cancelled.
+func (db *Database) LookupIPs(ctx context.Context, ips []net.IP) ([]*GeoData, error) {
+	records := make([]*GeoData, len(ips))
+	for idx, ip := range ips {
+		record, err := db.LookupIP(ctx, ip)
+		switch err.(type) {
+		case nil:
+			records[idx] = record
+		case *NotFoundError, *InvalidIpError:
+		default:
+			return nil, err
+		}
+	}
+	return records, nil
+}
+
+// Lookup parses ipText and returns its geodata, which is empty when the
+// database has no record of it.
 func (db *Database) Lookup(ipText string) (*GeoData, error) {
+	return LookupText(db, ipText)
+}
+
+// LookupAsn returns the autonomous system number and organization for
+// ipText. The database must be an ASN database, such as GeoLite2-ASN.
+func (db *Database) LookupAsn(ipText string) (uint, string, error) {
 	ip := net.ParseIP(ipText)
 	if ip == nil {
-		return nil, errors.New(fmt.Sprintf("unable to decode ip `%s`", ipText))
+		return 0, "", &InvalidIpError{Ip: ipText}
 	}
 
-	record, err := db.Reader.City(ip)
-	if err != nil {
-		return nil, err
+	var record geoip2.ASN
+	if err := db.decode(ip, &record); err != nil {
+		if _, notFound := err.(*NotFoundError); notFound {
+			return 0, "", nil
+		}
+		return 0, "", err
 	}
 
-	return NewFromGeoIp2City(record), nil
+	return record.AutonomousSystemNumber, record.AutonomousSystemOrganization, nil
 }
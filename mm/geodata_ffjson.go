@@ -343,6 +343,23 @@ func (j *JsonResponse) MarshalJSONBuf(buf fflib.EncodingBuffer) error {
 	} else {
 		buf.WriteString(`,"data":null`)
 	}
+	if len(j.Origins) != 0 {
+		buf.WriteString(`,"origins":`)
+		/* Falling back. type=map[string]string kind=map */
+		if j.Origins == nil {
+			buf.WriteString(`null`)
+		} else {
+			buf.WriteString(`{ `)
+			for key, value := range j.Origins {
+				fflib.WriteJsonString(buf, key)
+				buf.WriteString(`:`)
+				fflib.WriteJsonString(buf, string(value))
+				buf.WriteByte(',')
+			}
+			buf.Rewind(1)
+			buf.WriteByte('}')
+		}
+	}
 	buf.WriteByte('}')
 	return nil
 }
@@ -0,0 +1,84 @@
+package mm
+
+const GeoJsonMimeType = "application/geo+json"
+
+// ffjson: skip
+type Geometry struct {
+	Type        string    `json:"type"`
+	Coordinates []float64 `json:"coordinates"`
+}
+
+// ffjson: skip
+type FeatureProperties struct {
+	Ip                 string             `json:"ip"`
+	AccuracyRadius     uint16             `json:"accuracy_radius,omitempty"`
+	MetroCode          uint               `json:"metro_code,omitempty"`
+	TimeZone           string             `json:"time_zone,omitempty"`
+	City               City               `json:"city,omitempty"`
+	Continent          Continent          `json:"continent,omitempty"`
+	Country            Country            `json:"country,omitempty"`
+	Postal             Postal             `json:"postal,omitempty"`
+	RegisteredCountry  Country            `json:"registered_country,omitempty"`
+	RepresentedCountry RepresentedCountry `json:"represented_country,omitempty"`
+	Subdivisions       []Subdivision      `json:"subdivisions,omitempty"`
+	Subdivision        Subdivision        `json:"subdivision,omitempty"`
+	Traits             Traits             `json:"traits,omitempty"`
+}
+
+// ffjson: skip
+type Feature struct {
+	Type       string            `json:"type"`
+	Geometry   *Geometry         `json:"geometry"`
+	Properties FeatureProperties `json:"properties"`
+}
+
+// ffjson: skip
+type FeatureCollection struct {
+	Type     string     `json:"type"`
+	Features []*Feature `json:"features"`
+}
+
+// NewFeature converts the record for ipText into a GeoJSON Feature. The
+// geometry is left null when the record carries no location, as allowed
+// by RFC 7946.
+func NewFeature(ipText string, record *GeoData) *Feature {
+	var geometry *Geometry
+
+	if record.Location.AccuracyRadius != 0 || record.Location.Latitude != 0 || record.Location.Longitude != 0 {
+		// GeoJSON positions are longitude first
+		geometry = &Geometry{
+			Type:        "Point",
+			Coordinates: []float64{record.Location.Longitude, record.Location.Latitude},
+		}
+	}
+
+	return &Feature{
+		Type:     "Feature",
+		Geometry: geometry,
+		Properties: FeatureProperties{
+			Ip:                 ipText,
+			AccuracyRadius:     record.Location.AccuracyRadius,
+			MetroCode:          record.Location.MetroCode,
+			TimeZone:           record.Location.TimeZone,
+			City:               record.City,
+			Continent:          record.Continent,
+			Country:            record.Country,
+			Postal:             record.Postal,
+			RegisteredCountry:  record.RegisteredCountry,
+			RepresentedCountry: record.RepresentedCountry,
+			Subdivisions:       record.Subdivisions,
+			Subdivision:        record.Subdivision,
+			Traits:             record.Traits,
+		},
+	}
+}
+
+func NewFeatureCollection(features ...*Feature) *FeatureCollection {
+	if features == nil {
+		features = []*Feature{}
+	}
+	return &FeatureCollection{
+		Type:     "FeatureCollection",
+		Features: features,
+	}
+}
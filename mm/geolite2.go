@@ -0,0 +1,713 @@
+package mm
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	maxminddb "github.com/oschwald/maxminddb-golang"
+	"github.com/rabbitt/maxmind/mmdb"
+)
+
+// csvLocation is a row of a GeoLite2 Locations file, shared by the blocks
+// that refer to its geoname id.
+type csvLocation struct {
+	continent    Continent
+	country      Country
+	subdivisions []Subdivision
+	city         string
+	metroCode    uint
+	timeZone     string
+
+	// whether the metro_code cell was set, as a code may be 0
+	hasMetroCode bool
+}
+
+// csvBlock is a row of a GeoLite2 Blocks file.
+type csvBlock struct {
+	location    *csvLocation
+	registered  *csvLocation
+	represented *csvLocation
+	postal      string
+	latitude    float64
+	longitude   float64
+	radius      uint16
+	traits      Traits
+
+	// whether the latitude, longitude and accuracy_radius cells were set,
+	// as each may be 0
+	hasLatitude  bool
+	hasLongitude bool
+	hasRadius    bool
+}
+
+// geoData returns the geodata of the block.
+func (b *csvBlock) geoData() *GeoData {
+	data := &GeoData{
+		Location: Location{AccuracyRadius: b.radius, Latitude: b.latitude, Longitude: b.longitude},
+		Postal:   Postal{Code: b.postal},
+		Traits:   b.traits,
+	}
+
+	if loc := b.location; loc != nil {
+		data.City = City{Name: loc.city}
+		data.Continent = loc.continent
+		data.Country = loc.country
+		data.Location.MetroCode = loc.metroCode
+		data.Location.TimeZone = loc.timeZone
+		if len(loc.subdivisions) > 0 {
+			data.Subdivisions = append([]Subdivision(nil), loc.subdivisions...)
+			data.Subdivision = loc.subdivisions[len(loc.subdivisions)-1]
+		}
+	}
+	if b.registered != nil {
+		data.RegisteredCountry = b.registered.country
+	}
+	if b.represented != nil {
+		data.RepresentedCountry = RepresentedCountry{
+			IsInEuropeanUnion: b.represented.country.IsInEuropeanUnion,
+			IsoCode:           b.represented.country.IsoCode,
+			Name:              b.represented.country.Name,
+		}
+	}
+	return data
+}
+
+type trieNode struct {
+	children [2]uint32
+	block    *csvBlock
+}
+
+// prefixTrie is a binary trie of 128 bit addresses, holding IPv4 networks
+// under ::/96 as MaxMind databases do. Node 0 is the root, so a child of 0
+// is no child.
+type prefixTrie struct {
+	nodes []trieNode
+}
+
+func newPrefixTrie() *prefixTrie {
+	return &prefixTrie{nodes: make([]trieNode, 1)}
+}
+
+// trieKey returns the 128 bit address of ip within the trie.
+func trieKey(ip net.IP) []byte {
+	if ip4 := ip.To4(); ip4 != nil {
+		return append(make([]byte, 12), ip4...)
+	}
+	return ip.To16()
+}
+
+func bit(key []byte, idx int) int {
+	return int(key[idx/8]>>(7-uint(idx%8))) & 1
+}
+
+func (t *prefixTrie) insert(network *net.IPNet, block *csvBlock) {
+	key := trieKey(network.IP)
+	prefix, bits := network.Mask.Size()
+	if bits == 32 {
+		prefix += 96
+	}
+
+	current := uint32(0)
+	for idx := 0; idx < prefix; idx++ {
+		next := t.nodes[current].children[bit(key, idx)]
+		if next == 0 {
+			next = uint32(len(t.nodes))
+			t.nodes = append(t.nodes, trieNode{})
+			t.nodes[current].children[bit(key, idx)] = next
+		}
+		current = next
+	}
+	t.nodes[current].block = block
+}
+
+// lookup returns the block of the longest prefix holding ip, or nil.
+func (t *prefixTrie) lookup(ip net.IP) *csvBlock {
+	key := trieKey(ip)
+	if key == nil {
+		return nil
+	}
+
+	found := t.nodes[0].block
+	current := uint32(0)
+	for idx := 0; idx < 128; idx++ {
+		if current = t.nodes[current].children[bit(key, idx)]; current == 0 {
+			break
+		}
+		if t.nodes[current].block != nil {
+			found = t.nodes[current].block
+		}
+	}
+	return found
+}
+
+// walk calls fn with each network of the trie, broader networks before the
+// narrower ones within them, and lower addresses first.
+func (t *prefixTrie) walk(fn func(network *net.IPNet, block *csvBlock) error) error {
+	key := make([]byte, 16)
+
+	var visit func(current uint32, depth int) error
+	visit = func(current uint32, depth int) error {
+		node := &t.nodes[current]
+		if node.block != nil {
+			if err := fn(trieNetwork(key, depth), node.block); err != nil {
+				return err
+			}
+		}
+
+		for side, child := range node.children {
+			if child == 0 {
+				continue
+			}
+			if side == 1 {
+				key[depth/8] |= 1 << (7 - uint(depth%8))
+			}
+			if err := visit(child, depth+1); err != nil {
+				return err
+			}
+			key[depth/8] &^= 1 << (7 - uint(depth%8))
+		}
+		return nil
+	}
+	return visit(0, 0)
+}
+
+// trieNetwork returns the network of prefix bits of key, as an IPv4
+// network when it lies under ::/96.
+func trieNetwork(key []byte, prefix int) *net.IPNet {
+	ip := make(net.IP, 16)
+	copy(ip, key)
+
+	if prefix >= 96 && net.IP(ip[:12]).Equal(make(net.IP, 12)) {
+		return &net.IPNet{IP: net.IP(ip[12:]), Mask: net.CIDRMask(prefix-96, 32)}
+	}
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(prefix, 128)}
+}
+
+// CsvDatabase answers lookups from the CSV edition of a GeoLite2 or GeoIP2
+// City or Country database, loaded into an in-memory prefix trie.
+//
+// ffjson: skip
+type CsvDatabase struct {
+	path string
+	lock sync.RWMutex
+	trie *prefixTrie
+	info *DatabaseInfo
+}
+
+// csvRelease matches the date of a release in the name of the directory
+// MaxMind distributes it in, e.g. GeoLite2-City-CSV_20181016.
+var csvRelease = regexp.MustCompile(`_(\d{8})$`)
+
+// OpenCsvDatabase loads the CSV database in the directory at path, which
+// holds its Blocks-IPv4 and/or Blocks-IPv6 files, and its English
+// Locations file, as MaxMind distributes them.
+func OpenCsvDatabase(path string) (*CsvDatabase, error) {
+	trie, info, err := loadCsvDatabase(path)
+	if err != nil {
+		return nil, &DatabaseError{Path: path, Err: err}
+	}
+	return &CsvDatabase{path: path, trie: trie, info: info}, nil
+}
+
+// IsCsvDatabase reports whether path is a directory, holding the CSV
+// edition of a database rather than an .mmdb file.
+func IsCsvDatabase(path string) bool {
+	stat, err := os.Stat(path)
+	return err == nil && stat.IsDir()
+}
+
+func loadCsvDatabase(path string) (*prefixTrie, *DatabaseInfo, error) {
+	locationFiles, _ := filepath.Glob(filepath.Join(path, "*-Locations-en.csv"))
+	if len(locationFiles) != 1 {
+		return nil, nil, fmt.Errorf("expected one *-Locations-en.csv file, found %d", len(locationFiles))
+	}
+
+	locations, err := readCsvLocations(locationFiles[0])
+	if err != nil {
+		return nil, nil, err
+	}
+
+	info := &DatabaseInfo{
+		Type:        strings.TrimSuffix(filepath.Base(locationFiles[0]), "-Locations-en.csv"),
+		Description: map[string]string{},
+		Languages:   []string{"en"},
+		IpVersion:   4,
+	}
+
+	trie := newPrefixTrie()
+	blocks := 0
+	for _, version := range []string{"IPv4", "IPv6"} {
+		blocksFile := filepath.Join(path, info.Type+"-Blocks-"+version+".csv")
+		stat, err := os.Stat(blocksFile)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if err = readCsvBlocks(blocksFile, locations, trie); err != nil {
+			return nil, nil, err
+		}
+		if version == "IPv6" {
+			info.IpVersion = 6
+		}
+		if stat.ModTime().After(info.BuildTime) {
+			info.BuildTime = stat.ModTime().UTC()
+		}
+		blocks++
+	}
+	if blocks == 0 {
+		return nil, nil, fmt.Errorf("no %s-Blocks-IPv4.csv or %s-Blocks-IPv6.csv file", info.Type, info.Type)
+	}
+
+	if match := csvRelease.FindStringSubmatch(filepath.Base(filepath.Clean(path))); match != nil {
+		if released, err := time.Parse("20060102", match[1]); err == nil {
+			info.BuildTime = released
+		}
+	}
+
+	info.Description["en"] = info.Type + " database, loaded from CSV"
+	info.NodeCount = uint(len(trie.nodes))
+	return trie, info, nil
+}
+
+// csvFile reads the rows of a CSV file with a header, by column name.
+type csvFile struct {
+	path    string
+	reader  *csv.Reader
+	columns map[string]int
+	row     []string
+}
+
+func openCsvFile(path string) (*csvFile, io.Closer, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	reader := csv.NewReader(file)
+	reader.ReuseRecord = true
+	header, err := reader.Read()
+	if err != nil {
+		file.Close()
+		return nil, nil, fmt.Errorf("%s: unable to read csv header: %s", filepath.Base(path), err)
+	}
+
+	columns := map[string]int{}
+	for idx, name := range header {
+		columns[strings.TrimPrefix(name, "\ufeff")] = idx
+	}
+	return &csvFile{path: path, reader: reader, columns: columns}, file, nil
+}
+
+// next reads the next row, returning false at the end of the file.
+func (f *csvFile) next() (bool, error) {
+	row, err := f.reader.Read()
+	if err == io.EOF {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("%s: %s", filepath.Base(f.path), err)
+	}
+	f.row = row
+	return true, nil
+}
+
+// get returns the named column of the row, or "" when the file has no such
+// column.
+func (f *csvFile) get(column string) string {
+	if idx, found := f.columns[column]; found && idx < len(f.row) {
+		return f.row[idx]
+	}
+	return ""
+}
+
+// errorf reports an error at the current row.
+func (f *csvFile) errorf(format string, args ...interface{}) error {
+	line, _ := f.reader.FieldPos(0)
+	return fmt.Errorf("%s: line %d: %s", filepath.Base(f.path), line, fmt.Sprintf(format, args...))
+}
+
+func readCsvLocations(path string) (map[string]*csvLocation, error) {
+	f, closer, err := openCsvFile(path)
+	if err != nil {
+		return nil, err
+	}
+	defer closer.Close()
+
+	locations := map[string]*csvLocation{}
+	for {
+		more, err := f.next()
+		if err != nil || !more {
+			return locations, err
+		}
+
+		location := &csvLocation{
+			continent: Continent{Code: f.get("continent_code"), Name: f.get("continent_name")},
+			country: Country{
+				IsInEuropeanUnion: f.get("is_in_european_union") == "1",
+				IsoCode:           f.get("country_iso_code"),
+				Name:              f.get("country_name"),
+			},
+			city:     f.get("city_name"),
+			timeZone: f.get("time_zone"),
+		}
+		for _, level := range []string{"1", "2"} {
+			sub := Subdivision{IsoCode: f.get("subdivision_" + level + "_iso_code"), Name: f.get("subdivision_" + level + "_name")}
+			if sub != (Subdivision{}) {
+				location.subdivisions = append(location.subdivisions, sub)
+			}
+		}
+		if metro := f.get("metro_code"); metro != "" {
+			code, err := strconv.ParseUint(metro, 10, 16)
+			if err != nil {
+				return nil, f.errorf("invalid metro_code %s", metro)
+			}
+			location.metroCode, location.hasMetroCode = uint(code), true
+		}
+
+		locations[f.get("geoname_id")] = location
+	}
+}
+
+func readCsvBlocks(path string, locations map[string]*csvLocation, trie *prefixTrie) error {
+	f, closer, err := openCsvFile(path)
+	if err != nil {
+		return err
+	}
+	defer closer.Close()
+
+	if _, found := f.columns["network"]; !found {
+		return fmt.Errorf("%s: no network column", filepath.Base(path))
+	}
+
+	// lookupLocation returns the location of the geoname id in column,
+	// which may be empty.
+	lookupLocation := func(column string) (*csvLocation, error) {
+		id := f.get(column)
+		if id == "" {
+			return nil, nil
+		}
+		if location, found := locations[id]; found {
+			return location, nil
+		}
+		return nil, f.errorf("unknown %s %s", column, id)
+	}
+
+	for {
+		more, err := f.next()
+		if err != nil || !more {
+			return err
+		}
+
+		_, network, err := net.ParseCIDR(f.get("network"))
+		if err != nil {
+			return f.errorf("%s", err)
+		}
+
+		block := &csvBlock{
+			postal: f.get("postal_code"),
+			traits: Traits{
+				IsAnonymousProxy:    f.get("is_anonymous_proxy") == "1",
+				IsSatelliteProvider: f.get("is_satellite_provider") == "1",
+			},
+		}
+		if block.location, err = lookupLocation("geoname_id"); err != nil {
+			return err
+		}
+		if block.registered, err = lookupLocation("registered_country_geoname_id"); err != nil {
+			return err
+		}
+		if block.represented, err = lookupLocation("represented_country_geoname_id"); err != nil {
+			return err
+		}
+
+		for column, value := range map[string]*float64{"latitude": &block.latitude, "longitude": &block.longitude} {
+			if text := f.get(column); text != "" {
+				if *value, err = strconv.ParseFloat(text, 64); err != nil {
+					return f.errorf("invalid %s %s", column, text)
+				}
+			}
+		}
+		block.hasLatitude, block.hasLongitude = f.get("latitude") != "", f.get("longitude") != ""
+		if text := f.get("accuracy_radius"); text != "" {
+			radius, err := strconv.ParseUint(text, 10, 16)
+			if err != nil {
+				return f.errorf("invalid accuracy_radius %s", text)
+			}
+			block.radius, block.hasRadius = uint16(radius), true
+		}
+
+		trie.insert(network, block)
+	}
+}
+
+// Close releases the networks of the database.
+func (db *CsvDatabase) Close() {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	db.trie = newPrefixTrie()
+}
+
+// Reload reloads the CSV files, swapping in their networks once they have
+// all been read successfully.
+func (db *CsvDatabase) Reload() error {
+	swap, _, err := db.stageReload()
+	if err != nil {
+		return err
+	}
+	swap()
+	return nil
+}
+
+func (db *CsvDatabase) stageReload() (func(), func(), error) {
+	trie, info, err := loadCsvDatabase(db.path)
+	if err != nil {
+		return nil, nil, &DatabaseError{Path: db.path, Err: err}
+	}
+
+	swap := func() {
+		db.lock.Lock()
+		defer db.lock.Unlock()
+
+		db.trie, db.info = trie, info
+	}
+	return swap, func() {}, nil
+}
+
+// Metadata describes the database; its build time is the release date in
+// the name of its directory, or else when its files were last modified.
+func (db *CsvDatabase) Metadata() *DatabaseInfo {
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+
+	info := *db.info
+	return &info
+}
+
+// LookupIP returns the geodata for ip, or a *NotFoundError if the database
+// has no record of it.
+func (db *CsvDatabase) LookupIP(ctx context.Context, ip net.IP) (*GeoData, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if ip == nil {
+		return nil, &InvalidIpError{}
+	}
+
+	db.lock.RLock()
+	block := db.trie.lookup(ip)
+	db.lock.RUnlock()
+
+	if block == nil {
+		return nil, &NotFoundError{Ip: ip}
+	}
+	return block.geoData(), nil
+}
+
+// Lookup parses ipText and returns its geodata, which is empty when the
+// database has no record of it.
+func (db *CsvDatabase) Lookup(ipText string) (*GeoData, error) {
+	return LookupText(db, ipText)
+}
+
+// Networks calls fn with each network of the database and its geodata, in
+// address order, stopping at the first error fn returns.
+func (db *CsvDatabase) Networks(fn func(network *net.IPNet, data *GeoData) error) error {
+	// reloads swap in a new trie rather than change this one, so the walk
+	// need not hold them up
+	db.lock.RLock()
+	trie := db.trie
+	db.lock.RUnlock()
+
+	return trie.walk(func(network *net.IPNet, block *csvBlock) error {
+		return fn(network, block.geoData())
+	})
+}
+
+// names returns the English names of a record, or nil when name is empty.
+func names(name string) map[string]interface{} {
+	if name == "" {
+		return nil
+	}
+	return map[string]interface{}{"en": name}
+}
+
+// setFields sets the fields of record that are not empty. Numbers are
+// always set, as 0 is a valid latitude or code; those that may be unset are
+// left out of fields instead.
+func setFields(record map[string]interface{}, fields map[string]interface{}) {
+	for key, value := range fields {
+		switch v := value.(type) {
+		case string:
+			if v == "" {
+				continue
+			}
+		case bool:
+			if !v {
+				continue
+			}
+		case map[string]interface{}:
+			if len(v) == 0 {
+				continue
+			}
+		}
+		record[key] = value
+	}
+}
+
+// fieldsOf returns the non-empty fields of a map of fields.
+func fieldsOf(fields map[string]interface{}) map[string]interface{} {
+	record := map[string]interface{}{}
+	setFields(record, fields)
+	return record
+}
+
+func countryRecordOf(country Country) map[string]interface{} {
+	return fieldsOf(map[string]interface{}{
+		"is_in_european_union": country.IsInEuropeanUnion,
+		"iso_code":             country.IsoCode,
+		"names":                names(country.Name),
+	})
+}
+
+// locationRecord returns the location fields of the block, setting those
+// whose CSV cells were set.
+func (b *csvBlock) locationRecord() map[string]interface{} {
+	location := map[string]interface{}{}
+	if b.hasLatitude {
+		location["latitude"] = b.latitude
+	}
+	if b.hasLongitude {
+		location["longitude"] = b.longitude
+	}
+	if b.hasRadius {
+		location["accuracy_radius"] = b.radius
+	}
+	if loc := b.location; loc != nil {
+		if loc.hasMetroCode {
+			location["metro_code"] = uint16(loc.metroCode)
+		}
+		if loc.timeZone != "" {
+			location["time_zone"] = loc.timeZone
+		}
+	}
+	return location
+}
+
+// record returns the block as a GeoIP2 City record, in the form written by
+// mmdb.Writer.
+func (b *csvBlock) record() map[string]interface{} {
+	data := b.geoData()
+	record := fieldsOf(map[string]interface{}{
+		"city":               fieldsOf(map[string]interface{}{"names": names(data.City.Name)}),
+		"continent":          fieldsOf(map[string]interface{}{"code": data.Continent.Code, "names": names(data.Continent.Name)}),
+		"country":            countryRecordOf(data.Country),
+		"location":           b.locationRecord(),
+		"postal":             fieldsOf(map[string]interface{}{"code": data.Postal.Code}),
+		"registered_country": countryRecordOf(data.RegisteredCountry),
+		"represented_country": fieldsOf(map[string]interface{}{
+			"is_in_european_union": data.RepresentedCountry.IsInEuropeanUnion,
+			"iso_code":             data.RepresentedCountry.IsoCode,
+			"names":                names(data.RepresentedCountry.Name),
+			"type":                 data.RepresentedCountry.Type,
+		}),
+		"traits": fieldsOf(map[string]interface{}{
+			"is_anonymous_proxy":    data.Traits.IsAnonymousProxy,
+			"is_satellite_provider": data.Traits.IsSatelliteProvider,
+		}),
+	})
+
+	if len(data.Subdivisions) > 0 {
+		subdivisions := make([]interface{}, len(data.Subdivisions))
+		for idx, sub := range data.Subdivisions {
+			subdivisions[idx] = fieldsOf(map[string]interface{}{"iso_code": sub.IsoCode, "names": names(sub.Name)})
+		}
+		record["subdivisions"] = subdivisions
+	}
+	return record
+}
+
+// Convert inserts the networks of the database into w as GeoIP2 City
+// records, converting it to the MaxMind DB format.
+func (db *CsvDatabase) Convert(w *mmdb.Writer) error {
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+
+	return db.trie.walk(func(network *net.IPNet, block *csvBlock) error {
+		return w.Insert(network, block.record())
+	})
+}
+
+// IsCsv reports whether path is a CSV file of networks, in the format read
+// by mmdb.LoadFixture, or a directory holding the CSV edition of a database.
+func IsCsv(path string) bool {
+	return IsCsvDatabase(path) || strings.EqualFold(filepath.Ext(path), ".csv")
+}
+
+// compileReader compiles the CSV file, or CSV edition of a database, at
+// path into a MaxMind database in memory.
+func compileReader(path string) (*maxminddb.Reader, error) {
+	var w *mmdb.Writer
+	var err error
+
+	if IsCsvDatabase(path) {
+		w, err = convertCsvDatabase(path)
+	} else {
+		w, err = mmdb.LoadFixture(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if _, err = w.WriteTo(&buf); err != nil {
+		return nil, err
+	}
+	return maxminddb.FromBytes(buf.Bytes())
+}
+
+// CompileDatabase compiles the CSV file of networks, or the directory
+// holding the CSV edition of a database, at path into a database in memory,
+// for the offline tools and overlays; it is compiled again when reloaded.
+// The caller is responsible for closing it.
+func CompileDatabase(path string) (*Database, error) {
+	db := &Database{path: path, compiled: true}
+	reader, err := db.openReader()
+	if err != nil {
+		return nil, &DatabaseError{Path: path, Err: err}
+	}
+	db.setReader(reader)
+	return db, nil
+}
+
+// convertCsvDatabase loads the CSV database in the directory at path into a
+// writer of the MaxMind DB format.
+func convertCsvDatabase(path string) (*mmdb.Writer, error) {
+	trie, info, err := loadCsvDatabase(path)
+	if err != nil {
+		return nil, err
+	}
+
+	w, err := mmdb.NewWriter(info.Type, 6)
+	if err != nil {
+		return nil, err
+	}
+	w.Description, w.Languages, w.BuildTime = info.Description, info.Languages, info.BuildTime
+
+	db := &CsvDatabase{path: path, trie: trie, info: info}
+	return w, db.Convert(w)
+}
@@ -0,0 +1,191 @@
+package mm
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/rabbitt/maxmind/mmdb"
+)
+
+const csvFixture = "../testdata/GeoLite2-City-CSV_20181016"
+
+func openCsvFixture(t *testing.T) *CsvDatabase {
+	db, err := OpenCsvDatabase(csvFixture)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(db.Close)
+	return db
+}
+
+// the CSV fixture holds the networks of the City fixture
+var csvFixtureIps = []string{"81.2.69.142", "89.160.20.115", "175.16.199.1", "216.160.83.60", "2001:218::1", "::ffff:81.2.69.143"}
+
+func TestCsvDatabaseMatchesMmdb(t *testing.T) {
+	csvDb := openCsvFixture(t)
+	mmdbDb := openFixture(t, "GeoIP2-City-Test.json")
+
+	for _, text := range csvFixtureIps {
+		ip := net.ParseIP(text)
+		got, err := csvDb.LookupIP(context.Background(), ip)
+		if err != nil {
+			t.Errorf("%s: %s", text, err)
+			continue
+		}
+		want, err := mmdbDb.LookupIP(context.Background(), ip)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s:\ngot  %+v\nwant %+v", text, got, want)
+		}
+	}
+
+	// the CSV edition does not carry the type of represented countries
+	data, err := csvDb.LookupIP(context.Background(), net.ParseIP("67.43.156.1"))
+	if err != nil || data.RepresentedCountry.IsoCode != "US" || !data.Traits.IsAnonymousProxy || data.RegisteredCountry.IsoCode != "RO" {
+		t.Errorf("67.43.156.1 = %+v, %v", data, err)
+	}
+}
+
+func TestCsvDatabaseNotFound(t *testing.T) {
+	db := openCsvFixture(t)
+
+	var notFound *NotFoundError
+	if _, err := db.LookupIP(context.Background(), net.ParseIP("10.0.0.1")); !errors.As(err, &notFound) {
+		t.Errorf("LookupIP(10.0.0.1) = %v; want a *NotFoundError", err)
+	}
+	if data, err := db.Lookup("10.0.0.1"); err != nil || !data.Unknown() {
+		t.Errorf("Lookup(10.0.0.1) = %+v, %v; want an empty record", data, err)
+	}
+
+	var invalid *InvalidIpError
+	if _, err := db.Lookup("bogus"); !errors.As(err, &invalid) {
+		t.Errorf("Lookup(bogus) = %v; want an *InvalidIpError", err)
+	}
+}
+
+func TestCsvDatabaseInfo(t *testing.T) {
+	info := openCsvFixture(t).Metadata()
+
+	if info.Type != "GeoLite2-City" || info.IpVersion != 6 || !info.BuildTime.Equal(time.Date(2018, 10, 16, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected info %+v", info)
+	}
+}
+
+func TestCsvDatabaseConvert(t *testing.T) {
+	csvDb := openCsvFixture(t)
+
+	w, err := mmdb.NewWriter("GeoLite2-City", 6)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = csvDb.Convert(w); err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "GeoLite2-City.mmdb")
+	if err = w.WriteFile(path); err != nil {
+		t.Fatal(err)
+	}
+	converted, err := OpenDatabase(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer converted.Close()
+
+	for _, text := range append(csvFixtureIps, "67.43.156.1") {
+		ip := net.ParseIP(text)
+		want, _ := csvDb.LookupIP(context.Background(), ip)
+		got, err := converted.LookupIP(context.Background(), ip)
+		if err != nil || !reflect.DeepEqual(got, want) {
+			t.Errorf("%s:\ngot  %+v, %v\nwant %+v", text, got, err, want)
+		}
+	}
+}
+
+func TestCsvDatabaseConvertZeroLocation(t *testing.T) {
+	dir := t.TempDir()
+	locations, err := os.ReadFile(filepath.Join(csvFixture, "GeoLite2-City-Locations-en.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range map[string]string{
+		"GeoLite2-City-Locations-en.csv": string(locations),
+		"GeoLite2-City-Blocks-IPv4.csv":  "network,geoname_id,latitude,longitude,accuracy_radius\n10.0.0.0/8,6252001,0,0,0\n10.0.0.0/16,6252001,,,\n",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	db, err := CompileDatabase(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	// cells of 0 are written, and empty ones left out
+	for ip, want := range map[string]map[string]interface{}{
+		"10.1.0.1": {"latitude": float64(0), "longitude": float64(0), "accuracy_radius": uint64(0)},
+		"10.0.0.1": nil,
+	} {
+		var record struct {
+			Location map[string]interface{} `maxminddb:"location"`
+		}
+		if err := db.Reader.Lookup(net.ParseIP(ip), &record); err != nil || !reflect.DeepEqual(record.Location, want) {
+			t.Errorf("%s location = %#v, %v; want %#v", ip, record.Location, err, want)
+		}
+	}
+}
+
+func TestOpenCsvDatabaseErrors(t *testing.T) {
+	dir := t.TempDir()
+	locations, err := os.ReadFile(filepath.Join(csvFixture, "GeoLite2-City-Locations-en.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var dbErr *DatabaseError
+	for _, files := range []map[string]string{
+		{},
+		{"GeoLite2-City-Locations-en.csv": string(locations)},
+		{"GeoLite2-City-Locations-en.csv": string(locations), "GeoLite2-City-Blocks-IPv4.csv": "network,geoname_id\n10.0.0.0/8,42\n"},
+		{"GeoLite2-City-Locations-en.csv": string(locations), "GeoLite2-City-Blocks-IPv4.csv": "network,geoname_id\n10.0.0.0/33,6252001\n"},
+	} {
+		for name, content := range files {
+			if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if _, err := OpenCsvDatabase(dir); !errors.As(err, &dbErr) {
+			t.Errorf("OpenCsvDatabase with %d files = %v; want a *DatabaseError", len(files), err)
+		}
+	}
+}
+
+func TestCompileDatabaseCsvDirectory(t *testing.T) {
+	var dbErr *DatabaseError
+	if _, err := OpenDatabase(csvFixture); !errors.As(err, &dbErr) || dbErr.Err != errCsvEdition {
+		t.Errorf("OpenDatabase(%s) = %v; want a *DatabaseError", csvFixture, err)
+	}
+
+	db, err := CompileDatabase(csvFixture)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if info := db.Metadata(); info.Type != "GeoLite2-City" {
+		t.Errorf("unexpected info %+v", info)
+	}
+	if record, err := db.Lookup("2001:218::1"); err != nil || record.Country.IsoCode != "JP" {
+		t.Errorf("2001:218::1 = %+v, %v", record, err)
+	}
+}
@@ -0,0 +1,35 @@
+package mm
+
+// ffjson: skip
+type HealthCheck struct {
+	Name    string `json:"name"`
+	Status  string `json:"status"`
+	Message string `json:"message,omitempty"`
+}
+
+// ffjson: skip
+type HealthResponse struct {
+	Status string         `json:"status"`
+	Checks []*HealthCheck `json:"checks"`
+}
+
+// Ok reports whether every check passed.
+func (hr *HealthResponse) Ok() bool {
+	return hr.Status == "ok"
+}
+
+// Check records the outcome of a named check, marking the response as
+// failed if err is non-nil.
+func (hr *HealthResponse) Check(name string, err error) {
+	check := &HealthCheck{Name: name, Status: "ok"}
+	if err != nil {
+		check.Status = "failed"
+		check.Message = err.Error()
+		hr.Status = "failed"
+	}
+	hr.Checks = append(hr.Checks, check)
+}
+
+func NewHealthResponse() *HealthResponse {
+	return &HealthResponse{Status: "ok", Checks: []*HealthCheck{}}
+}
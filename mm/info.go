@@ -0,0 +1,41 @@
+package mm
+
+import "time"
+
+// ffjson: skip
+type DatabaseInfo struct {
+	Type        string            `json:"type"`
+	Description map[string]string `json:"description,omitempty"`
+	BuildTime   time.Time         `json:"build_time"`
+	IpVersion   uint              `json:"ip_version"`
+	Languages   []string          `json:"languages"`
+	NodeCount   uint              `json:"node_count"`
+	RecordSize  uint              `json:"record_size"`
+}
+
+// ffjson: skip
+type ServerInfo struct {
+	Started  time.Time     `json:"started"`
+	Uptime   float64       `json:"uptime_seconds"`
+	Database *DatabaseInfo `json:"database"`
+
+	// Overlays describes the databases layered over Database, by name.
+	Overlays map[string]*DatabaseInfo `json:"overlays,omitempty"`
+}
+
+// Metadata describes the database from its metadata.
+func (db *Database) Metadata() *DatabaseInfo {
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+
+	metadata := db.Reader.Metadata
+	return &DatabaseInfo{
+		Type:        metadata.DatabaseType,
+		Description: metadata.Description,
+		BuildTime:   time.Unix(int64(metadata.BuildEpoch), 0).UTC(),
+		IpVersion:   metadata.IPVersion,
+		Languages:   metadata.Languages,
+		NodeCount:   metadata.NodeCount,
+		RecordSize:  metadata.RecordSize,
+	}
+}
@@ -0,0 +1,245 @@
+package mm
+
+import (
+	"bytes"
+	"net"
+	"sort"
+
+	"github.com/oschwald/maxminddb-golang"
+)
+
+// NetworkWalker is implemented by providers that can list the networks
+// they hold.
+type NetworkWalker interface {
+	// Networks calls fn with each network and its geodata, stopping at
+	// the first error fn returns.
+	Networks(fn func(network *net.IPNet, data *GeoData) error) error
+}
+
+// NetworkRecord is a network and what a database says about it, as dumped
+// or listed.
+//
+// ffjson: skip
+type NetworkRecord struct {
+	Network string   `json:"network"`
+	Data    *GeoData `json:"data,omitempty"`
+
+	// the autonomous system of the network, when an ASN database is at hand
+	Asn   uint   `json:"autonomous_system_number,omitempty"`
+	AsOrg string `json:"autonomous_system_organization,omitempty"`
+}
+
+// ipv4Aliases are the networks of an IPv6 database that MaxMind, and
+// mmdb.Writer, point at the IPv4 networks; the function returns the IPv4
+// address embedded in an address within them.
+var ipv4Aliases = []struct {
+	network  *net.IPNet
+	embedded func(ip net.IP) net.IP
+}{
+	{&net.IPNet{IP: net.ParseIP("::ffff:0:0"), Mask: net.CIDRMask(96, 128)}, func(ip net.IP) net.IP { return ip[12:16] }},
+	{&net.IPNet{IP: net.ParseIP("2002::"), Mask: net.CIDRMask(16, 128)}, func(ip net.IP) net.IP { return ip[2:6] }},
+	{&net.IPNet{IP: net.ParseIP("2001::"), Mask: net.CIDRMask(32, 128)}, func(ip net.IP) net.IP {
+		return net.IP{ip[12] ^ 0xff, ip[13] ^ 0xff, ip[14] ^ 0xff, ip[15] ^ 0xff}
+	}},
+}
+
+// normalizeNetwork returns network with IPv4 networks, stored under ::/96
+// of an IPv6 database, as IPv4 networks.
+func normalizeNetwork(network *net.IPNet) *net.IPNet {
+	ones, bits := network.Mask.Size()
+	ip := network.IP.To16()
+	if bits == 128 && ones >= 96 && bytes.Equal(ip[:12], make([]byte, 12)) {
+		return &net.IPNet{IP: net.IP(append([]byte(nil), ip[12:]...)), Mask: net.CIDRMask(ones-96, 32)}
+	}
+	return network
+}
+
+// isAlias reports whether network lies in one of the ipv4Aliases, and
+// resolves to the same record as the IPv4 address it embeds.
+func isAlias(reader *maxminddb.Reader, network *net.IPNet) bool {
+	if len(network.IP) != net.IPv6len {
+		return false
+	}
+
+	for _, alias := range ipv4Aliases {
+		if !alias.network.Contains(network.IP) {
+			continue
+		}
+
+		aliased, err := reader.LookupOffset(network.IP)
+		if err != nil {
+			return false
+		}
+		embedded, err := reader.LookupOffset(append(make(net.IP, 12), alias.embedded(network.IP)...))
+		return err == nil && aliased == embedded
+	}
+	return false
+}
+
+// Networks calls fn with each network of the database and its geodata, in
+// address order, stopping at the first error fn returns. IPv4 networks are
+// reported once, as IPv4 networks, rather than again at each of the IPv6
+// networks aliased to them.
+func (db *Database) Networks(fn func(network *net.IPNet, data *GeoData) error) error {
+	return db.networks(nil, fn)
+}
+
+// NetworksWithin calls fn with the parts of the networks of the database
+// within limits, as Networks does. The geodata of networks outside the
+// limits is not decoded, and the walk ends past the last of them.
+func (db *Database) NetworksWithin(limits []*net.IPNet, fn func(network *net.IPNet, data *GeoData) error) error {
+	return db.networks(limitRanges(limits), fn)
+}
+
+// Within returns a walker of the parts of the networks of the database
+// within limits.
+func (db *Database) Within(limits []*net.IPNet) NetworkWalker {
+	return networksWithin{db: db, limits: limits}
+}
+
+type networksWithin struct {
+	db     *Database
+	limits []*net.IPNet
+}
+
+func (w networksWithin) Networks(fn func(network *net.IPNet, data *GeoData) error) error {
+	return w.db.NetworksWithin(w.limits, fn)
+}
+
+// limitRanges returns the addresses of limits in address order, dropping
+// the limits within another.
+func limitRanges(limits []*net.IPNet) []IPRange {
+	ranges := make([]IPRange, 0, len(limits))
+	for _, limit := range limits {
+		ranges = append(ranges, NetworkRange(limit))
+	}
+	sort.Slice(ranges, func(i, j int) bool {
+		if ranges[i].first != ranges[j].first {
+			return ranges[i].first.less(ranges[j].first)
+		}
+		return ranges[j].last.less(ranges[i].last)
+	})
+
+	kept := ranges[:0]
+	for _, r := range ranges {
+		if len(kept) == 0 || kept[len(kept)-1].last.less(r.first) {
+			kept = append(kept, r)
+		}
+	}
+	return kept
+}
+
+// networks walks the networks of the database within limits, or all of
+// them when limits is nil. The walk holds a reference to the reader rather
+// than the lock, so that neither a slow walk nor lookups made by fn hold up
+// a reload.
+func (db *Database) networks(limits []IPRange, fn func(network *net.IPNet, data *GeoData) error) error {
+	reader, release := db.acquire()
+	defer release()
+
+	record := cityRecords.Get().(*cityRecord)
+	defer cityRecords.Put(record)
+
+	var skipped struct{}
+	var r IPRange
+	networks := reader.Networks()
+	for networks.Next() {
+		if limits != nil {
+			if len(limits) == 0 {
+				// past the last limit
+				break
+			}
+
+			network, err := networks.Network(&skipped)
+			if err != nil {
+				return err
+			}
+			r = NetworkRange(normalizeNetwork(network))
+			for len(limits) > 0 && limits[0].last.less(r.first) {
+				limits = limits[1:]
+			}
+			if len(limits) == 0 || r.last.less(limits[0].first) {
+				continue
+			}
+		}
+
+		*record = cityRecord{}
+		network, err := networks.Network(record)
+		if err != nil {
+			return err
+		}
+		if reader.Metadata.IPVersion == 6 && isAlias(reader, network) {
+			continue
+		}
+
+		// the geodata is handed over, as fn may keep it
+		data := &GeoData{}
+		record.copyTo(data)
+		if limits == nil {
+			if err = fn(normalizeNetwork(network), data); err != nil {
+				return err
+			}
+			continue
+		}
+		for _, limit := range limits {
+			if r.last.less(limit.first) {
+				break
+			}
+			part, _ := r.Intersect(limit)
+			for _, network := range part.CIDRs() {
+				if err = fn(network, data); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return networks.Err()
+}
+
+// CollapseNetworks walks the networks of w that match, calling fn with the
+// fewest networks covering them, in address order; adjacent networks are
+// collapsed into the networks spanning them. It stops at the first error
+// match or fn returns.
+func CollapseNetworks(w NetworkWalker, match func(network *net.IPNet, data *GeoData) (bool, error), fn func(network *net.IPNet) error) error {
+	var current IPRange
+	var pending bool
+	flush := func() error {
+		if !pending {
+			return nil
+		}
+		pending = false
+		for _, network := range current.CIDRs() {
+			if err := fn(network); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	err := w.Networks(func(network *net.IPNet, data *GeoData) error {
+		matched, err := match(network, data)
+		if err != nil || !matched {
+			return err
+		}
+
+		r := NetworkRange(network)
+		if pending && current.Adjoins(r) {
+			current = current.Join(r)
+			return nil
+		}
+		if err = flush(); err != nil {
+			return err
+		}
+		current, pending = r, true
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return flush()
+}
+
+var (
+	_ NetworkWalker = (*Database)(nil)
+	_ NetworkWalker = (*CsvDatabase)(nil)
+)
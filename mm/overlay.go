@@ -0,0 +1,226 @@
+package mm
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"reflect"
+	"strings"
+)
+
+// overlay modes
+const (
+	// OverlayOverride overlays answer with their whole record, hiding
+	// the records of the databases beneath them.
+	OverlayOverride = "override"
+
+	// OverlayMerge overlays answer with the fields their record sets,
+	// leaving the rest to the databases beneath them.
+	OverlayMerge = "merge"
+)
+
+var OverlayModes = map[string]bool{
+	OverlayOverride: true,
+	OverlayMerge:    true,
+}
+
+// Overlay configures a database layered over the main one, such as local
+// overrides for VPN egress ranges or corrected cities.
+//
+// ffjson: skip
+type Overlay struct {
+	// Name identifies the overlay as the origin of fields; the basename
+	// of the file, without its extension, when empty.
+	Name string `json:"name"`
+
+	// Path is the MaxMind database, or CSV file of networks, to overlay.
+	Path *Pathname `json:"file"`
+
+	// Mode is how the overlay's records combine with those beneath it;
+	// OverlayOverride when empty.
+	Mode string `json:"mode"`
+}
+
+// Layer is a provider searched by a LayeredDatabase.
+//
+// ffjson: skip
+type Layer struct {
+	Name     string
+	Mode     string
+	Provider Provider
+}
+
+// LayeredDatabase searches a list of providers in priority order, answering
+// with the fields of the first that sets them, and reporting which layer
+// each field came from.
+//
+// ffjson: skip
+type LayeredDatabase struct {
+	// Layers are searched from first to last; the last is the base
+	// provider, the others overlays over it.
+	Layers []*Layer
+}
+
+// layerName returns the name of the database at path: its basename without
+// the extension.
+func layerName(path string) string {
+	name := NewPathname(path).Basename().Path()
+	if dot := strings.LastIndex(name, "."); dot > 0 {
+		name = name[:dot]
+	}
+	return name
+}
+
+// NewLayeredDatabase layers overlays, of which the first has the highest
+// priority, over base, which is named after its database type. The overlay
+// databases are opened as shared instances.
+func NewLayeredDatabase(base Provider, overlays []*Overlay) (*LayeredDatabase, error) {
+	layers := make([]*Layer, 0, len(overlays)+1)
+	names := map[string]bool{}
+
+	for idx, overlay := range overlays {
+		if overlay.Path == nil || overlay.Path.Path() == "" {
+			return nil, &ConfigError{Key: "database.overlays", Err: fmt.Errorf("overlay #%d has no file", idx)}
+		}
+
+		mode := overlay.Mode
+		if mode == "" {
+			mode = OverlayOverride
+		} else if !OverlayModes[mode] {
+			return nil, &ConfigError{Key: "database.overlays", Err: fmt.Errorf("overlay #%d has mode '%s'; expected one of 'override', or 'merge'", idx, mode)}
+		}
+
+		name := overlay.Name
+		if name == "" {
+			name = layerName(overlay.Path.Path())
+		}
+		if names[name] {
+			return nil, &ConfigError{Key: "database.overlays", Err: fmt.Errorf("overlay #%d is named '%s', as is another layer", idx, name)}
+		}
+		names[name] = true
+
+		database, err := GetDatabase(overlay.Path.Path())
+		if err != nil {
+			return nil, err
+		}
+
+		layers = append(layers, &Layer{Name: name, Mode: mode, Provider: database})
+	}
+
+	name := base.Metadata().Type
+	if name == "" || names[name] {
+		name = "base"
+	}
+	layers = append(layers, &Layer{Name: name, Mode: OverlayOverride, Provider: base})
+
+	return &LayeredDatabase{Layers: layers}, nil
+}
+
+// mergeFields copies the fields set in src, but not in dst, into dst,
+// recording layer as their origin under their dotted JSON path. The
+// subdivision is left to be derived from the merged subdivisions.
+func mergeFields(dst reflect.Value, src reflect.Value, prefix string, layer string, origins map[string]string) {
+	for idx := 0; idx < dst.NumField(); idx++ {
+		field := dst.Type().Field(idx)
+
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "subdivision" {
+			continue
+		}
+		if prefix != "" {
+			name = prefix + "." + name
+		}
+
+		to, from := dst.Field(idx), src.Field(idx)
+		if field.Type.Kind() == reflect.Struct {
+			mergeFields(to, from, name, layer, origins)
+			continue
+		}
+
+		if from.IsZero() || !to.IsZero() {
+			continue
+		}
+		to.Set(from)
+		origins[name] = layer
+	}
+}
+
+// LookupIP returns the geodata for ip, combined from the layers that have
+// a record of it, and the name of the layer each field came from, by its
+// dotted JSON path, e.g. city.name. It reports a *NotFoundError if no
+// layer has a record of ip.
+//
+// Fields are only taken from a merge overlay when set, so a merge overlay
+// cannot clear a field, e.g. set a boolean to false; an override overlay
+// can.
+func (ld *LayeredDatabase) LookupIP(ctx context.Context, ip net.IP) (*GeoData, map[string]string, error) {
+	data := &GeoData{}
+	origins := map[string]string{}
+	found := false
+
+	for _, layer := range ld.Layers {
+		record, err := layer.Provider.LookupIP(ctx, ip)
+		if _, notFound := err.(*NotFoundError); notFound {
+			continue
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+
+		found = true
+		mergeFields(reflect.ValueOf(data).Elem(), reflect.ValueOf(record).Elem(), "", layer.Name, origins)
+
+		if layer.Mode != OverlayMerge {
+			break
+		}
+	}
+
+	if !found {
+		return nil, nil, &NotFoundError{Ip: ip}
+	}
+
+	if len(data.Subdivisions) > 0 {
+		data.Subdivision = data.Subdivisions[len(data.Subdivisions)-1]
+	}
+	return data, origins, nil
+}
+
+// Reload reloads each of the layers that can be, together: the new data of
+// every layer is opened before any is swapped in, so that when one fails,
+// naming it in the error, every layer is left as it was.
+func (ld *LayeredDatabase) Reload() error {
+	var swaps, discards []func()
+	discard := func() {
+		for _, fn := range discards {
+			fn()
+		}
+	}
+
+	var reloaders []*Layer
+	for _, layer := range ld.Layers {
+		switch reloader := layer.Provider.(type) {
+		case stagedReloader:
+			swap, discardLayer, err := reloader.stageReload()
+			if err != nil {
+				discard()
+				return fmt.Errorf("layer %s: %s", layer.Name, err)
+			}
+			swaps, discards = append(swaps, swap), append(discards, discardLayer)
+		case Reloader:
+			reloaders = append(reloaders, layer)
+		}
+	}
+
+	// the rest, such as upstream servers, only refresh their description
+	for _, layer := range reloaders {
+		if err := layer.Provider.(Reloader).Reload(); err != nil {
+			discard()
+			return fmt.Errorf("layer %s: %s", layer.Name, err)
+		}
+	}
+
+	for _, swap := range swaps {
+		swap()
+	}
+	return nil
+}
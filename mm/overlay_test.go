@@ -0,0 +1,184 @@
+package mm
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func writeOverlay(t *testing.T, name string, content string) *Pathname {
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return NewPathname(path)
+}
+
+// testLayers layers a merging overlay of corrected cities, and an
+// overriding overlay of VPN egress ranges, over the City test fixture.
+func testLayers(t *testing.T) *LayeredDatabase {
+	base := openFixture(t, "GeoIP2-City-Test.json")
+	t.Cleanup(CloseDatabases)
+
+	corrections := writeOverlay(t, "corrections.csv", `network,city
+81.2.69.142/31,Islington
+89.160.20.112/28,Linköping (corrected)
+`)
+	vpn := writeOverlay(t, "vpn.csv", `network,country,country_name,city,location.accuracy_radius
+89.160.20.112/28,US,United States,Ashburn,1
+192.0.2.0/24,US,United States,Ashburn,1
+`)
+
+	layers, err := NewLayeredDatabase(base, []*Overlay{
+		{Path: corrections, Mode: OverlayMerge},
+		{Name: "vpn-egress", Path: vpn},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return layers
+}
+
+func TestLayeredDatabaseMerges(t *testing.T) {
+	layers := testLayers(t)
+	base := layers.Layers[2].Name
+
+	data, origins, err := layers.LookupIP(context.Background(), net.ParseIP("81.2.69.142"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data.City.Name != "Islington" || data.Country.IsoCode != "GB" || data.Subdivision.IsoCode != "ENG" {
+		t.Errorf("unexpected geodata %+v", data)
+	}
+	for field, want := range map[string]string{
+		"city.name":               "corrections",
+		"country.iso_code":        base,
+		"location.latitude":       base,
+		"subdivisions":            base,
+		"registered_country.name": base,
+	} {
+		if origins[field] != want {
+			t.Errorf("origin of %s = %s; want %s", field, origins[field], want)
+		}
+	}
+	if _, found := origins["subdivision"]; found {
+		t.Error("the subdivision has an origin of its own")
+	}
+}
+
+func TestLayeredDatabaseOverrides(t *testing.T) {
+	layers := testLayers(t)
+
+	data, origins, err := layers.LookupIP(context.Background(), net.ParseIP("89.160.20.115"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the merging overlay's city takes precedence, and the overriding
+	// overlay hides the rest of the base record
+	want := &GeoData{
+		City:     City{Name: "Linköping (corrected)"},
+		Country:  Country{IsoCode: "US", Name: "United States"},
+		Location: Location{AccuracyRadius: 1},
+	}
+	if !reflect.DeepEqual(data, want) {
+		t.Errorf("got %+v\nwant %+v", data, want)
+	}
+	if len(origins) != 4 || origins["city.name"] != "corrections" || origins["country.iso_code"] != "vpn-egress" ||
+		origins["location.accuracy_radius"] != "vpn-egress" {
+		t.Errorf("unexpected origins %v", origins)
+	}
+
+	data, origins, err = layers.LookupIP(context.Background(), net.ParseIP("192.0.2.1"))
+	if err != nil || data.City.Name != "Ashburn" || origins["city.name"] != "vpn-egress" {
+		t.Errorf("192.0.2.1 = %+v, %v, %v", data, origins, err)
+	}
+
+	var notFound *NotFoundError
+	if _, _, err = layers.LookupIP(context.Background(), net.ParseIP("10.0.0.1")); !errors.As(err, &notFound) {
+		t.Errorf("10.0.0.1 = %v; want a *NotFoundError", err)
+	}
+}
+
+func TestLayeredDatabaseReload(t *testing.T) {
+	layers := testLayers(t)
+	corrections := layers.Layers[0].Provider.(*Database)
+
+	if err := os.WriteFile(corrections.path, []byte("network,city\n81.2.69.142/31,Shoreditch\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := layers.Reload(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, _, err := layers.LookupIP(context.Background(), net.ParseIP("81.2.69.142"))
+	if err != nil || data.City.Name != "Shoreditch" {
+		t.Errorf("after reload, 81.2.69.142 = %+v, %v", data, err)
+	}
+}
+
+func TestLayeredDatabaseReloadFailure(t *testing.T) {
+	layers := testLayers(t)
+	corrections := layers.Layers[0].Provider.(*Database)
+	vpn := layers.Layers[1].Provider.(*Database)
+
+	if err := os.WriteFile(corrections.path, []byte("network,city\n81.2.69.142/31,Shoreditch\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(vpn.path, []byte("network,city\nnot a network,Ashburn\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := layers.Reload(); err == nil || !strings.Contains(err.Error(), "layer vpn-egress") {
+		t.Errorf("Reload() = %v; want an error naming the vpn-egress layer", err)
+	}
+
+	// no layer is reloaded when one fails
+	data, _, err := layers.LookupIP(context.Background(), net.ParseIP("81.2.69.142"))
+	if err != nil || data.City.Name != "Islington" {
+		t.Errorf("after a failed reload, 81.2.69.142 = %+v, %v", data, err)
+	}
+}
+
+func TestNewLayeredDatabaseErrors(t *testing.T) {
+	base := openFixture(t, "GeoIP2-City-Test.json")
+	t.Cleanup(CloseDatabases)
+	overlay := writeOverlay(t, "overlay.csv", "network,city\n10.0.0.0/8,Internal\n")
+
+	var configErr *ConfigError
+	for _, overlays := range [][]*Overlay{
+		{{Path: overlay, Mode: "replace"}},
+		{{}},
+		{{Path: overlay}, {Path: overlay}},
+	} {
+		if _, err := NewLayeredDatabase(base, overlays); !errors.As(err, &configErr) || configErr.Key != "database.overlays" {
+			t.Errorf("NewLayeredDatabase = %v; want a *ConfigError", err)
+		}
+	}
+
+	var dbErr *DatabaseError
+	missing := NewPathname(filepath.Join(t.TempDir(), "missing.mmdb"))
+	if _, err := NewLayeredDatabase(base, []*Overlay{{Path: missing}}); !errors.As(err, &dbErr) {
+		t.Errorf("NewLayeredDatabase = %v; want a *DatabaseError", err)
+	}
+}
+
+func TestConfigurationOverlays(t *testing.T) {
+	config := NewConfiguration()
+	err := config.LoadFromJson([]byte(`{"database.overlays": [
+		{"name": "vpn", "file": "/etc/maxmind/vpn.csv"},
+		{"file": "/etc/maxmind/corrections.mmdb", "mode": "merge"}
+	]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(config.Overlays) != 2 || config.Overlays[0].Name != "vpn" || config.Overlays[0].Path.Path() != "/etc/maxmind/vpn.csv" ||
+		config.Overlays[1].Mode != OverlayMerge {
+		t.Errorf("unexpected overlays %+v", config.Overlays)
+	}
+}
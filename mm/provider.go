@@ -0,0 +1,157 @@
+package mm
+
+import (
+	"context"
+	"net"
+	"sync"
+)
+
+// lookup backends
+const (
+	// BackendMmdb answers from a MaxMind database file; the default.
+	BackendMmdb = "mmdb"
+
+	// BackendCsv answers from the CSV edition of a database, held in memory.
+	BackendCsv = "csv"
+
+	// BackendUpstream answers from another maxmind server, over HTTP.
+	BackendUpstream = "upstream"
+)
+
+var Backends = map[string]bool{
+	BackendMmdb:     true,
+	BackendCsv:      true,
+	BackendUpstream: true,
+}
+
+// Provider is a source of geodata the server and lookup tool answer from.
+type Provider interface {
+	// LookupIP returns the geodata for ip, or a *NotFoundError if the
+	// provider has no record of it.
+	LookupIP(ctx context.Context, ip net.IP) (*GeoData, error)
+
+	// Metadata describes the data the provider answers from.
+	Metadata() *DatabaseInfo
+
+	// Close releases the provider's resources.
+	Close()
+}
+
+// Reloader is implemented by providers that can reload their data in place.
+type Reloader interface {
+	Reload() error
+}
+
+// stagedReloader is implemented by Reloaders whose reload can be split in
+// two: opening the new data, which may fail, and swapping it in, which
+// cannot; so that several can be reloaded together, or not at all.
+type stagedReloader interface {
+	// stageReload opens the new data, returning a function swapping it in,
+	// and one releasing it instead.
+	stageReload() (swap func(), discard func(), err error)
+}
+
+// LookupText parses ipText and returns its geodata from p, which is empty
+// when p has no record of it.
+func LookupText(p Provider, ipText string) (*GeoData, error) {
+	ip := net.ParseIP(ipText)
+	if ip == nil {
+		return nil, &InvalidIpError{Ip: ipText}
+	}
+
+	record, err := p.LookupIP(context.Background(), ip)
+	if _, notFound := err.(*NotFoundError); notFound {
+		return &GeoData{}, nil
+	}
+	return record, err
+}
+
+type memoryNetwork struct {
+	network *net.IPNet
+	data    *GeoData
+}
+
+// MemoryProvider is a Provider answering from networks held in memory, for
+// use in tests of code that depends on a Provider. It is safe for
+// concurrent use.
+//
+// ffjson: skip
+type MemoryProvider struct {
+	Info *DatabaseInfo
+
+	networks []memoryNetwork
+	lock     sync.RWMutex
+}
+
+func NewMemoryProvider(databaseType string) *MemoryProvider {
+	return &MemoryProvider{Info: &DatabaseInfo{Type: databaseType, IpVersion: 6, Languages: []string{"en"}}}
+}
+
+// Insert stores data as the geodata of the network in CIDR notation.
+// Lookups answer with the geodata of the narrowest network holding the ip.
+func (p *MemoryProvider) Insert(network string, data *GeoData) error {
+	_, ipNet, err := net.ParseCIDR(network)
+	if err != nil {
+		return err
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.networks = append(p.networks, memoryNetwork{network: ipNet, data: data})
+	return nil
+}
+
+func (p *MemoryProvider) LookupIP(ctx context.Context, ip net.IP) (*GeoData, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if ip == nil {
+		return nil, &InvalidIpError{}
+	}
+
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	var found *memoryNetwork
+	for idx := range p.networks {
+		candidate := &p.networks[idx]
+		if !candidate.network.Contains(ip) {
+			continue
+		}
+		if found == nil || prefixLength(candidate.network) >= prefixLength(found.network) {
+			found = candidate
+		}
+	}
+
+	if found == nil {
+		return nil, &NotFoundError{Ip: ip}
+	}
+	data := *found.data
+	return &data, nil
+}
+
+func prefixLength(network *net.IPNet) int {
+	ones, bits := network.Mask.Size()
+	if bits == 32 {
+		ones += 96
+	}
+	return ones
+}
+
+func (p *MemoryProvider) Metadata() *DatabaseInfo {
+	return p.Info
+}
+
+func (p *MemoryProvider) Close() {}
+
+var (
+	_ Provider = (*Database)(nil)
+	_ Provider = (*CsvDatabase)(nil)
+	_ Provider = (*MemoryProvider)(nil)
+	_ Reloader = (*Database)(nil)
+	_ Reloader = (*CsvDatabase)(nil)
+
+	_ stagedReloader = (*Database)(nil)
+	_ stagedReloader = (*CsvDatabase)(nil)
+)
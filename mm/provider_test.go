@@ -0,0 +1,77 @@
+package mm
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+)
+
+func TestMemoryProvider(t *testing.T) {
+	p := NewMemoryProvider("Internal")
+	for network, city := range map[string]string{
+		"10.0.0.0/8":   "Internal",
+		"10.1.0.0/16":  "London",
+		"fd00:1::/32":  "Ashburn",
+		"10.1.2.0/24":  "Reading",
+		"192.0.2.0/24": "",
+	} {
+		if err := p.Insert(network, &GeoData{City: City{Name: city}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := p.Insert("10.0.0.0/33", &GeoData{}); err == nil {
+		t.Error("Insert accepted an invalid network")
+	}
+
+	for ip, want := range map[string]string{
+		"10.9.9.9":        "Internal",
+		"10.1.1.1":        "London",
+		"10.1.2.3":        "Reading",
+		"::ffff:10.1.2.3": "Reading",
+		"fd00:1::1":       "Ashburn",
+	} {
+		record, err := p.LookupIP(context.Background(), net.ParseIP(ip))
+		if err != nil || record.City.Name != want {
+			t.Errorf("%s = %+v, %v; want %s", ip, record, err, want)
+		}
+	}
+
+	var notFound *NotFoundError
+	if _, err := p.LookupIP(context.Background(), net.ParseIP("172.16.0.1")); !errors.As(err, &notFound) {
+		t.Errorf("172.16.0.1 = %v; want a *NotFoundError", err)
+	}
+	if record, err := LookupText(p, "172.16.0.1"); err != nil || !record.Unknown() {
+		t.Errorf("LookupText(172.16.0.1) = %+v, %v; want an empty record", record, err)
+	}
+
+	var invalid *InvalidIpError
+	if _, err := LookupText(p, "bogus"); !errors.As(err, &invalid) {
+		t.Errorf("LookupText(bogus) = %v; want an *InvalidIpError", err)
+	}
+}
+
+func TestLayeredProviders(t *testing.T) {
+	base := NewMemoryProvider("Internal")
+	base.Insert("10.0.0.0/8", &GeoData{City: City{Name: "Internal"}, Country: Country{IsoCode: "US"}})
+	t.Cleanup(CloseDatabases)
+
+	overlay := writeOverlay(t, "sites.csv", "network,city\n10.1.0.0/16,London\n")
+	layers, err := NewLayeredDatabase(base, []*Overlay{{Path: overlay, Mode: OverlayMerge}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, origins, err := layers.LookupIP(context.Background(), net.ParseIP("10.1.0.1"))
+	if err != nil || data.City.Name != "London" || data.Country.IsoCode != "US" {
+		t.Fatalf("10.1.0.1 = %+v, %v", data, err)
+	}
+	if origins["city.name"] != "sites" || origins["country.iso_code"] != "Internal" {
+		t.Errorf("unexpected origins %v", origins)
+	}
+
+	// providers that cannot reload are left as they are
+	if err = layers.Reload(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,271 @@
+package mm
+
+import (
+	"errors"
+	"math/bits"
+	"net"
+)
+
+// ipNumber is an address as a 128 bit number, with IPv4 addresses at
+// ::/96, as they are stored in an IPv6 database.
+type ipNumber struct {
+	hi, lo uint64
+}
+
+func toIpNumber(ip net.IP) ipNumber {
+	var n ipNumber
+	if len(ip) == net.IPv4len {
+		for _, b := range ip {
+			n.lo = n.lo<<8 | uint64(b)
+		}
+		return n
+	}
+	for _, b := range ip.To16()[:8] {
+		n.hi = n.hi<<8 | uint64(b)
+	}
+	for _, b := range ip.To16()[8:] {
+		n.lo = n.lo<<8 | uint64(b)
+	}
+	return n
+}
+
+func (n ipNumber) less(m ipNumber) bool {
+	return n.hi < m.hi || n.hi == m.hi && n.lo < m.lo
+}
+
+func (n ipNumber) add(m ipNumber) ipNumber {
+	lo, carry := bits.Add64(n.lo, m.lo, 0)
+	hi, _ := bits.Add64(n.hi, m.hi, carry)
+	return ipNumber{hi, lo}
+}
+
+func (n ipNumber) sub(m ipNumber) ipNumber {
+	lo, borrow := bits.Sub64(n.lo, m.lo, 0)
+	hi, _ := bits.Sub64(n.hi, m.hi, borrow)
+	return ipNumber{hi, lo}
+}
+
+// hostMask returns the number with the low size bits set.
+func hostMask(size int) ipNumber {
+	switch {
+	case size <= 0:
+		return ipNumber{}
+	case size < 64:
+		return ipNumber{0, 1<<uint(size) - 1}
+	case size < 128:
+		return ipNumber{1<<uint(size-64) - 1, ^uint64(0)}
+	}
+	return ipNumber{^uint64(0), ^uint64(0)}
+}
+
+// trailingZeros returns the number of low bits of n that are zero.
+func (n ipNumber) trailingZeros() int {
+	if n.lo != 0 {
+		return bits.TrailingZeros64(n.lo)
+	}
+	return 64 + bits.TrailingZeros64(n.hi)
+}
+
+func (n ipNumber) ip() net.IP {
+	ip := make(net.IP, net.IPv6len)
+	for idx := 0; idx < 8; idx++ {
+		ip[idx] = byte(n.hi >> uint(56-8*idx))
+		ip[8+idx] = byte(n.lo >> uint(56-8*idx))
+	}
+	return ip
+}
+
+var one = ipNumber{0, 1}
+
+// IPRange is a span of addresses, such as the networks of a database
+// collapse into. IPv4 addresses are held at ::/96, as in an IPv6 database.
+type IPRange struct {
+	first, last ipNumber
+}
+
+// First returns the first address of the range, as an IPv4 address when
+// it is at ::/96.
+func (r IPRange) First() net.IP {
+	if r.first.hi == 0 && r.first.lo>>32 == 0 {
+		return r.first.ip()[12:]
+	}
+	return r.first.ip()
+}
+
+// NetworkRange returns the addresses of network.
+func NetworkRange(network *net.IPNet) IPRange {
+	ones, size := network.Mask.Size()
+	first := toIpNumber(network.IP.Mask(network.Mask))
+	return IPRange{first: first, last: first.add(hostMask(size - ones))}
+}
+
+// Adjoins reports whether r ends at the address before s begins.
+func (r IPRange) Adjoins(s IPRange) bool {
+	return r.last != hostMask(128) && r.last.add(one) == s.first
+}
+
+// Join returns the range spanning both r and s, which adjoins it.
+func (r IPRange) Join(s IPRange) IPRange {
+	return IPRange{first: r.first, last: s.last}
+}
+
+// CIDRs returns the fewest networks covering the range, in address order.
+// Networks at ::/96 are returned as IPv4 networks.
+func (r IPRange) CIDRs() []*net.IPNet {
+	var networks []*net.IPNet
+	first := r.first
+	for !r.last.less(first) {
+		// the largest block aligned at first that ends within the range
+		size := first.trailingZeros()
+		if size > 128 {
+			size = 128
+		}
+		for size > 0 && r.last.sub(first).less(hostMask(size)) {
+			size--
+		}
+
+		networks = append(networks, normalizeNetwork(&net.IPNet{IP: first.ip(), Mask: net.CIDRMask(128-size, 128)}))
+		if size == 128 {
+			break
+		}
+		next := first.add(hostMask(size)).add(one)
+		if next == (ipNumber{}) {
+			break
+		}
+		first = next
+	}
+	return networks
+}
+
+// rangedNetwork is a network as walked, and the range of its addresses
+// that remains to be merged.
+type rangedNetwork struct {
+	IPRange
+	data *GeoData
+}
+
+var errWalkStopped = errors.New("walk stopped")
+
+// walkNetworks walks w in the background, sending its networks on the
+// returned channel until done is closed, then its error on the other.
+func walkNetworks(w NetworkWalker, done <-chan struct{}) (<-chan rangedNetwork, <-chan error) {
+	networks := make(chan rangedNetwork, 64)
+	errs := make(chan error, 1)
+	go func() {
+		defer close(networks)
+		errs <- w.Networks(func(network *net.IPNet, data *GeoData) error {
+			select {
+			case networks <- rangedNetwork{NetworkRange(network), data}:
+				return nil
+			case <-done:
+				return errWalkStopped
+			}
+		})
+	}()
+	return networks, errs
+}
+
+// MergeNetworks walks the networks of a and b together, calling fn with
+// each range of addresses that either has a network for, in address
+// order, and the geodata a and b have for the whole range; nil when one
+// of them has no network there. It stops at the first error fn returns.
+func MergeNetworks(a NetworkWalker, b NetworkWalker, fn func(r IPRange, aData *GeoData, bData *GeoData) error) error {
+	done := make(chan struct{})
+	aNetworks, aErrs := walkNetworks(a, done)
+	bNetworks, bErrs := walkNetworks(b, done)
+
+	err := mergeRanges(aNetworks, bNetworks, fn)
+	close(done)
+
+	// drain the walks, which end once done is closed
+	for range aNetworks {
+	}
+	for range bNetworks {
+	}
+	for _, walkErr := range []error{<-aErrs, <-bErrs} {
+		if err == nil && walkErr != errWalkStopped {
+			err = walkErr
+		}
+	}
+	return err
+}
+
+// SplitNetworks returns a walker of the networks of w, split at the
+// boundaries of the networks of by, so that each network walked lies
+// within a single network of by, or outside all of them.
+func SplitNetworks(w NetworkWalker, by NetworkWalker) NetworkWalker {
+	return splitNetworks{w: w, by: by}
+}
+
+type splitNetworks struct {
+	w, by NetworkWalker
+}
+
+func (s splitNetworks) Networks(fn func(network *net.IPNet, data *GeoData) error) error {
+	return MergeNetworks(s.w, s.by, func(r IPRange, data *GeoData, _ *GeoData) error {
+		if data == nil {
+			return nil
+		}
+		for _, network := range r.CIDRs() {
+			if err := fn(network, data); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func mergeRanges(aNetworks <-chan rangedNetwork, bNetworks <-chan rangedNetwork, fn func(r IPRange, aData *GeoData, bData *GeoData) error) error {
+	aNext, aOk := <-aNetworks
+	bNext, bOk := <-bNetworks
+	for aOk || bOk {
+		var err error
+		switch {
+		case !bOk || aOk && aNext.last.less(bNext.first):
+			err = fn(aNext.IPRange, aNext.data, nil)
+			aNext, aOk = <-aNetworks
+		case !aOk || bNext.last.less(aNext.first):
+			err = fn(bNext.IPRange, nil, bNext.data)
+			bNext, bOk = <-bNetworks
+		case aNext.first.less(bNext.first):
+			err = fn(IPRange{aNext.first, bNext.first.sub(one)}, aNext.data, nil)
+			aNext.first = bNext.first
+		case bNext.first.less(aNext.first):
+			err = fn(IPRange{bNext.first, aNext.first.sub(one)}, nil, bNext.data)
+			bNext.first = aNext.first
+		default:
+			last := aNext.last
+			if bNext.last.less(last) {
+				last = bNext.last
+			}
+			err = fn(IPRange{aNext.first, last}, aNext.data, bNext.data)
+
+			if aNext.last == last {
+				aNext, aOk = <-aNetworks
+			} else {
+				aNext.first = last.add(one)
+			}
+			if bNext.last == last {
+				bNext, bOk = <-bNetworks
+			} else {
+				bNext.first = last.add(one)
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Intersect returns the addresses both r and s span, and whether there
+// are any.
+func (r IPRange) Intersect(s IPRange) (IPRange, bool) {
+	if s.first.less(r.first) {
+		s.first = r.first
+	}
+	if r.last.less(s.last) {
+		s.last = r.last
+	}
+	return s, !s.last.less(s.first)
+}
@@ -0,0 +1,161 @@
+package mm
+
+import (
+	"errors"
+	"net"
+	"reflect"
+	"testing"
+)
+
+func parseCidr(t *testing.T, text string) *net.IPNet {
+	_, network, err := net.ParseCIDR(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return network
+}
+
+func cidrStrings(networks []*net.IPNet) []string {
+	var texts []string
+	for _, network := range networks {
+		texts = append(texts, network.String())
+	}
+	return texts
+}
+
+func TestIPRangeCIDRs(t *testing.T) {
+	for _, test := range []struct {
+		first, last string
+		want        []string
+	}{
+		{"10.0.0.0/24", "10.0.0.0/24", []string{"10.0.0.0/24"}},
+		{"10.0.0.0/24", "10.0.1.0/24", []string{"10.0.0.0/23"}},
+		{"10.0.1.0/24", "10.0.2.0/24", []string{"10.0.1.0/24", "10.0.2.0/24"}},
+		{"10.0.0.1/32", "10.0.0.6/32", []string{"10.0.0.1/32", "10.0.0.2/31", "10.0.0.4/31", "10.0.0.6/32"}},
+		{"0.0.0.0/1", "128.0.0.0/1", []string{"0.0.0.0/0"}},
+		{"2001:db8::/33", "2001:db8:8000::/33", []string{"2001:db8::/32"}},
+		{"::/1", "8000::/1", []string{"::/0"}},
+	} {
+		r := NetworkRange(parseCidr(t, test.first)).Join(NetworkRange(parseCidr(t, test.last)))
+		if got := cidrStrings(r.CIDRs()); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s-%s = %v; want %v", test.first, test.last, got, test.want)
+		}
+	}
+
+	if !NetworkRange(parseCidr(t, "10.0.0.0/24")).Adjoins(NetworkRange(parseCidr(t, "10.0.1.0/24"))) {
+		t.Error("10.0.0.0/24 does not adjoin 10.0.1.0/24")
+	}
+	if NetworkRange(parseCidr(t, "10.0.0.0/24")).Adjoins(NetworkRange(parseCidr(t, "10.0.2.0/24"))) {
+		t.Error("10.0.0.0/24 adjoins 10.0.2.0/24")
+	}
+	if r, ok := NetworkRange(parseCidr(t, "10.0.0.0/8")).Intersect(NetworkRange(parseCidr(t, "10.1.0.0/16"))); !ok || cidrStrings(r.CIDRs())[0] != "10.1.0.0/16" {
+		t.Errorf("10.0.0.0/8 ∩ 10.1.0.0/16 = %v, %t", r.CIDRs(), ok)
+	}
+	if _, ok := NetworkRange(parseCidr(t, "10.0.0.0/8")).Intersect(NetworkRange(parseCidr(t, "2001:db8::/32"))); ok {
+		t.Error("10.0.0.0/8 intersects 2001:db8::/32")
+	}
+	if first := NetworkRange(parseCidr(t, "10.0.0.0/24")).First(); !first.Equal(net.ParseIP("10.0.0.0")) || len(first) != net.IPv4len {
+		t.Errorf("First() = %v", first)
+	}
+}
+
+// networkList is a NetworkWalker of networks, in address order, and their
+// countries.
+type networkList [][2]string
+
+func (l networkList) Networks(fn func(network *net.IPNet, data *GeoData) error) error {
+	for _, item := range l {
+		_, network, err := net.ParseCIDR(item[0])
+		if err != nil {
+			return err
+		}
+		data := &GeoData{}
+		data.Country.IsoCode = item[1]
+		if err = fn(normalizeNetwork(network), data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestMergeNetworks(t *testing.T) {
+	old := networkList{{"10.0.0.0/16", "GB"}, {"10.2.0.0/16", "US"}, {"2001:db8::/32", "JP"}}
+	new := networkList{{"10.0.1.0/24", "FR"}, {"10.1.0.0/16", "DE"}, {"2001:db8::/32", "JP"}}
+
+	var got []string
+	country := func(data *GeoData) string {
+		if data == nil {
+			return "-"
+		}
+		return data.Country.IsoCode
+	}
+	err := MergeNetworks(old, new, func(r IPRange, oldData *GeoData, newData *GeoData) error {
+		got = append(got, cidrStrings(r.CIDRs())[0]+" "+country(oldData)+" "+country(newData))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"10.0.0.0/24 GB -",
+		"10.0.1.0/24 GB FR",
+		"10.0.2.0/23 GB -",
+		"10.1.0.0/16 - DE",
+		"10.2.0.0/16 US -",
+		"2001:db8::/32 JP JP",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got  %q\nwant %q", got, want)
+	}
+
+	// an error stops both walks
+	stop := errors.New("stop")
+	calls := 0
+	err = MergeNetworks(old, new, func(r IPRange, oldData *GeoData, newData *GeoData) error {
+		calls++
+		return stop
+	})
+	if err != stop || calls != 1 {
+		t.Errorf("MergeNetworks = %v after %d calls; want %v after 1", err, calls, stop)
+	}
+}
+
+func TestMergeNetworksDatabases(t *testing.T) {
+	db := openFixture(t, "GeoIP2-City-Test.json")
+	csvDb := openCsvFixture(t)
+
+	// the CSV edition holds the networks of the database
+	err := MergeNetworks(db, csvDb, func(r IPRange, dbData *GeoData, csvData *GeoData) error {
+		if dbData == nil || csvData == nil || dbData.Country.IsoCode != csvData.Country.IsoCode {
+			t.Errorf("%v: %+v, %+v", r.CIDRs(), dbData, csvData)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCollapseNetworks(t *testing.T) {
+	networks := networkList{
+		{"10.0.0.0/24", "IR"}, {"10.0.1.0/24", "IR"}, {"10.0.2.0/24", "US"}, {"10.0.3.0/24", "IR"},
+		{"10.0.4.0/23", "IR"}, {"10.0.6.0/24", "IR"}, {"2001:db8::/33", "IR"}, {"2001:db8:8000::/33", "IR"},
+	}
+
+	var got []string
+	err := CollapseNetworks(networks, func(network *net.IPNet, data *GeoData) (bool, error) {
+		return data.Country.IsoCode == "IR", nil
+	}, func(network *net.IPNet) error {
+		got = append(got, network.String())
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"10.0.0.0/23", "10.0.3.0/24", "10.0.4.0/23", "10.0.6.0/24", "2001:db8::/32"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got  %q\nwant %q", got, want)
+	}
+}
@@ -0,0 +1,169 @@
+package mm
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// Allowance is the outcome of taking a token from a TokenBucket.
+//
+// ffjson: skip
+type Allowance struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	RetryAfter time.Duration
+}
+
+// ffjson: skip
+type TokenBucket struct {
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+	lock   sync.Mutex
+}
+
+// NewTokenBucket creates a bucket refilling at rate tokens per second and
+// holding at most burst tokens. A rate of zero or less never limits.
+func NewTokenBucket(rate float64, burst int) *TokenBucket {
+	if burst < 1 {
+		burst = int(math.Max(1, math.Ceil(rate)))
+	}
+	return &TokenBucket{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+func (tb *TokenBucket) Unlimited() bool {
+	return tb.rate <= 0
+}
+
+// Take removes a token from the bucket if one is available. When none is,
+// the allowance says how long until the next one will be.
+func (tb *TokenBucket) Take() Allowance {
+	return tb.TakeN(1)
+}
+
+// TakeN removes n tokens from the bucket if they are available. As a bucket
+// never holds more than its burst, costlier requests are allowed once it is
+// full, leaving it in debt until it has refilled by the difference.
+func (tb *TokenBucket) TakeN(n int) Allowance {
+	if tb.Unlimited() || n <= 0 {
+		return Allowance{Allowed: true}
+	}
+
+	tb.lock.Lock()
+	defer tb.lock.Unlock()
+
+	now := time.Now()
+	tb.tokens = math.Min(tb.burst, tb.tokens+now.Sub(tb.last).Seconds()*tb.rate)
+	tb.last = now
+
+	if needed := math.Min(tb.burst, float64(n)); tb.tokens < needed {
+		return Allowance{
+			Allowed:    false,
+			Limit:      int(tb.burst),
+			RetryAfter: time.Duration((needed - tb.tokens) / tb.rate * float64(time.Second)),
+		}
+	}
+
+	tb.tokens -= float64(n)
+	return Allowance{Allowed: true, Limit: int(tb.burst), Remaining: int(math.Max(0, tb.tokens))}
+}
+
+const (
+	RateLimitByIp     = "ip"
+	RateLimitByApiKey = "apikey"
+	RateLimitGlobal   = "global"
+)
+
+var RateLimitKeys = map[string]bool{
+	RateLimitByIp:     true,
+	RateLimitByApiKey: true,
+	RateLimitGlobal:   true,
+}
+
+// MaxRateLimitBuckets bounds the number of keys a RateLimiter tracks at
+// once.
+const MaxRateLimitBuckets = 100000
+
+// ffjson: skip
+type RateLimiter struct {
+	rate       float64
+	burst      int
+	buckets    map[string]*TokenBucket
+	maxBuckets int
+	lastPrune  time.Time
+	lock       sync.Mutex
+
+	// overflow is shared by the keys that arrive while the limiter is
+	// tracking its maximum number of keys.
+	overflow *TokenBucket
+}
+
+// NewRateLimiter creates a limiter that keeps a separate TokenBucket, with
+// the given rate and burst, for every key it is asked about, up to
+// MaxRateLimitBuckets of them.
+func NewRateLimiter(rate float64, burst int) *RateLimiter {
+	return &RateLimiter{
+		rate:       rate,
+		burst:      burst,
+		buckets:    map[string]*TokenBucket{},
+		maxBuckets: MaxRateLimitBuckets,
+		lastPrune:  time.Now(),
+		overflow:   NewTokenBucket(rate, burst),
+	}
+}
+
+// Take removes a token from the bucket for key, creating it if needed.
+func (rl *RateLimiter) Take(key string) Allowance {
+	return rl.TakeN(key, 1)
+}
+
+// TakeN removes n tokens from the bucket for key, creating it if needed.
+// While the limiter is tracking its maximum number of keys, new keys share
+// a single bucket.
+func (rl *RateLimiter) TakeN(key string, n int) Allowance {
+	rl.lock.Lock()
+	rl.prune(time.Minute)
+	bucket, found := rl.buckets[key]
+	if !found {
+		if len(rl.buckets) >= rl.maxBuckets {
+			rl.prune(time.Second)
+		}
+		if len(rl.buckets) < rl.maxBuckets {
+			bucket = NewTokenBucket(rl.rate, rl.burst)
+			rl.buckets[key] = bucket
+		} else {
+			bucket = rl.overflow
+		}
+	}
+	rl.lock.Unlock()
+
+	return bucket.TakeN(n)
+}
+
+// prune drops buckets that have been idle long enough to have refilled
+// completely, as they are indistinguishable from new ones, unless it did so
+// less than interval ago. It must be called with the lock held.
+func (rl *RateLimiter) prune(interval time.Duration) {
+	if time.Since(rl.lastPrune) < interval {
+		return
+	}
+	rl.lastPrune = time.Now()
+
+	for key, bucket := range rl.buckets {
+		bucket.lock.Lock()
+		idle := time.Since(bucket.last).Seconds()*bucket.rate + bucket.tokens
+		bucket.lock.Unlock()
+
+		if idle >= bucket.burst {
+			delete(rl.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package mm
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestTokenBucketTakeN(t *testing.T) {
+	bucket := NewTokenBucket(0.001, 10)
+
+	// a request costing more than the burst is allowed from a full bucket,
+	// leaving it in debt
+	if allowance := bucket.TakeN(25); !allowance.Allowed || allowance.Remaining != 0 {
+		t.Errorf("TakeN(25) = %+v; want allowed", allowance)
+	}
+	if allowance := bucket.TakeN(1); allowance.Allowed || allowance.RetryAfter.Seconds() < 15000 {
+		t.Errorf("TakeN(1) in debt = %+v; want a long wait", allowance)
+	}
+}
+
+func TestRateLimiterMaxBuckets(t *testing.T) {
+	limiter := NewRateLimiter(0.001, 1)
+	limiter.maxBuckets = 3
+
+	for idx := 0; idx < 3; idx++ {
+		if !limiter.Take(fmt.Sprint(idx)).Allowed {
+			t.Errorf("Take(%d) was not allowed", idx)
+		}
+	}
+
+	// beyond the maximum, new keys share a bucket
+	if !limiter.Take("3").Allowed || limiter.Take("4").Allowed {
+		t.Error("new keys beyond the maximum did not share a bucket")
+	}
+	if len(limiter.buckets) != 3 {
+		t.Errorf("limiter has %d buckets; want 3", len(limiter.buckets))
+	}
+}
@@ -0,0 +1,166 @@
+package mmdb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// data types of the MaxMind DB format
+const (
+	typeExtended = iota
+	typePointer
+	typeString
+	typeDouble
+	typeBytes
+	typeUint16
+	typeUint32
+	typeMap
+	typeInt32
+	typeUint64
+	typeUint128
+	typeArray
+	typeContainer
+	typeEndMarker
+	typeBool
+	typeFloat
+)
+
+// writeControl writes the control byte, and any extended type and size
+// bytes, that precede a value of type typeNum and size.
+func writeControl(buf *bytes.Buffer, typeNum int, size int) {
+	var control byte
+	var extended []byte
+
+	if typeNum > typeMap {
+		extended = []byte{byte(typeNum - typeMap)}
+	} else {
+		control = byte(typeNum << 5)
+	}
+
+	var sizeBytes []byte
+	switch {
+	case size < 29:
+		control |= byte(size)
+	case size < 29+256:
+		control |= 29
+		sizeBytes = []byte{byte(size - 29)}
+	case size < 285+65536:
+		control |= 30
+		size -= 285
+		sizeBytes = []byte{byte(size >> 8), byte(size)}
+	default:
+		control |= 31
+		size -= 65821
+		sizeBytes = []byte{byte(size >> 16), byte(size >> 8), byte(size)}
+	}
+
+	buf.WriteByte(control)
+	buf.Write(extended)
+	buf.Write(sizeBytes)
+}
+
+// writeUint writes value in the fewest big-endian bytes, up to width.
+func writeUint(buf *bytes.Buffer, typeNum int, value uint64, width int) {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], value)
+
+	data := b[8-width:]
+	for len(data) > 0 && data[0] == 0 {
+		data = data[1:]
+	}
+
+	writeControl(buf, typeNum, len(data))
+	buf.Write(data)
+}
+
+// encode appends value to buf in the MaxMind DB data format. Maps are
+// written with their keys sorted, so that equal values encode equally.
+func encode(buf *bytes.Buffer, value interface{}) error {
+	switch v := value.(type) {
+	case string:
+		writeControl(buf, typeString, len(v))
+		buf.WriteString(v)
+	case []byte:
+		writeControl(buf, typeBytes, len(v))
+		buf.Write(v)
+	case bool:
+		size := 0
+		if v {
+			size = 1
+		}
+		writeControl(buf, typeBool, size)
+	case float64:
+		writeControl(buf, typeDouble, 8)
+		binary.Write(buf, binary.BigEndian, math.Float64bits(v))
+	case float32:
+		writeControl(buf, typeFloat, 4)
+		binary.Write(buf, binary.BigEndian, math.Float32bits(v))
+	case uint16:
+		writeUint(buf, typeUint16, uint64(v), 2)
+	case uint32:
+		writeUint(buf, typeUint32, uint64(v), 4)
+	case uint64:
+		writeUint(buf, typeUint64, v, 8)
+	case uint:
+		writeUint(buf, typeUint64, uint64(v), 8)
+	case int32:
+		if v < 0 {
+			writeControl(buf, typeInt32, 4)
+			binary.Write(buf, binary.BigEndian, v)
+		} else {
+			writeUint(buf, typeInt32, uint64(v), 4)
+		}
+	case int:
+		if v < math.MinInt32 || v > math.MaxUint32 {
+			return fmt.Errorf("integer %d is out of range", v)
+		}
+		if v < 0 {
+			return encode(buf, int32(v))
+		}
+		return encode(buf, uint32(v))
+	case []string:
+		writeControl(buf, typeArray, len(v))
+		for _, item := range v {
+			encode(buf, item)
+		}
+	case []interface{}:
+		writeControl(buf, typeArray, len(v))
+		for _, item := range v {
+			if err := encode(buf, item); err != nil {
+				return err
+			}
+		}
+	case map[string]string:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		writeControl(buf, typeMap, len(v))
+		for _, key := range keys {
+			encode(buf, key)
+			encode(buf, v[key])
+		}
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		writeControl(buf, typeMap, len(v))
+		for _, key := range keys {
+			encode(buf, key)
+			if err := encode(buf, v[key]); err != nil {
+				return fmt.Errorf("%s: %s", key, err)
+			}
+		}
+	default:
+		return fmt.Errorf("unsupported type %T", value)
+	}
+	return nil
+}
@@ -0,0 +1,398 @@
+package mmdb
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Fixture describes a database, its metadata and the networks it holds,
+// as read from JSON or YAML.
+type Fixture struct {
+	DatabaseType string            `json:"database_type" yaml:"database_type"`
+	Description  map[string]string `json:"description" yaml:"description"`
+	Languages    []string          `json:"languages" yaml:"languages"`
+	IpVersion    int               `json:"ip_version" yaml:"ip_version"`
+	RecordSize   int               `json:"record_size" yaml:"record_size"`
+	BuildEpoch   int64             `json:"build_epoch" yaml:"build_epoch"`
+	Networks     []FixtureNetwork  `json:"networks" yaml:"networks"`
+}
+
+type FixtureNetwork struct {
+	Network string                 `json:"network" yaml:"network"`
+	Record  map[string]interface{} `json:"record" yaml:"record"`
+}
+
+// fieldTypes are the MaxMind DB types of the numeric and boolean fields of
+// the GeoIP2 and GeoLite2 databases, by key.
+var fieldTypes = map[string]string{
+	"accuracy_radius":          "uint16",
+	"average_income":           "uint32",
+	"autonomous_system_number": "uint32",
+	"confidence":               "uint16",
+	"geoname_id":               "uint32",
+	"latitude":                 "double",
+	"longitude":                "double",
+	"metro_code":               "uint16",
+	"population_density":       "uint32",
+}
+
+// CsvColumns are the short names CSV columns may use for the fields of
+// GeoIP2 City records, by the dotted path of the field.
+var CsvColumns = map[string]string{
+	"continent":        "continent.code",
+	"continent_name":   "continent.names.en",
+	"country":          "country.iso_code",
+	"country_name":     "country.names.en",
+	"subdivision":      "subdivisions.0.iso_code",
+	"subdivision_name": "subdivisions.0.names.en",
+	"city":             "city.names.en",
+	"postal":           "postal.code",
+	"latitude":         "location.latitude",
+	"lat":              "location.latitude",
+	"longitude":        "location.longitude",
+	"lon":              "location.longitude",
+	"accuracy_radius":  "location.accuracy_radius",
+	"time_zone":        "location.time_zone",
+}
+
+// fieldType returns the type of the field named key, or "" if it is not
+// known.
+func fieldType(key string) string {
+	if strings.HasPrefix(key, "is_") {
+		return "bool"
+	}
+	return fieldTypes[key]
+}
+
+// convert parses text as a value of the MaxMind DB type typeName.
+func convert(text string, typeName string) (interface{}, error) {
+	switch typeName {
+	case "", "string":
+		return text, nil
+	case "bool":
+		return strconv.ParseBool(text)
+	case "double":
+		return strconv.ParseFloat(text, 64)
+	case "float":
+		v, err := strconv.ParseFloat(text, 32)
+		return float32(v), err
+	case "uint16":
+		v, err := strconv.ParseUint(text, 10, 16)
+		return uint16(v), err
+	case "uint32":
+		v, err := strconv.ParseUint(text, 10, 32)
+		return uint32(v), err
+	case "uint64":
+		return strconv.ParseUint(text, 10, 64)
+	case "int32":
+		v, err := strconv.ParseInt(text, 10, 32)
+		return int32(v), err
+	}
+	return nil, fmt.Errorf("unknown type `%s`", typeName)
+}
+
+// typed converts value, as decoded from JSON or YAML, into the MaxMind DB
+// type of the field named key. Numbers of unknown fields are stored as
+// uint32s or int32s when integral, and as doubles otherwise.
+func typed(key string, value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		record := make(map[string]interface{}, len(v))
+		for k, item := range v {
+			converted, err := typed(k, item)
+			if err != nil {
+				return nil, err
+			}
+			record[k] = converted
+		}
+		return record, nil
+	case []interface{}:
+		items := make([]interface{}, len(v))
+		for idx, item := range v {
+			converted, err := typed(key, item)
+			if err != nil {
+				return nil, err
+			}
+			items[idx] = converted
+		}
+		return items, nil
+	case int:
+		return typed(key, float64(v))
+	case float64:
+		switch t := fieldType(key); {
+		case t == "double":
+			return v, nil
+		case t != "" && t != "bool":
+			return convert(strconv.FormatFloat(v, 'f', -1, 64), t)
+		case v == math.Trunc(v) && v >= 0 && v <= math.MaxUint32:
+			return uint32(v), nil
+		case v == math.Trunc(v) && v < 0 && v >= math.MinInt32:
+			return int32(v), nil
+		}
+		return v, nil
+	case string, bool:
+		return v, nil
+	case nil:
+		return nil, fmt.Errorf("%s has no value", key)
+	}
+	return nil, fmt.Errorf("%s has unsupported type %T", key, value)
+}
+
+// parseNetwork parses a network in CIDR notation, or a single address.
+func parseNetwork(text string) (*net.IPNet, error) {
+	if !strings.Contains(text, "/") {
+		ip := net.ParseIP(text)
+		if ip == nil {
+			return nil, fmt.Errorf("invalid network `%s`", text)
+		}
+		if ip4 := ip.To4(); ip4 != nil {
+			return &net.IPNet{IP: ip4, Mask: net.CIDRMask(32, 32)}, nil
+		}
+		return &net.IPNet{IP: ip, Mask: net.CIDRMask(128, 128)}, nil
+	}
+
+	_, network, err := net.ParseCIDR(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid network `%s`", text)
+	}
+	return network, nil
+}
+
+// NewWriterFromFixture creates a writer holding the fixture's networks.
+func NewWriterFromFixture(fixture *Fixture) (*Writer, error) {
+	ipVersion := fixture.IpVersion
+	if ipVersion == 0 {
+		ipVersion = 6
+	}
+
+	w, err := NewWriter(fixture.DatabaseType, ipVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	w.RecordSize = fixture.RecordSize
+	if fixture.Description != nil {
+		w.Description = fixture.Description
+	}
+	if fixture.Languages != nil {
+		w.Languages = fixture.Languages
+	}
+	if fixture.BuildEpoch != 0 {
+		w.BuildTime = time.Unix(fixture.BuildEpoch, 0)
+	}
+
+	for _, n := range fixture.Networks {
+		network, err := parseNetwork(n.Network)
+		if err != nil {
+			return nil, err
+		}
+
+		record, err := typed("", map[string]interface{}(n.Record))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", n.Network, err)
+		}
+
+		if err = w.Insert(network, record); err != nil {
+			return nil, err
+		}
+	}
+
+	return w, nil
+}
+
+// setPath sets the value at the dot separated path within record, e.g.
+// country.names.en. Numeric path elements index arrays, as in
+// subdivisions.0.iso_code.
+func setPath(record map[string]interface{}, path []string, value interface{}) {
+	for _, key := range path[:len(path)-1] {
+		child, ok := record[key].(map[string]interface{})
+		if !ok {
+			child = map[string]interface{}{}
+			record[key] = child
+		}
+		record = child
+	}
+	record[path[len(path)-1]] = value
+}
+
+// arrays replaces the maps of value whose keys are all indexes with arrays.
+func arrays(value interface{}) interface{} {
+	record, ok := value.(map[string]interface{})
+	if !ok {
+		return value
+	}
+
+	indexes := make([]int, 0, len(record))
+	for key, item := range record {
+		record[key] = arrays(item)
+		if idx, err := strconv.Atoi(key); err == nil && idx >= 0 {
+			indexes = append(indexes, idx)
+		}
+	}
+
+	if len(record) == 0 || len(indexes) != len(record) {
+		return record
+	}
+
+	sort.Ints(indexes)
+	items := make([]interface{}, 0, len(indexes))
+	for _, idx := range indexes {
+		items = append(items, record[strconv.Itoa(idx)])
+	}
+	return items
+}
+
+// ReadCsv inserts the networks of CSV data into w. The header names a
+// network column, and the dot separated path of each other column within
+// the record, optionally followed by its type, as in
+// `location.latitude:double`, or one of the short names of CsvColumns.
+// Empty cells are left out of the record.
+func (w *Writer) ReadCsv(in io.Reader) error {
+	reader := csv.NewReader(in)
+	reader.Comment = '#'
+
+	header, err := reader.Read()
+	if err != nil {
+		return fmt.Errorf("unable to read csv header: %s", err)
+	}
+
+	networkColumn := -1
+	paths := make([][]string, len(header))
+	types := make([]string, len(header))
+	for idx, column := range header {
+		name, typeName := strings.TrimSpace(column), ""
+		if colon := strings.LastIndex(name, ":"); colon >= 0 {
+			name, typeName = name[:colon], name[colon+1:]
+		}
+
+		if name == "network" {
+			networkColumn = idx
+			continue
+		}
+
+		if path, found := CsvColumns[name]; found {
+			name = path
+		}
+
+		paths[idx] = strings.Split(name, ".")
+		if typeName == "" {
+			typeName = fieldType(paths[idx][len(paths[idx])-1])
+		}
+		if _, err := convert("0", typeName); err != nil {
+			return fmt.Errorf("column %s: %s", column, err)
+		}
+		types[idx] = typeName
+	}
+
+	if networkColumn < 0 {
+		return errors.New("csv has no network column")
+	}
+
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		network, err := parseNetwork(strings.TrimSpace(row[networkColumn]))
+		if err != nil {
+			line, _ := reader.FieldPos(networkColumn)
+			return fmt.Errorf("line %d: %s", line, err)
+		}
+
+		record := map[string]interface{}{}
+		for idx, cell := range row {
+			if idx == networkColumn || cell == "" {
+				continue
+			}
+
+			value, err := convert(cell, types[idx])
+			if err != nil {
+				line, _ := reader.FieldPos(idx)
+				return fmt.Errorf("line %d, column %s: %s", line, header[idx], err)
+			}
+			setPath(record, paths[idx], value)
+		}
+
+		if err = w.Insert(network, arrays(record)); err != nil {
+			return err
+		}
+	}
+}
+
+// LoadFixture reads the description of a database from a JSON (.json),
+// YAML (.yaml or .yml) or CSV (.csv) file. CSV files describe only the
+// networks; the database is an IPv6 one named after the file, e.g.
+// GeoLite2-ASN.csv describes a GeoLite2-ASN database.
+func LoadFixture(path string) (*Writer, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	ext := strings.ToLower(filepath.Ext(path))
+
+	var fixture Fixture
+	switch ext {
+	case ".json":
+		err = json.Unmarshal(data, &fixture)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &fixture)
+	case ".csv":
+		w, err := NewWriter(strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)), 6)
+		if err != nil {
+			return nil, err
+		}
+		if err = w.ReadCsv(strings.NewReader(string(data))); err != nil {
+			return nil, fmt.Errorf("%s: %s", path, err)
+		}
+		return w, nil
+	default:
+		return nil, fmt.Errorf("%s: unknown fixture format `%s`; expected .json, .yaml, .yml or .csv", path, ext)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", path, err)
+	}
+	return NewWriterFromFixture(&fixture)
+}
+
+// BuildFixture writes the database described by the file at path, as read
+// by LoadFixture, to out.
+func BuildFixture(path string, out string) error {
+	w, err := LoadFixture(path)
+	if err != nil {
+		return err
+	}
+	return w.WriteFile(out)
+}
+
+// WriteFile writes the database to the file at path.
+func (w *Writer) WriteFile(path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if _, err = w.WriteTo(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,308 @@
+// Package mmdb writes databases in the MaxMind DB format, such as test
+// fixtures or databases of internal network ranges, that mm.Database and
+// the server can load.
+package mmdb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"time"
+)
+
+// metadataMarker separates the data section from the metadata.
+var metadataMarker = []byte("\xab\xcd\xefMaxMind.com")
+
+// ipv4Aliases are the networks of an IPv6 database that embed IPv4
+// addresses, and so are aliased to the IPv4 networks at ::/96.
+var ipv4Aliases = []*net.IPNet{
+	{IP: net.ParseIP("::ffff:0:0"), Mask: net.CIDRMask(96, 128)},
+	{IP: net.ParseIP("2002::"), Mask: net.CIDRMask(16, 128)},
+}
+
+type node struct {
+	children [2]*node
+	data     *record
+}
+
+type record struct {
+	offset int
+}
+
+// Writer builds a MaxMind DB in memory. Networks are inserted with their
+// records, and later networks take precedence over the parts of earlier
+// ones they overlap.
+type Writer struct {
+	// DatabaseType names the structure of the records, e.g. GeoIP2-City
+	DatabaseType string
+
+	Description map[string]string
+	Languages   []string
+
+	// IpVersion is 4 or 6; IPv4 networks are stored under ::/96 of an
+	// IPv6 database.
+	IpVersion int
+
+	// RecordSize is the size, in bits, of the search tree records; 24, 28
+	// or 32. When 0, the smallest size that fits the database is used.
+	RecordSize int
+
+	// BuildTime is recorded as the build epoch; the time the database is
+	// written when zero.
+	BuildTime time.Time
+
+	// AliasIpv4 makes the IPv4-mapped (::ffff:0:0/96) and 6to4 (2002::/16)
+	// networks of an IPv6 database resolve as the IPv4 addresses they
+	// embed, as MaxMind's own databases do. Networks within them cannot be
+	// inserted.
+	AliasIpv4 bool
+
+	root    *node
+	data    bytes.Buffer
+	records map[string]*record
+}
+
+func NewWriter(databaseType string, ipVersion int) (*Writer, error) {
+	if ipVersion != 4 && ipVersion != 6 {
+		return nil, fmt.Errorf("invalid ip version %d; expected 4 or 6", ipVersion)
+	}
+
+	return &Writer{
+		DatabaseType: databaseType,
+		Description:  map[string]string{},
+		Languages:    []string{},
+		IpVersion:    ipVersion,
+		root:         &node{},
+		records:      map[string]*record{},
+	}, nil
+}
+
+// networkBits returns the address of network as IpVersion sized bytes, and
+// the length of its prefix within them.
+func (w *Writer) networkBits(network *net.IPNet) ([]byte, int, error) {
+	ones, bits := network.Mask.Size()
+	if bits == 0 {
+		return nil, 0, fmt.Errorf("network %s has a non-canonical mask", network)
+	}
+
+	if ip := network.IP.To4(); ip != nil && bits == 32 {
+		if w.IpVersion == 4 {
+			return ip, ones, nil
+		}
+		// IPv4 networks live at ::a.b.c.d of an IPv6 database
+		return append(make([]byte, 12), ip...), ones + 96, nil
+	}
+
+	if w.IpVersion == 4 {
+		return nil, 0, fmt.Errorf("cannot insert IPv6 network %s into an IPv4 database", network)
+	}
+
+	if w.AliasIpv4 {
+		for _, alias := range ipv4Aliases {
+			aliasOnes, _ := alias.Mask.Size()
+			if alias.Contains(network.IP) && ones >= aliasOnes {
+				return nil, 0, fmt.Errorf("network %s is within %s, which is aliased to the IPv4 networks", network, alias)
+			}
+		}
+	}
+	return network.IP.To16(), ones, nil
+}
+
+// addRecord encodes value into the data section, reusing the record of an
+// equal value written before.
+func (w *Writer) addRecord(value interface{}) (*record, error) {
+	var buf bytes.Buffer
+	if err := encode(&buf, value); err != nil {
+		return nil, err
+	}
+
+	if r, found := w.records[buf.String()]; found {
+		return r, nil
+	}
+
+	r := &record{offset: w.data.Len()}
+	w.data.Write(buf.Bytes())
+	w.records[buf.String()] = r
+	return r, nil
+}
+
+// Insert stores value as the record of every address in network. Values
+// are strings, bools, float64s (doubles), float32s, uint16s, uint32s,
+// uint64s, int32s, []byte, and slices and string keyed maps of them.
+func (w *Writer) Insert(network *net.IPNet, value interface{}) error {
+	address, prefix, err := w.networkBits(network)
+	if err != nil {
+		return err
+	}
+
+	r, err := w.addRecord(value)
+	if err != nil {
+		return fmt.Errorf("unable to encode the record of %s: %s", network, err)
+	}
+
+	w.insert(address, prefix, &node{data: r})
+	return nil
+}
+
+// insert places leaf at prefix bits of address, splitting any broader
+// leaf on the way so that the rest of it keeps its record.
+func (w *Writer) insert(address []byte, prefix int, leaf *node) {
+	if prefix == 0 {
+		// the root is always a node, so a network of everything is its
+		// two halves
+		w.root = &node{children: [2]*node{leaf, leaf}}
+		return
+	}
+
+	current := w.root
+	for depth := 0; depth < prefix-1; depth++ {
+		bit := (address[depth/8] >> (7 - uint(depth%8))) & 1
+
+		child := current.children[bit]
+		switch {
+		case child == nil:
+			child = &node{}
+		case child.data != nil:
+			child = &node{children: [2]*node{child, child}}
+		}
+		current.children[bit] = child
+		current = child
+	}
+
+	bit := (address[(prefix-1)/8] >> (7 - uint((prefix-1)%8))) & 1
+	current.children[bit] = leaf
+}
+
+// alias points the IPv4 alias networks at the node holding the IPv4
+// networks, ::/96, so that they share its subtree.
+func (w *Writer) alias() {
+	ipv4 := w.root
+	for depth := 0; depth < 96 && ipv4 != nil && ipv4.data == nil; depth++ {
+		ipv4 = ipv4.children[0]
+	}
+	if ipv4 == nil {
+		return
+	}
+
+	for _, network := range ipv4Aliases {
+		ones, _ := network.Mask.Size()
+		w.insert(network.IP.To16(), ones, ipv4)
+	}
+}
+
+// number assigns each node of the tree its index, breadth first, returning
+// the nodes in order.
+func (w *Writer) number() ([]*node, map[*node]int) {
+	nodes := []*node{w.root}
+	index := map[*node]int{w.root: 0}
+
+	for idx := 0; idx < len(nodes); idx++ {
+		for _, child := range nodes[idx].children {
+			if child == nil || child.data != nil {
+				continue
+			}
+			if _, seen := index[child]; !seen {
+				index[child] = len(nodes)
+				nodes = append(nodes, child)
+			}
+		}
+	}
+	return nodes, index
+}
+
+func recordSizeFor(maxValue uint64) (int, error) {
+	for _, size := range []int{24, 28, 32} {
+		if maxValue < 1<<uint(size) {
+			return size, nil
+		}
+	}
+	return 0, errors.New("database is too large for a 32 bit record size")
+}
+
+func writeNode(tree []byte, offset int, recordSize int, left uint32, right uint32) {
+	switch recordSize {
+	case 24:
+		tree[offset+0], tree[offset+1], tree[offset+2] = byte(left>>16), byte(left>>8), byte(left)
+		tree[offset+3], tree[offset+4], tree[offset+5] = byte(right>>16), byte(right>>8), byte(right)
+	case 28:
+		tree[offset+0], tree[offset+1], tree[offset+2] = byte(left>>16), byte(left>>8), byte(left)
+		tree[offset+3] = byte((left>>24)&0x0f)<<4 | byte((right>>24)&0x0f)
+		tree[offset+4], tree[offset+5], tree[offset+6] = byte(right>>16), byte(right>>8), byte(right)
+	case 32:
+		binary.BigEndian.PutUint32(tree[offset:], left)
+		binary.BigEndian.PutUint32(tree[offset+4:], right)
+	}
+}
+
+// WriteTo writes the database to out.
+func (w *Writer) WriteTo(out io.Writer) (int64, error) {
+	if w.AliasIpv4 && w.IpVersion == 6 {
+		w.alias()
+	}
+
+	nodes, index := w.number()
+	nodeCount := len(nodes)
+
+	recordSize := w.RecordSize
+	maxValue := uint64(nodeCount) + 16 + uint64(w.data.Len())
+	if recordSize == 0 {
+		var err error
+		if recordSize, err = recordSizeFor(maxValue); err != nil {
+			return 0, err
+		}
+	} else if recordSize != 24 && recordSize != 28 && recordSize != 32 {
+		return 0, fmt.Errorf("invalid record size %d; expected 24, 28 or 32", recordSize)
+	} else if maxValue >= 1<<uint(recordSize) {
+		return 0, fmt.Errorf("database is too large for a %d bit record size", recordSize)
+	}
+
+	value := func(child *node) uint32 {
+		switch {
+		case child == nil:
+			return uint32(nodeCount)
+		case child.data != nil:
+			return uint32(nodeCount + 16 + child.data.offset)
+		}
+		return uint32(index[child])
+	}
+
+	nodeSize := recordSize / 4
+	tree := make([]byte, nodeCount*nodeSize)
+	for idx, n := range nodes {
+		writeNode(tree, idx*nodeSize, recordSize, value(n.children[0]), value(n.children[1]))
+	}
+
+	buildTime := w.BuildTime
+	if buildTime.IsZero() {
+		buildTime = time.Now()
+	}
+
+	var metadata bytes.Buffer
+	err := encode(&metadata, map[string]interface{}{
+		"binary_format_major_version": uint16(2),
+		"binary_format_minor_version": uint16(0),
+		"build_epoch":                 uint64(buildTime.Unix()),
+		"database_type":               w.DatabaseType,
+		"description":                 w.Description,
+		"ip_version":                  uint16(w.IpVersion),
+		"languages":                   w.Languages,
+		"node_count":                  uint32(nodeCount),
+		"record_size":                 uint16(recordSize),
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	var written int64
+	for _, chunk := range [][]byte{tree, make([]byte, 16), w.data.Bytes(), metadataMarker, metadata.Bytes()} {
+		n, err := out.Write(chunk)
+		written += int64(n)
+		if err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
@@ -0,0 +1,354 @@
+package mmdb
+
+import (
+	"bytes"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	maxminddb "github.com/oschwald/maxminddb-golang"
+)
+
+func mustNetwork(t *testing.T, text string) *net.IPNet {
+	network, err := parseNetwork(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return network
+}
+
+func open(t *testing.T, w *Writer) *maxminddb.Reader {
+	var buf bytes.Buffer
+	if _, err := w.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	reader, err := maxminddb.FromBytes(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return reader
+}
+
+func lookup(t *testing.T, reader *maxminddb.Reader, ip string) interface{} {
+	var result interface{}
+	if err := reader.Lookup(net.ParseIP(ip), &result); err != nil {
+		t.Fatal(err)
+	}
+	return result
+}
+
+func TestWriterRoundTripsValues(t *testing.T) {
+	w, err := NewWriter("Test", 6)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	long := strings.Repeat("x", 70000)
+	value := map[string]interface{}{
+		"string":   "value",
+		"long":     long,
+		"bytes":    []byte{1, 2, 3},
+		"true":     true,
+		"false":    false,
+		"double":   -12.5,
+		"float":    float32(1.5),
+		"uint16":   uint16(300),
+		"uint32":   uint32(70000),
+		"uint64":   uint64(1) << 40,
+		"int32":    int32(-5),
+		"zero":     uint32(0),
+		"array":    []interface{}{"a", uint16(1)},
+		"strings":  []string{"en", "de"},
+		"map":      map[string]string{"en": "London"},
+		"nested":   map[string]interface{}{"names": map[string]interface{}{"en": "Europe"}},
+		"positive": int32(5),
+	}
+	if err = w.Insert(mustNetwork(t, "1.2.3.0/24"), value); err != nil {
+		t.Fatal(err)
+	}
+
+	got := lookup(t, open(t, w), "1.2.3.4")
+	want := map[string]interface{}{
+		"string":   "value",
+		"long":     long,
+		"bytes":    []byte{1, 2, 3},
+		"true":     true,
+		"false":    false,
+		"double":   -12.5,
+		"float":    float32(1.5),
+		"uint16":   uint64(300),
+		"uint32":   uint64(70000),
+		"uint64":   uint64(1) << 40,
+		"int32":    -5,
+		"zero":     uint64(0),
+		"array":    []interface{}{"a", uint64(1)},
+		"strings":  []interface{}{"en", "de"},
+		"map":      map[string]interface{}{"en": "London"},
+		"nested":   map[string]interface{}{"names": map[string]interface{}{"en": "Europe"}},
+		"positive": 5,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %#v\nwant %#v", got, want)
+	}
+}
+
+func TestWriterLaterNetworksOverrideEarlier(t *testing.T) {
+	for _, version := range []int{4, 6} {
+		w, err := NewWriter("Test", version)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		w.Insert(mustNetwork(t, "10.0.0.0/8"), "broad")
+		w.Insert(mustNetwork(t, "10.1.0.0/16"), "narrow")
+		w.Insert(mustNetwork(t, "10.1.2.0/24"), "narrowest")
+		w.Insert(mustNetwork(t, "10.1.0.0/16"), "replaced")
+
+		reader := open(t, w)
+		for ip, want := range map[string]interface{}{
+			"10.200.0.1": "broad",
+			"10.1.0.1":   "replaced",
+			"10.1.2.3":   "replaced",
+			"11.0.0.1":   nil,
+		} {
+			if got := lookup(t, reader, ip); got != want {
+				t.Errorf("ipv%d: %s = %v; want %v", version, ip, got, want)
+			}
+		}
+	}
+}
+
+func TestWriterRecordSizes(t *testing.T) {
+	for _, size := range []int{24, 28, 32} {
+		w, _ := NewWriter("Test", 6)
+		w.RecordSize = size
+		w.Insert(mustNetwork(t, "2001:db8::/32"), "v6")
+		w.Insert(mustNetwork(t, "192.0.2.0/24"), "v4")
+
+		reader := open(t, w)
+		if reader.Metadata.RecordSize != uint(size) {
+			t.Errorf("record size = %d; want %d", reader.Metadata.RecordSize, size)
+		}
+		if got := lookup(t, reader, "2001:db8::1"); got != "v6" {
+			t.Errorf("%d bit records: 2001:db8::1 = %v", size, got)
+		}
+		if got := lookup(t, reader, "192.0.2.1"); got != "v4" {
+			t.Errorf("%d bit records: 192.0.2.1 = %v", size, got)
+		}
+	}
+}
+
+func TestWriterMetadata(t *testing.T) {
+	w, _ := NewWriter("GeoIP2-City", 6)
+	w.Languages = []string{"en"}
+	w.Description = map[string]string{"en": "test"}
+	w.BuildTime = time.Unix(1524254400, 0)
+	w.Insert(mustNetwork(t, "::/0"), "everything")
+
+	reader := open(t, w)
+	metadata := reader.Metadata
+	if metadata.DatabaseType != "GeoIP2-City" || metadata.IPVersion != 6 || metadata.BuildEpoch != 1524254400 ||
+		metadata.RecordSize != 24 || metadata.BinaryFormatMajorVersion != 2 ||
+		!reflect.DeepEqual(metadata.Languages, []string{"en"}) || metadata.Description["en"] != "test" {
+		t.Errorf("unexpected metadata %#v", metadata)
+	}
+
+	if got := lookup(t, reader, "8.8.8.8"); got != "everything" {
+		t.Errorf("8.8.8.8 = %v", got)
+	}
+}
+
+func TestWriterRejectsMismatchedNetworks(t *testing.T) {
+	w, _ := NewWriter("Test", 4)
+	if err := w.Insert(mustNetwork(t, "2001:db8::/32"), "v6"); err == nil {
+		t.Error("inserted an IPv6 network into an IPv4 database")
+	}
+
+	if _, err := NewWriter("Test", 5); err == nil {
+		t.Error("created a database of ip version 5")
+	}
+}
+
+func TestLoadFixture(t *testing.T) {
+	w, err := LoadFixture(filepath.Join("..", "testdata", "GeoIP2-City-Test.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var record struct {
+		City struct {
+			GeoNameID uint              `maxminddb:"geoname_id"`
+			Names     map[string]string `maxminddb:"names"`
+		} `maxminddb:"city"`
+		Location struct {
+			AccuracyRadius uint16  `maxminddb:"accuracy_radius"`
+			Latitude       float64 `maxminddb:"latitude"`
+		} `maxminddb:"location"`
+		Country struct {
+			IsInEuropeanUnion bool `maxminddb:"is_in_european_union"`
+		} `maxminddb:"country"`
+	}
+
+	reader := open(t, w)
+	if err = reader.Lookup(net.ParseIP("81.2.69.142"), &record); err != nil {
+		t.Fatal(err)
+	}
+	if record.City.GeoNameID != 2643743 || record.City.Names["de"] != "London" ||
+		record.Location.AccuracyRadius != 10 || record.Location.Latitude != 51.5142 ||
+		!record.Country.IsInEuropeanUnion {
+		t.Errorf("unexpected record %+v", record)
+	}
+}
+
+func TestLoadFixtureYaml(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "fixture.yaml")
+	writeFile(t, path, `
+database_type: Test
+ip_version: 4
+networks:
+  - network: 192.0.2.0/24
+    record:
+      location: {accuracy_radius: 5, latitude: 1}
+      site: lab
+`)
+
+	w, err := LoadFixture(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := lookup(t, open(t, w), "192.0.2.1")
+	want := map[string]interface{}{
+		"location": map[string]interface{}{"accuracy_radius": uint64(5), "latitude": float64(1)},
+		"site":     "lab",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %#v; want %#v", got, want)
+	}
+}
+
+func TestLoadFixtureCsv(t *testing.T) {
+	w, err := LoadFixture(filepath.Join("..", "testdata", "GeoLite2-ASN-Test.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reader := open(t, w)
+	if reader.Metadata.DatabaseType != "GeoLite2-ASN-Test" {
+		t.Errorf("database type = %s", reader.Metadata.DatabaseType)
+	}
+
+	got := lookup(t, reader, "2600:6000::1")
+	want := map[string]interface{}{
+		"autonomous_system_number":       uint64(237),
+		"autonomous_system_organization": "Merit Network Inc.",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %#v; want %#v", got, want)
+	}
+}
+
+func TestBuildFixture(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "GeoLite2-ASN-Test.mmdb")
+	if err := BuildFixture(filepath.Join("..", "testdata", "GeoLite2-ASN-Test.csv"), path); err != nil {
+		t.Fatal(err)
+	}
+
+	reader, err := maxminddb.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+	if got := lookup(t, reader, "1.128.0.1"); !reflect.DeepEqual(got, map[string]interface{}{
+		"autonomous_system_number":       uint64(1221),
+		"autonomous_system_organization": "Telstra Pty Ltd",
+	}) {
+		t.Errorf("got %#v", got)
+	}
+
+	if err = BuildFixture(filepath.Join("..", "testdata", "missing.json"), path); err == nil {
+		t.Error("BuildFixture of a missing fixture succeeded")
+	}
+}
+
+func TestReadCsvPaths(t *testing.T) {
+	w, _ := NewWriter("Test", 6)
+	err := w.ReadCsv(strings.NewReader(`network,country.iso_code,subdivisions.0.iso_code,subdivisions.1.iso_code,site.rack:uint16,postal.code
+192.0.2.0/24,GB,ENG,LND,12,02134
+198.51.100.1,,,,,
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reader := open(t, w)
+	got := lookup(t, reader, "192.0.2.9")
+	want := map[string]interface{}{
+		"country":      map[string]interface{}{"iso_code": "GB"},
+		"subdivisions": []interface{}{map[string]interface{}{"iso_code": "ENG"}, map[string]interface{}{"iso_code": "LND"}},
+		"site":         map[string]interface{}{"rack": uint64(12)},
+		"postal":       map[string]interface{}{"code": "02134"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %#v; want %#v", got, want)
+	}
+
+	if got := lookup(t, reader, "198.51.100.1"); !reflect.DeepEqual(got, map[string]interface{}{}) {
+		t.Errorf("198.51.100.1 = %#v", got)
+	}
+	if got := lookup(t, reader, "198.51.100.2"); got != nil {
+		t.Errorf("198.51.100.2 = %#v", got)
+	}
+
+	if err = w.ReadCsv(strings.NewReader("network,site:uint16\n192.0.2.0/24,rack\n")); err == nil {
+		t.Error("read a non-numeric uint16")
+	}
+	if err = w.ReadCsv(strings.NewReader("site\nrack\n")); err == nil {
+		t.Error("read a csv without a network column")
+	}
+}
+
+func writeFile(t *testing.T, path string, content string) {
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWriterAliasesIpv4(t *testing.T) {
+	w, _ := NewWriter("Test", 6)
+	w.AliasIpv4 = true
+	w.Insert(mustNetwork(t, "1.2.3.0/24"), "v4")
+	w.Insert(mustNetwork(t, "2000::/3"), "global unicast")
+
+	reader := open(t, w)
+	for ip, want := range map[string]interface{}{
+		"1.2.3.4":         "v4",
+		"2002:102:304::1": "v4",
+		"2002:102:404::1": nil,
+		"2001:db8::1":     "global unicast",
+		"2003::1":         "global unicast",
+		"::ffff:1.2.3.4":  "v4",
+	} {
+		if got := lookup(t, reader, ip); got != want {
+			t.Errorf("%s = %v; want %v", ip, got, want)
+		}
+	}
+
+	for _, network := range []string{"2002:102:300::/40", "::ffff:1.2.3.0/120"} {
+		if err := w.Insert(mustNetwork(t, network), "aliased"); err == nil {
+			t.Errorf("inserted %s, which is aliased", network)
+		}
+	}
+
+	// networks inserted after writing are aliased when written again
+	w.Insert(mustNetwork(t, "5.6.7.0/24"), "later")
+	if got := lookup(t, open(t, w), "2002:506:708::"); got != "later" {
+		t.Errorf("2002:506:708:: = %v", got)
+	}
+}
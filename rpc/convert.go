@@ -0,0 +1,64 @@
+package rpc
+
+import "github.com/rabbitt/maxmind/mm"
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative geoip.proto
+
+func NewFromGeoData(record *mm.GeoData) *GeoData {
+	if record == nil {
+		return nil
+	}
+
+	subdivisions := make([]*Subdivision, 0, len(record.Subdivisions))
+	for _, sub := range record.Subdivisions {
+		subdivisions = append(subdivisions, &Subdivision{
+			IsoCode: sub.IsoCode,
+			Name:    sub.Name,
+		})
+	}
+
+	return &GeoData{
+		City: &City{
+			Name: record.City.Name,
+		},
+		Continent: &Continent{
+			Code: record.Continent.Code,
+			Name: record.Continent.Name,
+		},
+		Country: &Country{
+			IsInEuropeanUnion: record.Country.IsInEuropeanUnion,
+			IsoCode:           record.Country.IsoCode,
+			Name:              record.Country.Name,
+		},
+		Location: &Location{
+			AccuracyRadius: uint32(record.Location.AccuracyRadius),
+			Latitude:       record.Location.Latitude,
+			Longitude:      record.Location.Longitude,
+			MetroCode:      uint32(record.Location.MetroCode),
+			TimeZone:       record.Location.TimeZone,
+		},
+		Postal: &Postal{
+			Code: record.Postal.Code,
+		},
+		RegisteredCountry: &Country{
+			IsInEuropeanUnion: record.RegisteredCountry.IsInEuropeanUnion,
+			IsoCode:           record.RegisteredCountry.IsoCode,
+			Name:              record.RegisteredCountry.Name,
+		},
+		RepresentedCountry: &RepresentedCountry{
+			IsInEuropeanUnion: record.RepresentedCountry.IsInEuropeanUnion,
+			IsoCode:           record.RepresentedCountry.IsoCode,
+			Name:              record.RepresentedCountry.Name,
+			Type:              record.RepresentedCountry.Type,
+		},
+		Subdivisions: subdivisions,
+		Subdivision: &Subdivision{
+			IsoCode: record.Subdivision.IsoCode,
+			Name:    record.Subdivision.Name,
+		},
+		Traits: &Traits{
+			IsAnonymousProxy:    record.Traits.IsAnonymousProxy,
+			IsSatelliteProvider: record.Traits.IsSatelliteProvider,
+		},
+	}
+}
@@ -0,0 +1,222 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             v3.21.12
+// source: geoip.proto
+
+package rpc
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	GeoIP_Lookup_FullMethodName       = "/maxmind.geoip.GeoIP/Lookup"
+	GeoIP_BatchLookup_FullMethodName  = "/maxmind.geoip.GeoIP/BatchLookup"
+	GeoIP_StreamLookup_FullMethodName = "/maxmind.geoip.GeoIP/StreamLookup"
+)
+
+// GeoIPClient is the client API for GeoIP service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type GeoIPClient interface {
+	// Lookup returns the geodata for a single ip.
+	Lookup(ctx context.Context, in *LookupRequest, opts ...grpc.CallOption) (*LookupResponse, error)
+	// BatchLookup returns the geodata for each requested ip, in order.
+	BatchLookup(ctx context.Context, in *BatchLookupRequest, opts ...grpc.CallOption) (*BatchLookupResponse, error)
+	// StreamLookup answers each request on the stream as it arrives.
+	StreamLookup(ctx context.Context, opts ...grpc.CallOption) (GeoIP_StreamLookupClient, error)
+}
+
+type geoIPClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewGeoIPClient(cc grpc.ClientConnInterface) GeoIPClient {
+	return &geoIPClient{cc}
+}
+
+func (c *geoIPClient) Lookup(ctx context.Context, in *LookupRequest, opts ...grpc.CallOption) (*LookupResponse, error) {
+	out := new(LookupResponse)
+	err := c.cc.Invoke(ctx, GeoIP_Lookup_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *geoIPClient) BatchLookup(ctx context.Context, in *BatchLookupRequest, opts ...grpc.CallOption) (*BatchLookupResponse, error) {
+	out := new(BatchLookupResponse)
+	err := c.cc.Invoke(ctx, GeoIP_BatchLookup_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *geoIPClient) StreamLookup(ctx context.Context, opts ...grpc.CallOption) (GeoIP_StreamLookupClient, error) {
+	stream, err := c.cc.NewStream(ctx, &GeoIP_ServiceDesc.Streams[0], GeoIP_StreamLookup_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &geoIPStreamLookupClient{stream}
+	return x, nil
+}
+
+type GeoIP_StreamLookupClient interface {
+	Send(*LookupRequest) error
+	Recv() (*LookupResponse, error)
+	grpc.ClientStream
+}
+
+type geoIPStreamLookupClient struct {
+	grpc.ClientStream
+}
+
+func (x *geoIPStreamLookupClient) Send(m *LookupRequest) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *geoIPStreamLookupClient) Recv() (*LookupResponse, error) {
+	m := new(LookupResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// GeoIPServer is the server API for GeoIP service.
+// All implementations must embed UnimplementedGeoIPServer
+// for forward compatibility
+type GeoIPServer interface {
+	// Lookup returns the geodata for a single ip.
+	Lookup(context.Context, *LookupRequest) (*LookupResponse, error)
+	// BatchLookup returns the geodata for each requested ip, in order.
+	BatchLookup(context.Context, *BatchLookupRequest) (*BatchLookupResponse, error)
+	// StreamLookup answers each request on the stream as it arrives.
+	StreamLookup(GeoIP_StreamLookupServer) error
+	mustEmbedUnimplementedGeoIPServer()
+}
+
+// UnimplementedGeoIPServer must be embedded to have forward compatible implementations.
+type UnimplementedGeoIPServer struct {
+}
+
+func (UnimplementedGeoIPServer) Lookup(context.Context, *LookupRequest) (*LookupResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Lookup not implemented")
+}
+func (UnimplementedGeoIPServer) BatchLookup(context.Context, *BatchLookupRequest) (*BatchLookupResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method BatchLookup not implemented")
+}
+func (UnimplementedGeoIPServer) StreamLookup(GeoIP_StreamLookupServer) error {
+	return status.Errorf(codes.Unimplemented, "method StreamLookup not implemented")
+}
+func (UnimplementedGeoIPServer) mustEmbedUnimplementedGeoIPServer() {}
+
+// UnsafeGeoIPServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to GeoIPServer will
+// result in compilation errors.
+type UnsafeGeoIPServer interface {
+	mustEmbedUnimplementedGeoIPServer()
+}
+
+func RegisterGeoIPServer(s grpc.ServiceRegistrar, srv GeoIPServer) {
+	s.RegisterService(&GeoIP_ServiceDesc, srv)
+}
+
+func _GeoIP_Lookup_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(LookupRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GeoIPServer).Lookup(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: GeoIP_Lookup_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GeoIPServer).Lookup(ctx, req.(*LookupRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GeoIP_BatchLookup_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BatchLookupRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GeoIPServer).BatchLookup(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: GeoIP_BatchLookup_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GeoIPServer).BatchLookup(ctx, req.(*BatchLookupRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GeoIP_StreamLookup_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(GeoIPServer).StreamLookup(&geoIPStreamLookupServer{stream})
+}
+
+type GeoIP_StreamLookupServer interface {
+	Send(*LookupResponse) error
+	Recv() (*LookupRequest, error)
+	grpc.ServerStream
+}
+
+type geoIPStreamLookupServer struct {
+	grpc.ServerStream
+}
+
+func (x *geoIPStreamLookupServer) Send(m *LookupResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *geoIPStreamLookupServer) Recv() (*LookupRequest, error) {
+	m := new(LookupRequest)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// GeoIP_ServiceDesc is the grpc.ServiceDesc for GeoIP service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var GeoIP_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "maxmind.geoip.GeoIP",
+	HandlerType: (*GeoIPServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Lookup",
+			Handler:    _GeoIP_Lookup_Handler,
+		},
+		{
+			MethodName: "BatchLookup",
+			Handler:    _GeoIP_BatchLookup_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamLookup",
+			Handler:       _GeoIP_StreamLookup_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "geoip.proto",
+}